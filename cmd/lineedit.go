@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errInterrupt: người dùng nhấn Ctrl-C khi đang nhập
+var errInterrupt = errors.New("interrupted")
+
+// lineEditor đọc một dòng từ terminal ở raw mode: backspace, Ctrl-U xóa
+// dòng, mũi tên lên/xuống duyệt lịch sử, tab hoàn thành từ cuối cùng
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *[]string
+	// complete trả về các từ có thể thay cho word, từ cuối của dòng;
+	// head là phần dòng đứng trước word
+	complete func(head, word string) []string
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	line := []rune{}
+	pos := len(*e.history) // vị trí trong lịch sử; len = dòng đang nhập
+	var draft []rune       // dòng đang nhập khi duyệt lịch sử
+
+	redraw := func() { fmt.Fprintf(e.out, "\r\033[K%s%s", prompt, string(line)) }
+	fmt.Fprint(e.out, prompt)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+			return string(line), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\n")
+			return "", errInterrupt
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+		case 127, '\b':
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				redraw()
+			}
+		case 21: // Ctrl-U
+			line = line[:0]
+			redraw()
+		case '\t':
+			line = e.completeLine(line, prompt)
+			redraw()
+		case 27: // ESC [ A / ESC [ B
+			if b, _ := e.in.ReadByte(); b != '[' {
+				continue
+			}
+			history := *e.history
+			switch b, _ := e.in.ReadByte(); b {
+			case 'A':
+				if pos == 0 {
+					continue
+				}
+				if pos == len(history) {
+					draft = line
+				}
+				pos--
+				line = []rune(history[pos])
+			case 'B':
+				if pos == len(history) {
+					continue
+				}
+				pos++
+				if pos == len(history) {
+					line = draft
+				} else {
+					line = []rune(history[pos])
+				}
+			default:
+				continue
+			}
+			redraw()
+		default:
+			if r >= ' ' {
+				line = append(line, r)
+				fmt.Fprint(e.out, string(r))
+			}
+		}
+	}
+}
+
+// completeLine hoàn thành từ cuối của line. Nhiều lựa chọn thì điền phần
+// chung, không điền thêm được gì thì in danh sách lựa chọn.
+func (e *lineEditor) completeLine(line []rune, prompt string) []rune {
+	s := string(line)
+	head, word := "", s
+	if i := strings.LastIndexByte(s, ' '); i >= 0 {
+		head, word = s[:i+1], s[i+1:]
+	}
+	candidates := e.complete(head, word)
+	switch len(candidates) {
+	case 0:
+		return line
+	case 1:
+		return []rune(head + candidates[0] + " ")
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(word) {
+		return []rune(head + prefix)
+	}
+	fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+	return line
+}
@@ -1,196 +1,563 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/NationalWind/ses-project/pkg/bench"
+	"github.com/NationalWind/ses-project/pkg/config"
+	"github.com/NationalWind/ses-project/pkg/explorer"
 	"github.com/NationalWind/ses-project/pkg/process"
+	"github.com/NationalWind/ses-project/pkg/scenario"
+	"github.com/NationalWind/ses-project/pkg/transport"
+	"github.com/NationalWind/ses-project/pkg/workload"
 )
 
-type Config struct {
-	NumProcesses       int             `json:"num_processes"`
-	MessagesPerProcess int             `json:"messages_per_process"`
-	MessagesPerMinute  int             `json:"messages_per_minute"`
-	Processes          []ProcessConfig `json:"processes"`
+// Exit code chung của mọi lệnh
+const (
+	exitOK      = 0 // thành công
+	exitFailure = 1 // lỗi khi chạy, hoặc kiểm tra không đạt
+	exitUsage   = 2 // sai cú pháp lệnh hoặc flag
+)
+
+// command là một lệnh con: ses <name> [flags]
+type command struct {
+	name    string
+	summary string
+	run     func(configPath string, args []string) int
 }
 
-type ProcessConfig struct {
-	ID      int    `json:"id"`
-	Address string `json:"address"`
-	Port    int    `json:"port"`
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "Run one process of the cluster", runProcess},
+		{"cluster", "Run every process in the config and wait for them", runCluster},
+		{"runs", "List the runs in the log directory", runRuns},
+		{"timeline", "Print the logs of a run in hybrid logical clock order", runTimeline},
+		{"trace", "Merge the message traces of a run into one OTLP/JSON file", runTrace},
+		{"verify", "Check the logs of a run for lost, duplicate or out-of-order deliveries", runVerify},
+		{"stats", "Show the statistics of a running process (see run --admin)", runStats},
+		{"top", "Live dashboard of every process (see run --admin)", runTop},
+		{"config", "Validate or convert config files", runConfig},
+		{"bench", "Benchmark an in-process cluster and write a report", withConfig(runBench)},
+		{"scenario", "Run scenario files", runScenario},
+		{"explore", "Check every interleaving of a small run", runExplore},
+		{"replay", "Replay recorded arrivals", runReplay},
+		{"gencerts", "Generate the TLS CA and process certificates", withConfig(runGenCerts)},
+	}
 }
 
 func main() {
-	config, err := loadConfig("config/config.json")
+	configPath, args, err := configFlag(os.Args[1:])
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		os.Exit(1)
+		fmt.Println(err)
+		os.Exit(exitUsage)
 	}
+	os.Exit(dispatch(configPath, args))
+}
 
-	if err := os.MkdirAll("logs", 0755); err != nil {
-		fmt.Printf("Error creating logs directory: %v\n", err)
-		os.Exit(1)
+// dispatch chạy lệnh args[0] và trả về exit code
+func dispatch(configPath string, args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitUsage
+	}
+	// Cách gọi cũ "ses <id> [send]" vẫn dùng được
+	if _, err := strconv.Atoi(args[0]); err == nil {
+		legacy := []string{"--id", args[0]}
+		if len(args) >= 2 && args[1] == "send" {
+			legacy = append(legacy, "--send")
+		}
+		return runProcess(configPath, legacy)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			return dispatch(configPath, []string{args[1], "--help"})
+		}
+		printUsage()
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(configPath, args[1:])
+		}
 	}
+	fmt.Printf("Unknown command %q\n\n", args[0])
+	printUsage()
+	return exitUsage
+}
 
-	if len(os.Args) < 2 {
-		fmt.Println("Missing process ID argument")
-		os.Exit(1)
+func printUsage() {
+	fmt.Println("Usage: ses [--config file] <command> [flags]")
+	fmt.Println("\nCommands:")
+	for _, c := range commands {
+		fmt.Printf("  %-10s %s\n", c.name, c.summary)
 	}
+	fmt.Println("\nThe config file is --config, else $" + config.EnvConfigPath + ", else " + config.DefaultPath + ".")
+	fmt.Println(`Run "ses <command> --help" for the flags of a command.`)
+}
 
-	processID, err := strconv.Atoi(os.Args[1])
-	if err != nil || processID < 0 || processID >= config.NumProcesses {
-		fmt.Println("Invalid process ID")
-		os.Exit(1)
+// newFlags tạo FlagSet cho một lệnh; usage là cú pháp sau "ses"
+func newFlags(name, usage, summary string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.Usage = func() {
+		fmt.Printf("Usage: ses %s\n\n%s\n", usage, summary)
+		hasFlags := false
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Println("\nFlags:")
+			flags.PrintDefaults()
+		}
 	}
+	return flags
+}
 
-	autoSend := false
-	if len(os.Args) >= 3 && os.Args[2] == "send" {
-		autoSend = true
+// parseFlags đọc flag trong args. ok = false nếu lệnh phải dừng ngay với
+// exit code code: 0 sau --help, 2 nếu flag sai
+func parseFlags(flags *flag.FlagSet, args []string) (code int, ok bool) {
+	err := flags.Parse(args)
+	switch {
+	case err == nil:
+		return exitOK, true
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK, false
 	}
+	return exitUsage, false
+}
 
-	// Build peers map and get own config
-	peers := make(map[int]string)
-	var myConfig ProcessConfig
-	for _, pc := range config.Processes {
-		if pc.ID == processID {
-			myConfig = pc
-		} else {
-			peers[pc.ID] = fmt.Sprintf("%s:%d", pc.Address, pc.Port)
+// usageError in lỗi cú pháp kèm usage của lệnh
+func usageError(flags *flag.FlagSet, format string, args ...interface{}) int {
+	fmt.Printf(format+"\n\n", args...)
+	flags.Usage()
+	return exitUsage
+}
+
+func logDirFlag(flags *flag.FlagSet) *string {
+	return flags.String("log-dir", process.DefaultLogDir, "directory of the runs; each run has its own directory of process logs, spill and record files")
+}
+
+func loadConfig(path string) (*config.Config, bool) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return nil, false
+	}
+	return cfg, true
+}
+
+// withConfig load config trước khi chạy lệnh
+func withConfig(run func(cfg *config.Config, args []string) int) func(string, []string) int {
+	return func(configPath string, args []string) int {
+		cfg, ok := loadConfig(configPath)
+		if !ok {
+			return exitFailure
+		}
+		return run(cfg, args)
+	}
+}
+
+// configFlag tách --config <path> (hoặc --config=<path>) khỏi args. Không
+// có flag thì dùng $SES_CONFIG, rồi config/config.json.
+func configFlag(args []string) (path string, rest []string, err error) {
+	path = os.Getenv(config.EnvConfigPath)
+	if path == "" {
+		path = config.DefaultPath
+	}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--config" && name != "-config" {
+			rest = append(rest, args[i])
+			continue
 		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("%s needs a file path", name)
+			}
+			i++
+			value = args[i]
+		}
+		path = value
+	}
+	return path, rest, nil
+}
+
+// runReplay replay các file record và trả về exit code:
+// 0 nếu mọi quyết định trùng khớp, 1 nếu có sai khác hoặc lỗi
+func runReplay(_ string, args []string) int {
+	flags := newFlags("replay", "replay <logs/process_N.record.jsonl>...",
+		"Replay recorded arrivals and check that every BUFFERED/DELIVERED decision is reproduced.")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		return usageError(flags, "missing record file")
+	}
+
+	exitCode := exitOK
+	for _, path := range paths {
+		report, err := process.Replay(path, io.Discard)
+		if err != nil {
+			fmt.Printf("Error replaying %s: %v\n", path, err)
+			exitCode = exitFailure
+			continue
+		}
+
+		fmt.Printf("\n=== Replay P%d (%s) ===\n", report.Header.ProcessID, path)
+		fmt.Printf("Seed: %d\n", report.Header.Seed)
+		fmt.Printf("Sends: %d | Arrivals: %d | Delivered: %d | Buffered: %d\n",
+			report.Sends, report.Arrivals, report.Delivered, report.Buffered)
+		if len(report.Mismatches) == 0 {
+			fmt.Println("✅ All BUFFERED/DELIVERED decisions reproduced")
+			continue
+		}
+
+		exitCode = exitFailure
+		fmt.Printf("❌ %d mismatches\n", len(report.Mismatches))
+		for _, m := range report.Mismatches {
+			fmt.Printf("  #%d %s %s: %s\n", m.Seq, m.Kind, m.What, m.Detail)
+		}
+	}
+	return exitCode
+}
+
+// runExplore duyệt mọi interleaving của spec, ví dụ:
+//
+//	ses explore "P0->P2 P0->P1 P1->P2"
+//
+// Exit code 0 nếu SES đúng trong mọi interleaving, 1 nếu có vi phạm hoặc lỗi
+func runExplore(_ string, args []string) int {
+	flags := newFlags("explore", `explore "P0->P2 P0->P1 P1->P2"`,
+		"Check causal delivery and liveness in every order of the given sends and their arrivals.")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() == 0 {
+		return usageError(flags, "missing spec")
+	}
+	spec, err := explorer.ParseSpec(strings.Join(flags.Args(), " "))
+	if err != nil {
+		return usageError(flags, "Invalid spec: %v", err)
 	}
 
-	// Create process
-	p, err := process.NewProcess(
-		processID,
-		myConfig.Address,
-		myConfig.Port,
-		config.NumProcesses,
-		peers,
-	)
+	start := time.Now()
+	report, err := explorer.Explore(spec, explorer.Options{})
 	if err != nil {
-		fmt.Printf("Error creating process: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("Error exploring: %v\n", err)
+		return exitFailure
 	}
-	defer p.Close()
 
-	if err := p.Start(); err != nil {
-		fmt.Printf("Error starting process: %v\n", err)
-		os.Exit(1)
+	fmt.Printf("Processes: %d | Messages: %d\n", spec.NumProcesses, report.Messages)
+	fmt.Printf("States: %d | Transitions: %d | Time: %v\n",
+		report.States, report.Transitions, time.Since(start).Round(time.Millisecond))
+	if report.Violation == nil {
+		fmt.Printf("✅ Causal delivery and liveness hold in all %s interleavings\n", report.Interleavings)
+		return exitOK
 	}
 
-	fmt.Printf("[P%d] Process started successfully!\n", processID)
+	fmt.Printf("❌ %s violation: %s\n", report.Violation.Kind, report.Violation.Description)
+	fmt.Println("Minimal counterexample:")
+	for i, step := range report.Violation.Trace {
+		fmt.Printf("  %2d. %s\n", i+1, step)
+	}
+	return exitFailure
+}
 
-	// Nếu autoSend = true
-	if autoSend {
-		// QUAN TRỌNG: Đợi tất cả process khác start lên
-		// Delay dài hơn để đảm bảo mọi process đã ready
-		fmt.Printf("[P%d] Waiting for all processes to start...\n", processID)
-		time.Sleep(5 * time.Second)
+// runScenario chạy các file kịch bản trên cluster trong cùng process:
+//
+//	ses scenario scenarios/*.ses
+//
+// Exit code 0 nếu mọi kỳ vọng đúng, 1 nếu có bước sai hoặc lỗi
+func runScenario(_ string, args []string) int {
+	flags := newFlags("scenario", "scenario <file.ses>...",
+		"Run scenario files on an in-process cluster and check their expectations.")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		return usageError(flags, "missing scenario file")
+	}
+
+	exitCode := exitOK
+	for _, path := range paths {
+		s, err := scenario.ParseFile(path)
+		if err != nil {
+			fmt.Printf("Invalid scenario: %v\n", err)
+			exitCode = exitFailure
+			continue
+		}
+		report, err := scenario.Run(s, io.Discard)
+		if err != nil {
+			fmt.Printf("Error running %s: %v\n", path, err)
+			exitCode = exitFailure
+			continue
+		}
 
-		fmt.Printf("[P%d] Starting to send messages...\n", processID)
-		p.SendMessages(config.MessagesPerProcess, config.MessagesPerMinute)
+		fmt.Printf("\n=== Scenario %s (%d processes) ===\n", s.Name, s.NumProcesses)
+		for _, step := range report.Steps {
+			if step.Failure != "" {
+				fmt.Printf("  ❌ %3d  %s\n         %s\n", step.Line, step.Text, step.Failure)
+				continue
+			}
+			fmt.Printf("  ✅ %3d  %s  → %s\n", step.Line, step.Text, step.Result)
+		}
+		if report.Failures == 0 {
+			fmt.Printf("✅ All %d steps passed\n", len(report.Steps))
+			continue
+		}
+		exitCode = exitFailure
+		fmt.Printf("❌ %d of %d steps failed\n", report.Failures, len(report.Steps))
+	}
+	return exitCode
+}
+
+// runConfig xử lý các lệnh về file config:
+//
+//	ses config validate [file...]
+//	ses config convert [-to json|yaml|toml] [input] [output]
+func runConfig(configPath string, args []string) int {
+	usage := func() {
+		fmt.Println("Usage: ses config validate [file...]")
+		fmt.Println("       ses config convert [-to json|yaml|toml] [input] [output]")
+		fmt.Println("\nRun \"ses config <command> --help\" for details.")
+	}
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(configPath, args[1:])
+	case "convert":
+		return runConfigConvert(configPath, args[1:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return exitOK
+	}
+	fmt.Printf("Unknown config command %q\n\n", args[0])
+	usage()
+	return exitUsage
+}
 
-		// Đợi một chút để process khác gửi messages đến
-		fmt.Printf("[P%d] Finished sending, waiting for incoming messages...\n", processID)
-		time.Sleep(10 * time.Second)
+// runConfigValidate load các file config (mặc định file hiện tại), áp dụng
+// biến môi trường SES_*, và báo mọi lỗi. Exit code 1 nếu có file không hợp lệ
+func runConfigValidate(configPath string, args []string) int {
+	flags := newFlags("config validate", "config validate [file...]",
+		"Check config files, with SES_* environment overrides applied, and report every problem.")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{configPath}
+	}
 
-		// Chờ tất cả messages được deliver
-		fmt.Printf("[P%d] Waiting for message delivery to complete...\n", processID)
-		if err := p.WaitForCompletion(60 * time.Second); err != nil {
-			fmt.Printf("[P%d] Warning: %v\n", processID, err)
+	exitCode := exitOK
+	for _, path := range paths {
+		cfg, err := config.Load(path)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			exitCode = exitFailure
+			continue
 		}
+		fmt.Printf("✅ %s: %d processes, %s transport, %s topology\n",
+			path, cfg.NumProcesses, orDefault(cfg.Transport.Type, "tcp"), orDefault(cfg.Topology.Type, "full"))
+	}
+	return exitCode
+}
 
-		// In stats cuối cùng
-		printStats(p)
-		return
+// runConfigConvert dịch file config sang định dạng khác. Thiếu input thì
+// dùng file config hiện tại, thiếu output thì in ra stdout; định dạng chọn
+// theo phần mở rộng hoặc -to
+func runConfigConvert(configPath string, args []string) int {
+	flags := newFlags("config convert", "config convert [-to json|yaml|toml] [input] [output]",
+		"Translate a config file between JSON, YAML and TOML. Without output it prints to stdout.")
+	to := flags.String("to", "", "output format: json | yaml | toml (default: from the output extension)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	input, output := configPath, ""
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		input = flags.Arg(0)
+	case 2:
+		input, output = flags.Arg(0), flags.Arg(1)
+	default:
+		return usageError(flags, "too many arguments")
 	}
 
-	// Interactive mode nếu không autoSend
-	fmt.Println("\nCommands:")
-	fmt.Println("  's' - Start sending messages")
-	fmt.Println("  'i' - Show statistics")
-	fmt.Println("  'b' - Show buffered messages")
-	fmt.Println("  'v' - Show vector clock")
-	fmt.Println("  'q' - Quit")
-	fmt.Print("\n> ")
-
-	// Interactive loop
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		cmd := scanner.Text()
-		switch cmd {
-		case "s":
-			go p.SendMessages(config.MessagesPerProcess, config.MessagesPerMinute)
-		case "i":
-			printStats(p)
-		case "b":
-			printBuffered(p)
-		case "v":
-			printVectorClock(p)
-		case "q":
-			fmt.Println("Shutting down...")
-			return
-		default:
-			fmt.Println("Unknown command")
-		}
-		fmt.Print("\n> ")
-	}
-}
-
-func loadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
+	from, err := config.FormatOf(input)
 	if err != nil {
-		return nil, err
+		return usageError(flags, "%v", err)
+	}
+	var format config.Format
+	switch {
+	case *to != "":
+		format, err = config.ParseFormat(*to)
+	case output != "":
+		format, err = config.FormatOf(output)
+	default:
+		err = fmt.Errorf("-to is needed when writing to stdout")
 	}
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, err
+	if err != nil {
+		return usageError(flags, "%v", err)
 	}
-	return &config, nil
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		return exitFailure
+	}
+	out, err := config.Convert(data, from, format)
+	if err != nil {
+		fmt.Printf("Error converting %s: %v\n", input, err)
+		return exitFailure
+	}
+	if output == "" {
+		os.Stdout.Write(out)
+		return exitOK
+	}
+	if err := os.WriteFile(output, out, 0644); err != nil {
+		fmt.Printf("Error writing config: %v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("✅ Converted %s (%s) to %s (%s)\n", input, from, output, format)
+	return exitOK
 }
 
-func printStats(p *process.Process) {
-	stats := p.GetStats()
-	fmt.Println("\n=== Process Statistics ===")
-	fmt.Printf("Process ID: %d\n", stats["id"])
-	fmt.Printf("Local Time (tP): %v\n", stats["local_time"])
-	fmt.Printf("Delivered Messages: %d\n", stats["delivered_count"])
-	fmt.Printf("Buffered Messages: %d\n", stats["buffered_count"])
-	fmt.Println("\nSent Messages:")
-	for id, count := range stats["sent_messages"].(map[int]int) {
-		fmt.Printf("  To P%d: %d\n", id, count)
+// runBench chạy cluster trong cùng process dưới workload trong config (hoặc
+// Poisson nếu config không có workload) và ghi report JSON và Markdown:
+//
+//	ses bench -processes 5 -messages 500 -rate 6000 -json bench.json -md bench.md
+//
+// Exit code 0 nếu mọi message được deliver, 1 nếu lỗi
+func runBench(cfg *config.Config, args []string) int {
+	flags := newFlags("bench", "bench [flags]",
+		"Run an in-process cluster under a workload and write JSON and Markdown reports.")
+	processes := flags.Int("processes", cfg.NumProcesses, "number of processes")
+	messages := flags.Int("messages", 200, "messages started by each process")
+	rate := flags.Float64("rate", 6000, "mean messages per minute per process")
+	arrivals := flags.String("arrivals", "", "uniform | poisson | burst (default: config workload, else poisson)")
+	destinations := flags.String("destinations", "", "uniform | zipf | hotspot (default: config workload)")
+	transportType := flags.String("transport", cfg.Transport.Type, "tcp | unix | udp")
+	seed := flags.Int64("seed", cfg.Seed, "seed for the workload (0 = time-based)")
+	timeout := flags.Duration("timeout", bench.DefaultTimeout, "maximum time to wait for every delivery")
+	jsonPath := flags.String("json", "bench.json", "JSON report path (empty = skip)")
+	mdPath := flags.String("md", "bench.md", "Markdown report path (empty = skip)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
 	}
-	fmt.Println("Received Messages:")
-	for id, count := range stats["received_messages"].(map[int]int) {
-		fmt.Printf("  From P%d: %d\n", id, count)
+	if flags.NArg() > 0 {
+		return usageError(flags, "unexpected argument %q", flags.Arg(0))
 	}
 
-	// Tính tổng
-	totalSent := 0
-	for _, count := range stats["sent_messages"].(map[int]int) {
-		totalSent += count
+	run := *cfg
+	run.NumProcesses = *processes
+	run.Transport.Type = *transportType
+	if !run.Workload.Enabled() {
+		run.Workload = workload.Config{Arrivals: "poisson"}
+	}
+	if *arrivals != "" {
+		run.Workload.Arrivals = *arrivals
+	}
+	if *destinations != "" {
+		run.Workload.Destinations = *destinations
 	}
-	totalReceived := 0
-	for _, count := range stats["received_messages"].(map[int]int) {
-		totalReceived += count
+	run.Workload.Messages, run.Workload.Rate = *messages, *rate
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	if _, err := newBaseTransport(&run, 0); err != nil {
+		fmt.Printf("Error in config: %v\n", err)
+		return exitFailure
+	}
+
+	fmt.Printf("Running %d processes over %s: %s arrivals, %d messages/process at %.0f/min...\n",
+		run.NumProcesses, orDefault(run.Transport.Type, "tcp"), run.Workload.Arrivals, *messages, *rate)
+	report, err := bench.Run(bench.Config{
+		Processes: run.NumProcesses,
+		Workload:  run.WorkloadConfig(),
+		Seed:      *seed,
+		Transport: func(id int) transport.Transport {
+			t, _ := newBaseTransport(&run, id)
+			return t
+		},
+		TransportName: orDefault(run.Transport.Type, "tcp"),
+		Timeout:       *timeout,
+	})
+	if err != nil {
+		fmt.Printf("Error running benchmark: %v\n", err)
+		return exitFailure
+	}
+
+	if err := report.WriteMarkdown(os.Stdout); err != nil {
+		return exitFailure
 	}
-	fmt.Printf("\nTotal Sent: %d\n", totalSent)
-	fmt.Printf("Total Received: %d\n", totalReceived)
-	fmt.Printf("Total Delivered: %d\n", stats["delivered_count"])
-	fmt.Printf("Total Buffered: %d\n", stats["buffered_count"])
+	for _, out := range []struct {
+		path  string
+		write func(io.Writer) error
+	}{{*jsonPath, report.WriteJSON}, {*mdPath, report.WriteMarkdown}} {
+		if out.path == "" {
+			continue
+		}
+		if err := writeFile(out.path, out.write); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+			return exitFailure
+		}
+		fmt.Printf("Report written to %s\n", out.path)
+	}
+	return exitOK
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
-func printBuffered(p *process.Process) {
-	stats := p.GetStats()
-	fmt.Printf("\nBuffered Messages: %d\n", stats["buffered_count"])
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
 }
 
-func printVectorClock(p *process.Process) {
-	stats := p.GetStats()
-	fmt.Printf("\nLocal Time (tP): %v\n", stats["local_time"])
-	fmt.Printf("Vector P entries: %v\n", stats["vector_p"])
+// runGenCerts tạo CA và certificate cho mọi process trong config:
+//
+//	ses gencerts [dir]   (mặc định dir = certs)
+func runGenCerts(cfg *config.Config, args []string) int {
+	flags := newFlags("gencerts", "gencerts [dir]",
+		"Generate a CA and a certificate for every process in the config (default dir: "+config.DefaultCertDir+").")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	dir := config.DefaultCertDir
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	if err := transport.GenerateCerts(dir, cfg.NumProcesses); err != nil {
+		fmt.Printf("Error generating certificates: %v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("✅ Generated CA and %d process certificates in %s (valid %v)\n",
+		cfg.NumProcesses, dir, transport.CertValidity)
+	return exitOK
 }
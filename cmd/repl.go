@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NationalWind/ses-project/pkg/config"
+	"github.com/NationalWind/ses-project/pkg/process"
+)
+
+// repl là chế độ interactive của "ses run" (không có --send)
+type repl struct {
+	p       *process.Process
+	cfg     *config.Config
+	history []string
+}
+
+// replCommand là một lệnh của REPL
+type replCommand struct {
+	name    string
+	alias   string // lệnh một chữ cái cũ
+	args    string
+	summary string
+	run     func(r *repl, args []string) (quit bool)
+	// complete trả về các giá trị cho tham số đầu tiên, dùng cho tab
+	complete func(r *repl) []string
+}
+
+var replCommands []replCommand
+
+func init() {
+	replCommands = []replCommand{
+		{"send", "", "<peer> <text>", "Send one message", (*repl).send, (*repl).peers},
+		{"broadcast", "", "<text>", "Send a message to every other process", (*repl).broadcast, nil},
+		{"multicast", "", "<text>", "Send a message every process delivers in the same total order", (*repl).multicast, nil},
+		{"order", "", "", "List the multicast messages delivered, in total order", (*repl).order, nil},
+		{"auto", "s", "", "Start sending the configured messages", (*repl).auto, nil},
+		{"buffer", "b", "", "List buffered messages and what blocks them", (*repl).buffer, nil},
+		{"vp", "v", "", "Show tP and V_P", (*repl).vectorClock, nil},
+		{"stats", "i", "", "Show statistics", (*repl).stats, nil},
+		{"hold", "", "[peer]", "Hold outgoing messages to peer (no peer: list held peers)", (*repl).hold, (*repl).peers},
+		{"release", "", "<peer|all>", "Send the messages held for peer", (*repl).release, (*repl).heldPeers},
+		{"history", "", "", "List previous commands (!N runs command N)", (*repl).showHistory, nil},
+		{"help", "", "[command]", "Show commands", (*repl).help, commandNames},
+		{"quit", "q", "", "Quit", (*repl).quit, nil},
+	}
+}
+
+func findReplCommand(name string) *replCommand {
+	for i, c := range replCommands {
+		if c.name == name || c.alias == name || (name == "exit" && c.name == "quit") {
+			return &replCommands[i]
+		}
+	}
+	return nil
+}
+
+// runREPL đọc lệnh từ stdin đến khi quit hoặc hết input. Trên terminal,
+// REPL có tab completion và lịch sử bằng phím mũi tên.
+func runREPL(p *process.Process, cfg *config.Config) int {
+	r := &repl{p: p, cfg: cfg}
+	r.help(nil)
+	fmt.Println()
+
+	read := r.plainReader()
+	if restore, err := rawMode(int(os.Stdin.Fd())); err == nil {
+		defer restore()
+		editor := &lineEditor{
+			in:       bufio.NewReader(os.Stdin),
+			out:      os.Stdout,
+			history:  &r.history,
+			complete: r.complete,
+		}
+		read = editor.readLine
+	}
+
+	for {
+		line, err := read("> ")
+		if errors.Is(err, errInterrupt) {
+			continue
+		}
+		if err != nil {
+			return exitOK
+		}
+		if r.exec(line) {
+			fmt.Println("Shutting down...")
+			return exitOK
+		}
+	}
+}
+
+// plainReader đọc từng dòng khi stdin không phải terminal
+func (r *repl) plainReader() func(prompt string) (string, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+	return func(prompt string) (string, error) {
+		fmt.Print(prompt)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return scanner.Text(), nil
+	}
+}
+
+// exec chạy một dòng lệnh, trả về true nếu phải thoát
+func (r *repl) exec(line string) bool {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "!") {
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(r.history) {
+			fmt.Printf("No command %s in history\n", line[1:])
+			return false
+		}
+		line = r.history[n-1]
+		fmt.Println(line)
+	}
+	if line == "" {
+		return false
+	}
+	if len(r.history) == 0 || r.history[len(r.history)-1] != line {
+		r.history = append(r.history, line)
+	}
+
+	fields := strings.Fields(line)
+	c := findReplCommand(fields[0])
+	if c == nil {
+		fmt.Printf("Unknown command %q, type \"help\" for the list\n", fields[0])
+		return false
+	}
+	return c.run(r, fields[1:])
+}
+
+// complete trả về các lựa chọn bắt đầu bằng word: tên lệnh cho từ đầu
+// tiên, giá trị của tham số đầu tiên cho từ thứ hai
+func (r *repl) complete(head, word string) []string {
+	var options []string
+	switch fields := strings.Fields(head); len(fields) {
+	case 0:
+		options = commandNames(r)
+	case 1:
+		if c := findReplCommand(fields[0]); c != nil && c.complete != nil {
+			options = c.complete(r)
+		}
+	}
+	var matches []string
+	for _, o := range options {
+		if strings.HasPrefix(o, word) {
+			matches = append(matches, o)
+		}
+	}
+	return matches
+}
+
+func commandNames(*repl) []string {
+	names := make([]string, len(replCommands))
+	for i, c := range replCommands {
+		names[i] = c.name
+	}
+	return names
+}
+
+// peers trả về ID các process khác
+func (r *repl) peers() []string {
+	var ids []string
+	for id := 0; id < r.p.NumProcesses; id++ {
+		if id != r.p.ID {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	return ids
+}
+
+func (r *repl) heldPeers() []string {
+	var ids []string
+	for _, id := range sortedIDs(r.p.Held()) {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return append(ids, "all")
+}
+
+// parsePeer đọc ID peer dạng "2" hoặc "P2"
+func (r *repl) parsePeer(s string) (int, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(s), "P"))
+	switch {
+	case err != nil || id < 0 || id >= r.p.NumProcesses:
+		fmt.Printf("Invalid peer %q: want 0..%d\n", s, r.p.NumProcesses-1)
+		return 0, false
+	case id == r.p.ID:
+		fmt.Printf("P%d is this process\n", id)
+		return 0, false
+	}
+	return id, true
+}
+
+func (r *repl) send(args []string) bool {
+	if len(args) < 2 {
+		fmt.Println("Usage: send <peer> <text>")
+		return false
+	}
+	peer, ok := r.parsePeer(args[0])
+	if !ok {
+		return false
+	}
+	if _, held := r.p.Held()[peer]; held {
+		fmt.Printf("✋ P%d is held: the message is sent on release\n", peer)
+	}
+	_, done, err := r.p.Send(peer, strings.Join(args[1:], " "))
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return false
+	}
+	go func() {
+		if err := <-done; err != nil {
+			fmt.Printf("❌ Send to P%d failed: %v\n", peer, err)
+		}
+	}()
+	return false
+}
+
+func (r *repl) broadcast(args []string) bool {
+	if len(args) == 0 {
+		fmt.Println("Usage: broadcast <text>")
+		return false
+	}
+	done := r.p.Broadcast(strings.Join(args, " "))
+	go func() {
+		if err := <-done; err != nil {
+			fmt.Printf("❌ Broadcast failed: %v\n", err)
+		}
+	}()
+	return false
+}
+
+func (r *repl) multicast(args []string) bool {
+	if len(args) == 0 {
+		fmt.Println("Usage: multicast <text>")
+		return false
+	}
+	msg, done := r.p.Multicast(strings.Join(args, " "))
+	fmt.Printf("📣 %s | lamport=%d\n", msg.ID, msg.Lamport)
+	go func() {
+		if err := <-done; err != nil {
+			fmt.Printf("❌ Multicast %s failed: %v\n", msg.ID, err)
+		}
+	}()
+	return false
+}
+
+func (r *repl) order([]string) bool {
+	delivered := r.p.TotalOrderDelivered()
+	fmt.Printf("Total Order Delivered: %d\n", len(delivered))
+	for i, msg := range delivered {
+		fmt.Printf("  %2d. %s from P%d | lamport=%d | %s\n", i+1, msg.ID, msg.SenderID, msg.Lamport, msg.PayloadSummary())
+	}
+	return false
+}
+
+func (r *repl) auto([]string) bool {
+	go send(r.p, r.cfg)
+	return false
+}
+
+func (r *repl) buffer([]string) bool {
+	blocked := r.p.BufferedMessages()
+	fmt.Printf("Buffered Messages: %d\n", len(blocked))
+	for i, b := range blocked {
+		fmt.Printf("  %2d. %s from P%d: %s\n", i+1, b.Message.ID, b.Message.SenderID, b.Reason())
+	}
+	return false
+}
+
+func (r *repl) vectorClock([]string) bool {
+	stats := r.p.Stats()
+	fmt.Printf("tP  = %v\n", stats.LocalTime)
+	entries := stats.VectorP
+	sort.Slice(entries, func(i, j int) bool { return entries[i].TargetProcessID < entries[j].TargetProcessID })
+	fmt.Printf("V_P = %d entries\n", len(entries))
+	for _, e := range entries {
+		fmt.Printf("  (P%d, %v)\n", e.TargetProcessID, e.Timestamp)
+	}
+	return false
+}
+
+func (r *repl) stats([]string) bool {
+	printStats(r.p.Stats())
+	return false
+}
+
+func (r *repl) hold(args []string) bool {
+	if len(args) == 0 {
+		held := r.p.Held()
+		if len(held) == 0 {
+			fmt.Println("No peer is held")
+		}
+		for _, id := range sortedIDs(held) {
+			fmt.Printf("✋ P%d: %d messages waiting\n", id, held[id])
+		}
+		return false
+	}
+	peer, ok := r.parsePeer(args[0])
+	if !ok {
+		return false
+	}
+	if err := r.p.Hold(peer); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return false
+	}
+	fmt.Printf("✋ Holding messages to P%d, \"release %d\" sends them\n", peer, peer)
+	return false
+}
+
+func (r *repl) release(args []string) bool {
+	if len(args) != 1 {
+		fmt.Println("Usage: release <peer|all>")
+		return false
+	}
+	peers := []int{}
+	if args[0] == "all" {
+		peers = sortedIDs(r.p.Held())
+	} else if peer, ok := r.parsePeer(args[0]); ok {
+		peers = append(peers, peer)
+	} else {
+		return false
+	}
+	for _, peer := range peers {
+		n, err := r.p.Release(peer)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		fmt.Printf("▶️ Released %d messages to P%d\n", n, peer)
+	}
+	return false
+}
+
+func (r *repl) showHistory([]string) bool {
+	for i, line := range r.history {
+		fmt.Printf("%4d  %s\n", i+1, line)
+	}
+	return false
+}
+
+func (r *repl) help(args []string) bool {
+	if len(args) > 0 {
+		c := findReplCommand(args[0])
+		if c == nil {
+			fmt.Printf("Unknown command %q\n", args[0])
+			return false
+		}
+		fmt.Printf("%s %s\n  %s\n", c.name, c.args, c.summary)
+		return false
+	}
+	fmt.Println("Commands:")
+	for _, c := range replCommands {
+		usage := strings.TrimSpace(c.name + " " + c.args)
+		if c.alias != "" {
+			usage += " (" + c.alias + ")"
+		}
+		fmt.Printf("  %-24s %s\n", usage, c.summary)
+	}
+	return false
+}
+
+func (r *repl) quit([]string) bool {
+	return true
+}
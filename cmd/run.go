@@ -0,0 +1,446 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/auth"
+	"github.com/NationalWind/ses-project/pkg/config"
+	"github.com/NationalWind/ses-project/pkg/process"
+	"github.com/NationalWind/ses-project/pkg/runlog"
+	"github.com/NationalWind/ses-project/pkg/topology"
+	"github.com/NationalWind/ses-project/pkg/transport"
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+// runProcess chạy một process của cluster:
+//
+//	ses run --id 3 [--send] [--log-dir logs] [--run name] [--admin 127.0.0.1:9103] [-v | -q]
+//
+// Không có --send thì process chờ lệnh từ stdin. Log ghi vào thư mục run
+// <log-dir>/<name>; các process cùng --run ghi chung một run. Exit code 1
+// nếu process không khởi động được hoặc (với --send) không deliver hết
+// message.
+func runProcess(configPath string, args []string) (code int) {
+	flags := newFlags("run", "run --id N [flags]",
+		"Run one process of the cluster. Without --send it waits for commands on stdin.")
+	id := flags.Int("id", -1, "process ID (required)")
+	autoSend := flags.Bool("send", false, "send right away, wait for delivery, print statistics and exit")
+	logDir := logDirFlag(flags)
+	runName := flags.String("run", "", "run directory inside --log-dir, shared by every process started with the same name (default: a new run named after the start time)")
+	admin := flags.String("admin", "", "serve statistics over HTTP on this address, e.g. 127.0.0.1:9100 (default: processes[N].admin in the config)")
+	verbosity := verbosityFlags(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "unexpected argument %q", flags.Arg(0))
+	}
+	if err := verbosity.check(); err != nil {
+		return usageError(flags, "%v", err)
+	}
+
+	cfg, ok := loadConfig(configPath)
+	if !ok {
+		return exitFailure
+	}
+	processID := *id
+	if processID < 0 || processID >= cfg.NumProcesses {
+		return usageError(flags, "--id must be in 0..%d", cfg.NumProcesses-1)
+	}
+	start := time.Now()
+	dir, err := newRun(*logDir, *runName, true, configPath, cfg.Seed, start)
+	if err != nil {
+		fmt.Printf("Error creating run directory: %v\n", err)
+		return exitFailure
+	}
+
+	myConfig, _ := cfg.Process(processID)
+	peers := cfg.Peers(processID)
+	if *admin == "" {
+		*admin = myConfig.Admin
+	}
+
+	// Create process
+	p, err := process.NewProcess(
+		processID,
+		myConfig.Address,
+		myConfig.Port,
+		cfg.NumProcesses,
+		peers,
+		dir,
+	)
+	if err != nil {
+		fmt.Printf("Error creating process: %v\n", err)
+		return exitFailure
+	}
+	defer p.Close()
+	defer func() {
+		updateRun(dir, func(m *runlog.Manifest) { m.Finished(processID, time.Now(), code, nil) })
+	}()
+	closeLogs, err := setupLogs(p, cfg.Log, verbosity, dir)
+	defer closeLogs()
+	if err != nil {
+		fmt.Printf("Error configuring logs: %v\n", err)
+		return exitFailure
+	}
+
+	policy, _ := process.ParseOverflowPolicy(cfg.OverflowPolicy) // đã kiểm tra khi load
+	if err := p.SetBufferLimit(cfg.BufferLimit, policy); err != nil {
+		fmt.Printf("Error configuring buffer: %v\n", err)
+		return exitFailure
+	}
+	if cfg.Seed != 0 {
+		p.SetSeed(cfg.Seed)
+	}
+	p.SetHLCMaxOffset(cfg.HLCMaxOffset())
+	fileLevel := cfg.Log.File.Level
+	if fileLevel == "" {
+		fileLevel = "info" // như ParseLogLevel
+	}
+	updateRun(dir, func(m *runlog.Manifest) {
+		m.Started(processID, start, p.Seed())
+		m.Process(processID).FileLogLevel = fileLevel
+	})
+	fmt.Printf("[P%d] 📁 Logs in %s\n", processID, dir)
+	if cfg.Topology.Type != "" && cfg.Topology.Type != "full" {
+		t, err := topology.New(cfg.Topology, cfg.NumProcesses)
+		if err != nil {
+			fmt.Printf("Error in topology: %v\n", err)
+			return exitFailure
+		}
+		p.SetTopology(t)
+		fmt.Printf("[P%d] 🕸 Topology %s, neighbors %v\n", processID, t.Name, t.Neighbors(processID))
+	}
+
+	base, err := newBaseTransport(cfg, processID)
+	if err != nil {
+		fmt.Printf("Error in config: %v\n", err)
+		return exitFailure
+	}
+	p.SetTransport(base)
+	if cfg.TLS.Enabled {
+		t, err := newTLSTransport(cfg, myConfig)
+		if err != nil {
+			fmt.Printf("Error configuring TLS: %v\n", err)
+			return exitFailure
+		}
+		t.Base = base
+		p.SetTransport(t)
+		fmt.Printf("[P%d] 🔒 Mutual TLS enabled (identity %s)\n", processID, transport.Identity(processID))
+	}
+	if cfg.Auth.Enabled || cfg.Encryption.Enabled {
+		keyring, err := auth.NewKeyring(cfg.Auth.Keys)
+		if err != nil {
+			fmt.Printf("Error configuring keys: %v\n", err)
+			return exitFailure
+		}
+		if cfg.Auth.Enabled {
+			p.SetKeyring(keyring)
+			fmt.Printf("[P%d] 🔑 HMAC message authentication enabled (%d keys)\n", processID, len(cfg.Auth.Keys))
+		}
+		if cfg.Encryption.Enabled {
+			p.SetPayloadEncryption(keyring)
+			fmt.Printf("[P%d] 🔐 End-to-end payload encryption enabled\n", processID)
+		}
+	}
+	if cfg.Workload.Enabled() {
+		g, err := newWorkload(cfg, processID, p.Seed())
+		if err != nil {
+			fmt.Printf("Error in workload: %v\n", err)
+			return exitFailure
+		}
+		p.SetWorkload(g)
+		fmt.Printf("[P%d] 📈 Workload: %s arrivals, %s destinations, %d messages\n",
+			processID, cfg.Workload.Arrivals, cfg.Workload.Destinations, g.Total())
+	}
+	if cfg.Record {
+		if err := p.StartRecording(); err != nil {
+			fmt.Printf("Error starting recording: %v\n", err)
+			return exitFailure
+		}
+		fmt.Printf("[P%d] Recording arrivals (seed=%d)\n", processID, p.Seed())
+	}
+	if cfg.Trace {
+		if err := p.StartTracing(); err != nil {
+			fmt.Printf("Error starting tracing: %v\n", err)
+			return exitFailure
+		}
+		fmt.Printf("[P%d] 🧵 Tracing messages (OTLP/JSON)\n", processID)
+	}
+
+	if err := p.Start(); err != nil {
+		fmt.Printf("Error starting process: %v\n", err)
+		return exitFailure
+	}
+	if *admin != "" {
+		listener, err := net.Listen("tcp", *admin)
+		if err != nil {
+			fmt.Printf("Error starting admin endpoint: %v\n", err)
+			return exitFailure
+		}
+		defer listener.Close()
+		go http.Serve(listener, p.AdminHandler())
+		fmt.Printf("[P%d] 🛠 Admin endpoint at http://%s/stats\n", processID, listener.Addr())
+	}
+
+	fmt.Printf("[P%d] Process started successfully!\n", processID)
+
+	// Với --send: gửi, chờ deliver, in stats rồi thoát
+	if *autoSend {
+		// QUAN TRỌNG: Đợi tất cả process khác start lên
+		// Delay dài hơn để đảm bảo mọi process đã ready
+		fmt.Printf("[P%d] Waiting for all processes to start...\n", processID)
+		time.Sleep(5 * time.Second)
+
+		fmt.Printf("[P%d] Starting to send messages...\n", processID)
+		send(p, cfg)
+
+		// Đợi một chút để process khác gửi messages đến
+		fmt.Printf("[P%d] Finished sending, waiting for incoming messages...\n", processID)
+		time.Sleep(10 * time.Second)
+
+		// Chờ tất cả messages được deliver
+		fmt.Printf("[P%d] Waiting for message delivery to complete...\n", processID)
+		err := p.WaitForCompletion(60 * time.Second)
+		if err != nil {
+			fmt.Printf("[P%d] Warning: %v\n", processID, err)
+		}
+		if cfg.TotalOrderMessages > 0 {
+			if terr := p.WaitForTotalOrder(cfg.NumProcesses*cfg.TotalOrderMessages, 60*time.Second); terr != nil {
+				fmt.Printf("[P%d] Warning: %v\n", processID, terr)
+				err = errors.Join(err, terr)
+			}
+		}
+
+		// In stats cuối cùng
+		printStats(p.Stats())
+		if err != nil {
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	// Interactive mode nếu không có --send
+	return runREPL(p, cfg)
+}
+
+// newBaseTransport tạo transport theo config.transport
+func newBaseTransport(cfg *config.Config, processID int) (transport.Transport, error) {
+	switch cfg.Transport.Type {
+	case "", "tcp":
+		return transport.TCP{LoopbackOnly: cfg.Transport.LoopbackOnly}, nil
+	case "unix":
+		dir := cfg.Transport.SocketDir
+		if dir == "" {
+			dir = config.DefaultSocketDir
+		}
+		return transport.Unix{Dir: dir, ProcessID: processID}, nil
+	case "udp":
+		if cfg.Transport.UDPLoss < 0 || cfg.Transport.UDPLoss >= 1 {
+			return nil, fmt.Errorf("udp_loss must be in [0, 1), got %v", cfg.Transport.UDPLoss)
+		}
+		return &transport.UDP{Loss: cfg.Transport.UDPLoss}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport type %q (want tcp, unix or udp)", cfg.Transport.Type)
+	}
+}
+
+// newTLSTransport đọc CA và certificate của process này theo config
+func newTLSTransport(cfg *config.Config, pc config.ProcessConfig) (*transport.TLS, error) {
+	caFile := cfg.TLS.CAFile
+	if caFile == "" {
+		caFile = transport.CAFile(config.DefaultCertDir)
+	}
+	// Mặc định certificate nằm cạnh CA, như gencerts tạo ra
+	certFile, keyFile := transport.CertFiles(filepath.Dir(caFile), pc.ID)
+	if pc.CertFile != "" {
+		certFile = pc.CertFile
+	}
+	if pc.KeyFile != "" {
+		keyFile = pc.KeyFile
+	}
+	return transport.NewTLS(caFile, certFile, keyFile)
+}
+
+// newWorkload tạo generator theo config
+func newWorkload(cfg *config.Config, processID int, seed int64) (*workload.Generator, error) {
+	// Mỗi process một nguồn random riêng, vẫn chỉ phụ thuộc vào seed
+	return workload.New(cfg.WorkloadConfig(), processID, cfg.NumProcesses, seed+int64(processID))
+}
+
+// send gửi message theo workload nếu có, không thì theo SendMessages; song
+// song multicast total_order_messages message total order
+func send(p *process.Process, cfg *config.Config) {
+	var wg sync.WaitGroup
+	if cfg.TotalOrderMessages > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.MulticastMessages(cfg.TotalOrderMessages, cfg.MessagesPerMinute)
+		}()
+	}
+	defer wg.Wait()
+
+	if cfg.Workload.Enabled() {
+		if err := p.RunWorkload(); err != nil {
+			fmt.Printf("[P%d] Error: %v\n", p.ID, err)
+		}
+		return
+	}
+	p.SendMessages(cfg.MessagesPerProcess, cfg.MessagesPerMinute)
+}
+
+func printStats(stats process.Stats) {
+	fmt.Println("\n=== Process Statistics ===")
+	fmt.Printf("Process ID: %d\n", stats.ID)
+	fmt.Printf("Local Time (tP): %v\n", stats.LocalTime)
+	fmt.Printf("Delivered Messages: %d\n", stats.Delivered)
+	fmt.Printf("Buffered Messages: %d\n", stats.Buffered)
+	fmt.Println("\nSent Messages:")
+	for _, id := range sortedIDs(stats.SentMessages) {
+		fmt.Printf("  To P%d: %d\n", id, stats.SentMessages[id])
+	}
+	fmt.Println("Received Messages:")
+	for _, id := range sortedIDs(stats.ReceivedMessages) {
+		fmt.Printf("  From P%d: %d\n", id, stats.ReceivedMessages[id])
+	}
+
+	fmt.Printf("\nTotal Sent: %d\n", stats.TotalSent())
+	fmt.Printf("Total Received: %d\n", stats.TotalReceived())
+	fmt.Printf("Total Delivered: %d\n", stats.Delivered)
+	fmt.Printf("Total Buffered: %d\n", stats.Buffered)
+	fmt.Printf("Ever Buffered: %d\n", stats.BufferedTotal)
+	fmt.Printf("Spilled to Disk: %d\n", stats.Spilled)
+	fmt.Printf("Rejected (Buffer Full): %d\n", stats.Rejected)
+	fmt.Printf("Forwarded (Relay): %d\n", stats.Forwarded)
+	fmt.Printf("Chains Completed: %d\n", stats.ChainsCompleted)
+	fmt.Printf("Total Order Delivered: %d\n", stats.TotalDelivered)
+	for kind, count := range stats.InvalidMessages {
+		fmt.Printf("Invalid (%s): %d\n", kind, count)
+	}
+	if counters := stats.Transport; len(counters) > 0 {
+		names := make([]string, 0, len(counters))
+		for name := range counters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("Transport:")
+		for _, name := range names {
+			fmt.Printf("  %s: %d\n", name, counters[name])
+		}
+	}
+}
+
+func sortedIDs(counts map[int]int) []int {
+	ids := make([]int, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// verbosity là -v / -q của các lệnh chạy process
+type verbosity struct {
+	verbose, quiet *bool
+}
+
+func verbosityFlags(flags *flag.FlagSet) verbosity {
+	return verbosity{
+		verbose: flags.Bool("v", false, "print every message event on the console (log.console.level = debug)"),
+		quiet:   flags.Bool("q", false, "print only startup messages, warnings, errors and statistics (log.console.level = warn)"),
+	}
+}
+
+func (v verbosity) check() error {
+	if *v.verbose && *v.quiet {
+		return fmt.Errorf("-v and -q cannot be used together")
+	}
+	return nil
+}
+
+// args trả về flag để truyền cho process con
+func (v verbosity) args() []string {
+	switch {
+	case *v.verbose:
+		return []string{"-v"}
+	case *v.quiet:
+		return []string{"-q"}
+	}
+	return nil
+}
+
+// consoleLevel là level của console theo config, -v và -q ghi đè
+func (v verbosity) consoleLevel(configured string) string {
+	switch {
+	case *v.verbose:
+		return "debug"
+	case *v.quiet:
+		return "warn"
+	}
+	return configured
+}
+
+// setupLogs tạo các sink log theo config.log: file log trong thư mục run
+// dir, console và các sink thêm. close đóng các file mà sink đã mở.
+func setupLogs(p *process.Process, cfg config.LogConfig, v verbosity, dir string) (close func(), err error) {
+	var files []*os.File
+	close = func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	// Level và format đã được kiểm tra khi load config
+	level, _ := process.ParseLogLevel(cfg.File.Level)
+	format, _ := process.ParseLogFormat(cfg.File.Format)
+	if err := p.SetFileLog(format, level); err != nil {
+		return close, err
+	}
+
+	console := cfg.Console
+	console.Path = "stdout"
+	console.Level = v.consoleLevel(console.Level)
+	for _, sink := range append([]config.LogSink{console}, cfg.Sinks...) {
+		level, _ := process.ParseLogLevel(sink.Level)
+		format, _ := process.ParseLogFormat(sink.Format)
+		if level == process.LevelOff {
+			continue
+		}
+		var w io.Writer
+		switch sink.Path {
+		case "stdout":
+			w = os.Stdout
+		case "stderr":
+			w = os.Stderr
+		default:
+			path := strings.ReplaceAll(sink.Path, "{id}", strconv.Itoa(p.ID))
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			f, err := os.Create(path)
+			if err != nil {
+				return close, err
+			}
+			files = append(files, f)
+			w = f
+		}
+		h, err := process.NewLogHandler(w, format, level, p.ID)
+		if err != nil {
+			return close, err
+		}
+		p.AddLogSink(h)
+	}
+	return close, nil
+}
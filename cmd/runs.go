@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/runlog"
+	"github.com/NationalWind/ses-project/pkg/tracing"
+)
+
+// newRun tạo thư mục run trong base (name rỗng: đặt theo giờ bắt đầu) và
+// ghi thông tin chung của lần chạy vào manifest. join = true thì dùng lại
+// run name nếu đã có, để các process chạy riêng ghi chung một run.
+func newRun(base, name string, join bool, configPath string, seed int64, start time.Time) (string, error) {
+	var dir string
+	var err error
+	if join && name != "" {
+		dir, err = runlog.Join(base, name, start)
+	} else {
+		dir, err = runlog.Create(base, name, start)
+	}
+	if err != nil {
+		return "", err
+	}
+	return dir, runlog.Update(dir, func(m *runlog.Manifest) {
+		if m.ConfigPath != "" {
+			return // process khác trong run đã ghi
+		}
+		m.Command = os.Args
+		m.ConfigPath = configPath
+		m.Seed = seed
+		m.GitCommit = runlog.GitCommit()
+		m.ConfigFile, _ = runlog.CopyConfig(dir, configPath)
+	})
+}
+
+// updateRun sửa manifest của dir; lỗi chỉ được cảnh báo vì log của lần chạy
+// vẫn dùng được khi thiếu manifest
+func updateRun(dir string, change func(m *runlog.Manifest)) {
+	if err := runlog.Update(dir, change); err != nil {
+		fmt.Printf("⚠️ Cannot update the run manifest: %v\n", err)
+	}
+}
+
+// runDir chọn thư mục log để đọc: run name trong base, không có name thì
+// run mới nhất, chưa có run nào thì chính base (log từ trước khi có run)
+func runDir(base, name string) string {
+	if name != "" {
+		return filepath.Join(base, name)
+	}
+	if dir, ok := runlog.Latest(base); ok {
+		return dir
+	}
+	return base
+}
+
+// runRuns liệt kê các lần chạy trong --log-dir:
+//
+//	ses runs [--log-dir logs]
+func runRuns(_ string, args []string) int {
+	flags := newFlags("runs", "runs [flags]",
+		"List the runs in the log directory with their start time, duration, process results, seed and commit.")
+	logDir := logDirFlag(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "unexpected argument %q", flags.Arg(0))
+	}
+
+	runs, err := runlog.List(*logDir)
+	if err != nil {
+		fmt.Printf("Error reading runs: %v\n", err)
+		return exitFailure
+	}
+	if len(runs) == 0 {
+		fmt.Printf("No runs in %s\n", *logDir)
+		return exitOK
+	}
+	latest, _ := runlog.Latest(*logDir)
+	fmt.Printf("  %-20s %-19s %9s  %-10s %-20s %s\n", "RUN", "START", "DURATION", "PROCESSES", "SEED", "COMMIT")
+	for _, m := range runs {
+		marker := " "
+		if filepath.Join(*logDir, m.Name) == latest {
+			marker = "*"
+		}
+		duration := "running"
+		if m.End != nil {
+			duration = m.Duration().Round(time.Second).String()
+		}
+		result := fmt.Sprintf("%d/%d ok", m.Succeeded(), len(m.Processes))
+		commit, dirty := strings.CutSuffix(m.GitCommit, "-dirty")
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if dirty {
+			commit += "-dirty"
+		}
+		fmt.Printf("%s %-20s %-19s %9s  %-10s %-20d %s\n", marker, m.Name,
+			m.Start.Format("2006-01-02 15:04:05"), duration, result, m.Seed, commit)
+	}
+	return exitOK
+}
+
+// runTrace gộp file trace của các process trong một run thành một file
+// OTLP/JSON duy nhất để mở trong trace viewer:
+//
+//	ses trace [--log-dir logs] [--run name] [-o file]
+func runTrace(_ string, args []string) int {
+	flags := newFlags("trace", "trace [flags]",
+		"Merge the process_N.trace.jsonl files of a run (written when \"trace\" is true in the config)\n"+
+			"into one OTLP/JSON file that trace viewers can open.")
+	logDir := logDirFlag(flags)
+	runName := flags.String("run", "", "run directory inside --log-dir (default: the latest run)")
+	out := flags.String("o", "", "output file, - for stdout (default: trace.json in the run directory)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "unexpected argument %q", flags.Arg(0))
+	}
+
+	dir := runDir(*logDir, *runName)
+	paths, _ := filepath.Glob(filepath.Join(dir, "process_*.trace.jsonl"))
+	if len(paths) == 0 {
+		fmt.Printf("No traces in %s (set \"trace\": true in the config)\n", dir)
+		return exitFailure
+	}
+	if *out == "-" {
+		if err := tracing.Merge(os.Stdout, paths...); err != nil {
+			fmt.Printf("Error merging traces: %v\n", err)
+			return exitFailure
+		}
+		return exitOK
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, "trace.json")
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Printf("Error creating %s: %v\n", *out, err)
+		return exitFailure
+	}
+	defer f.Close()
+	if err := tracing.Merge(f, paths...); err != nil {
+		fmt.Printf("Error merging traces: %v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("🧵 Merged %d process traces into %s\n", len(paths), *out)
+	return exitOK
+}
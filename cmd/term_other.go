@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// rawMode không có trên hệ điều hành này: REPL đọc từng dòng, không có
+// tab completion và lịch sử bằng phím mũi tên, ses top chỉ in một lần
+func rawMode(fd int) (restore func(), err error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
+
+func termSize(fd int) (cols, rows int, err error) {
+	return 0, 0, errors.New("terminal size not supported")
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/process"
+)
+
+const (
+	// maxTopEvents là số dòng log gần nhất của cả cluster mà ses top giữ lại
+	maxTopEvents = 500
+	// maxTopBuffer là số message trong buffer được liệt kê khi xem một process
+	maxTopBuffer = 20
+)
+
+// runTop hiển thị dashboard của cả cluster qua admin endpoint của từng
+// process:
+//
+//	ses top [--addr host:port,...] [--interval 1s] [--once]
+//
+// Không có --addr thì dùng processes[N].admin trong config.
+func runTop(configPath string, args []string) int {
+	flags := newFlags("top", "top [flags]",
+		"Live dashboard of every process: tP, buffer depth, delivered counts and recent events, read from the admin endpoints.\n"+
+			"Keys: up/down (or k/j) select a process, enter shows its buffer, esc goes back, q quits.")
+	addrs := flags.String("addr", "", "comma-separated admin addresses (default: processes[N].admin in the config)")
+	interval := flags.Duration("interval", time.Second, "refresh interval")
+	once := flags.Bool("once", false, "print one snapshot and exit (also used when stdin is not a terminal)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "unexpected argument %q", flags.Arg(0))
+	}
+	if *interval <= 0 {
+		return usageError(flags, "--interval must be positive")
+	}
+
+	var targets []string
+	if *addrs != "" {
+		for _, a := range strings.Split(*addrs, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				targets = append(targets, a)
+			}
+		}
+	} else {
+		cfg, ok := loadConfig(configPath)
+		if !ok {
+			return exitFailure
+		}
+		for _, pc := range cfg.Processes {
+			if pc.Admin != "" {
+				targets = append(targets, pc.Admin)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return usageError(flags, "no admin endpoints: set processes[N].admin in the config or use --addr")
+	}
+
+	m := newTopModel(targets, min(*interval, 2*time.Second))
+	if !*once {
+		if restore, err := rawMode(int(os.Stdin.Fd())); err == nil {
+			defer restore()
+			return m.run(*interval)
+		}
+	}
+	m.poll()
+	fmt.Print(m.render(120, 0))
+	if m.reachable() == 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// topProcess là trạng thái của một process theo lần đọc gần nhất
+type topProcess struct {
+	addr   string
+	client *process.AdminClient
+	stats  process.Stats
+	err    error
+	id     int // ID của process, -1 khi chưa đọc được lần nào
+	seq    int // Seq của dòng log mới nhất đã đọc
+}
+
+func (tp *topProcess) name() string {
+	if tp.id < 0 {
+		return tp.addr
+	}
+	return fmt.Sprintf("P%d", tp.id)
+}
+
+// topModel là dữ liệu và trạng thái màn hình của ses top
+type topModel struct {
+	procs    []*topProcess
+	events   []string // dòng log của mọi process, mới nhất ở cuối
+	selected int
+	detail   bool // đang xem buffer của process selected
+	buffer   []process.Blocked
+	bufErr   error
+}
+
+func newTopModel(addrs []string, timeout time.Duration) *topModel {
+	m := &topModel{}
+	for _, addr := range addrs {
+		m.procs = append(m.procs, &topProcess{addr: addr, client: process.NewAdminClient(addr, timeout), id: -1})
+	}
+	return m
+}
+
+// run vẽ lại màn hình mỗi interval và xử lý phím đến khi người dùng thoát
+func (m *topModel) run(interval time.Duration) int {
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- string(buf[:n])
+		}
+	}()
+
+	// Alternate screen, ẩn cursor; trả lại như cũ khi thoát
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	m.poll()
+	for {
+		cols, rows, err := termSize(int(os.Stdout.Fd()))
+		if err != nil || cols <= 0 {
+			cols, rows = 120, 40
+		}
+		// Dòng cuối không xuống dòng để màn hình không bị cuộn
+		frame := strings.TrimSuffix(m.render(cols, rows), "\n")
+		fmt.Print("\033[H" + strings.ReplaceAll(frame, "\n", "\033[K\n") + "\033[K\033[J")
+
+		select {
+		case <-ticker.C:
+			m.poll()
+		case key, ok := <-keys:
+			if !ok || !m.handleKey(key) {
+				return exitOK
+			}
+			if m.detail {
+				m.pollBuffer()
+			}
+		}
+	}
+}
+
+// handleKey xử lý một lần nhấn phím, false nếu phải thoát
+func (m *topModel) handleKey(key string) bool {
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\x1b[A", "k":
+		m.selected = max(m.selected-1, 0)
+	case "\x1b[B", "j":
+		m.selected = min(m.selected+1, len(m.procs)-1)
+	case "\r", "\n", "b":
+		m.detail = true
+	case "\x1b", "\x7f", "h":
+		m.detail = false
+	}
+	return true
+}
+
+// poll đọc stats và log mới của mọi process song song
+func (m *topModel) poll() {
+	newLines := make([][]process.LogLine, len(m.procs))
+	var wg sync.WaitGroup
+	for i, tp := range m.procs {
+		wg.Add(1)
+		go func(i int, tp *topProcess) {
+			defer wg.Done()
+			tp.stats, tp.err = tp.client.Stats()
+			if tp.err != nil {
+				return
+			}
+			tp.id = tp.stats.ID
+			lines, err := tp.client.Log(tp.seq)
+			if err != nil {
+				return
+			}
+			// Process khởi động lại: Seq bắt đầu lại từ 1
+			if len(lines) > 0 && lines[0].Seq <= tp.seq {
+				lines, _ = tp.client.Log(0)
+			}
+			newLines[i] = lines
+		}(i, tp)
+	}
+	wg.Wait()
+
+	var batch []string
+	for i, lines := range newLines {
+		for _, line := range lines {
+			batch = append(batch, compactLogLine(line.Line))
+			m.procs[i].seq = line.Seq
+		}
+	}
+	// Xen kẽ dòng của các process theo giờ trong log
+	sort.SliceStable(batch, func(i, j int) bool { return logTime(batch[i]) < logTime(batch[j]) })
+	m.events = append(m.events, batch...)
+	if n := len(m.events); n > maxTopEvents {
+		m.events = append([]string(nil), m.events[n-maxTopEvents:]...)
+	}
+	if m.detail {
+		m.pollBuffer()
+	}
+}
+
+func (m *topModel) pollBuffer() {
+	m.buffer, m.bufErr = m.procs[m.selected].client.Buffer()
+}
+
+func (m *topModel) reachable() int {
+	n := 0
+	for _, tp := range m.procs {
+		if tp.err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// render vẽ màn hình rộng cols cột. rows = 0: không giới hạn số dòng.
+func (m *topModel) render(cols, rows int) string {
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, truncate(fmt.Sprintf(format, args...), cols))
+	}
+
+	add("ses top: %d/%d processes up, %s", m.reachable(), len(m.procs), time.Now().Format("15:04:05"))
+	if m.detail {
+		m.renderBuffer(add)
+	} else {
+		m.renderGrid(add)
+	}
+
+	// Phần còn lại của màn hình là các event gần nhất
+	events := m.events
+	title := "Recent events"
+	if m.detail {
+		tp := m.procs[m.selected]
+		prefix := fmt.Sprintf("[P%d] ", tp.id)
+		events = nil
+		for _, e := range m.events {
+			if strings.HasPrefix(e, prefix) {
+				events = append(events, e)
+			}
+		}
+		title = "Recent events of " + tp.name()
+	}
+	room := 10
+	if rows > 0 {
+		room = rows - len(lines) - 2
+	}
+	add("")
+	add("%s", title)
+	if room > 0 && len(events) > room {
+		events = events[len(events)-room:]
+	}
+	for _, e := range events[:max(min(room, len(events)), 0)] {
+		add("  %s", e)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (m *topModel) renderGrid(add func(string, ...interface{})) {
+	maxBuffered := 1
+	for _, tp := range m.procs {
+		if tp.err == nil {
+			maxBuffered = max(maxBuffered, tp.stats.Buffered)
+		}
+	}
+	add("")
+	add("  %-5s %5s %-10s %6s %7s %7s %7s %5s  %s", "PROC", "BUF", "", "EVER", "DELIV", "SENT", "RECV", "REJ", "tP")
+	for i, tp := range m.procs {
+		marker := " "
+		if i == m.selected {
+			marker = ">"
+		}
+		if tp.err != nil {
+			add("%s %-5s down: %v", marker, tp.name(), tp.err)
+			continue
+		}
+		s := tp.stats
+		// Thanh độ sâu buffer, so với process có buffer sâu nhất
+		bar := strings.Repeat("█", (s.Buffered*10+maxBuffered-1)/maxBuffered)
+		add("%s %-5s %5d %-10s %6d %7d %7d %7d %5d  %v", marker, fmt.Sprintf("P%d", s.ID),
+			s.Buffered, bar, s.BufferedTotal, s.Delivered, s.TotalSent(), s.TotalReceived(), s.Rejected, s.LocalTime)
+	}
+	add("")
+	add("up/down select, enter buffer, q quit")
+}
+
+func (m *topModel) renderBuffer(add func(string, ...interface{})) {
+	tp := m.procs[m.selected]
+	add("")
+	if tp.err != nil {
+		add("%s down: %v", tp.name(), tp.err)
+	} else {
+		add("P%d  tP=%v  delivered %d", tp.stats.ID, tp.stats.LocalTime, tp.stats.Delivered)
+	}
+	switch {
+	case m.bufErr != nil:
+		add("Buffer: %v", m.bufErr)
+	case len(m.buffer) == 0:
+		add("Buffer is empty")
+	default:
+		add("Buffer: %d messages", len(m.buffer))
+	}
+	for i, b := range m.buffer[:min(len(m.buffer), maxTopBuffer)] {
+		add("  %3d. %s from P%d: %s", i+1, b.Message.ID, b.Message.SenderID, b.Reason())
+	}
+	if len(m.buffer) > maxTopBuffer {
+		add("  ... and %d more", len(m.buffer)-maxTopBuffer)
+	}
+	add("")
+	add("esc back, q quit")
+}
+
+// logDate là ngày trong prefix của log.LstdFlags
+var logDate = regexp.MustCompile(`^(\[P\d+\] )\d{4}/\d\d/\d\d `)
+
+// compactLogLine bỏ ngày khỏi dòng log, chỉ giữ giờ
+func compactLogLine(line string) string {
+	return logDate.ReplaceAllString(line, "$1")
+}
+
+// logTime trả về giờ "15:04:05" của dòng đã qua compactLogLine
+func logTime(line string) string {
+	if _, rest, ok := strings.Cut(line, "] "); ok && len(rest) >= 8 {
+		return rest[:8]
+	}
+	return ""
+}
+
+// truncate cắt s còn tối đa width ký tự
+func truncate(s string, width int) string {
+	if r := []rune(s); len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// MinSecretSize là độ dài tối thiểu (byte) của secret
+const MinSecretSize = 16
+
+// Key là một shared key trong config.
+// Peers = [a, b]: key chỉ dùng cho cặp P_a <-> P_b, chỉ hai process đó giữ.
+// Peers rỗng: key chung, mỗi cặp dùng key riêng derive từ secret. Mọi
+// process giữ secret gốc nên derive được key của mọi cặp: key chung chống
+// được kẻ ngoài cluster, không cô lập các process với nhau. Muốn một process
+// không giả mạo được message giữa hai process khác, dùng key theo Peers.
+// NotBefore/NotAfter là cửa sổ hiệu lực (zero = không giới hạn). Khi xoay
+// key, cho cửa sổ của key mới và key cũ chồng lên nhau: sender chuyển sang
+// key mới ngay khi nó có hiệu lực, receiver vẫn nhận key cũ đến NotAfter.
+type Key struct {
+	ID        string    `json:"id"`
+	Secret    string    `json:"secret"` // hex
+	Peers     []int     `json:"peers,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// active kiểm tra key có hiệu lực tại thời điểm now
+func (k *Key) active(now time.Time) bool {
+	if !k.NotBefore.IsZero() && now.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !now.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// appliesTo kiểm tra key có dùng được cho cặp (a, b) không
+func (k *Key) appliesTo(a, b int) bool {
+	if len(k.Peers) == 0 {
+		return true
+	}
+	return (k.Peers[0] == a && k.Peers[1] == b) || (k.Peers[0] == b && k.Peers[1] == a)
+}
+
+type keyEntry struct {
+	Key
+	secret []byte
+}
+
+// pairSecret trả về secret dùng cho cặp (a, b), không phụ thuộc chiều gửi
+func (e *keyEntry) pairSecret(a, b int) []byte {
+	if len(e.Peers) != 0 {
+		return e.secret
+	}
+	if a > b {
+		a, b = b, a
+	}
+	mac := hmac.New(sha256.New, e.secret)
+	fmt.Fprintf(mac, "ses-pair %d-%d", a, b)
+	return mac.Sum(nil)
+}
+
+// Keyring ký và kiểm tra HMAC-SHA256 của message, và mã hóa Content
+// bằng AEAD (xem seal.go)
+type Keyring struct {
+	keys []keyEntry
+	now  func() time.Time
+}
+
+// NewKeyring kiểm tra và nạp các key từ config
+func NewKeyring(keys []Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys configured")
+	}
+	k := &Keyring{now: time.Now}
+	for i, key := range keys {
+		if key.ID == "" {
+			return nil, fmt.Errorf("key %d: missing id", i)
+		}
+		secret, err := hex.DecodeString(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: secret is not hex: %w", key.ID, err)
+		}
+		if len(secret) < MinSecretSize {
+			return nil, fmt.Errorf("key %q: secret has %d bytes, want at least %d", key.ID, len(secret), MinSecretSize)
+		}
+		if len(key.Peers) != 0 && (len(key.Peers) != 2 || key.Peers[0] == key.Peers[1] || key.Peers[0] < 0 || key.Peers[1] < 0) {
+			return nil, fmt.Errorf("key %q: peers must be two different process IDs, got %v", key.ID, key.Peers)
+		}
+		if !key.NotBefore.IsZero() && !key.NotAfter.IsZero() && !key.NotAfter.After(key.NotBefore) {
+			return nil, fmt.Errorf("key %q: not_after %v is not after not_before %v", key.ID, key.NotAfter, key.NotBefore)
+		}
+		for _, other := range k.keys {
+			if other.ID == key.ID && overlapsPeers(other.Key, key) {
+				return nil, fmt.Errorf("key %q: duplicate id", key.ID)
+			}
+		}
+		k.keys = append(k.keys, keyEntry{Key: key, secret: secret})
+	}
+	return k, nil
+}
+
+// overlapsPeers kiểm tra hai key có cùng áp dụng cho một cặp nào đó không
+func overlapsPeers(a, b Key) bool {
+	if len(a.Peers) == 0 || len(b.Peers) == 0 {
+		return true
+	}
+	return a.appliesTo(b.Peers[0], b.Peers[1])
+}
+
+// signingKey chọn key mới nhất (NotBefore lớn nhất) đang có hiệu lực cho cặp
+func (k *Keyring) signingKey(a, b int, now time.Time) *keyEntry {
+	var best *keyEntry
+	for i := range k.keys {
+		e := &k.keys[i]
+		if !e.appliesTo(a, b) || !e.active(now) {
+			continue
+		}
+		if best == nil || !e.NotBefore.Before(best.NotBefore) {
+			best = e
+		}
+	}
+	return best
+}
+
+// CanSign kiểm tra cặp (a, b) có key đang hiệu lực để Sign và Seal không
+func (k *Keyring) CanSign(a, b int) error {
+	if k.signingKey(a, b, k.now()) == nil {
+		return fmt.Errorf("no active key for P%d-P%d", a, b)
+	}
+	return nil
+}
+
+// Sign đặt KeyID và MAC cho msg bằng key hiện hành của cặp sender/receiver
+func (k *Keyring) Sign(msg *message.Message) error {
+	e := k.signingKey(msg.SenderID, msg.ReceiverID, k.now())
+	if e == nil {
+		return fmt.Errorf("no active key for P%d-P%d", msg.SenderID, msg.ReceiverID)
+	}
+	sign(e, msg)
+	return nil
+}
+
+func sign(e *keyEntry, msg *message.Message) {
+	msg.KeyID = e.ID
+	msg.MAC = computeMAC(e.pairSecret(msg.SenderID, msg.ReceiverID), msg)
+}
+
+// Verify kiểm tra MAC của msg. Lỗi trả về là *message.ValidationError với
+// Kind = message.ErrBadMAC. Chỉ gọi sau msg.Validate: SenderID và
+// ReceiverID phải hợp lệ.
+func (k *Keyring) Verify(msg *message.Message) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &message.ValidationError{MessageID: msg.ID, Kind: message.ErrBadMAC, Detail: fmt.Sprintf(format, args...)}
+	}
+	if len(msg.MAC) == 0 {
+		return invalid("message is not signed")
+	}
+
+	e, err := k.activeKey(msg)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	if !hmac.Equal(msg.MAC, computeMAC(e.pairSecret(msg.SenderID, msg.ReceiverID), msg)) {
+		return invalid("MAC does not match (key %q)", e.ID)
+	}
+	return nil
+}
+
+// lookup tìm key có ID keyID dùng cho cặp (a, b)
+func (k *Keyring) lookup(keyID string, a, b int) *keyEntry {
+	for i := range k.keys {
+		if k.keys[i].ID == keyID && k.keys[i].appliesTo(a, b) {
+			return &k.keys[i]
+		}
+	}
+	return nil
+}
+
+// activeKey trả về key msg.KeyID của cặp sender/receiver, lỗi nếu không
+// có hoặc đã hết hiệu lực
+func (k *Keyring) activeKey(msg *message.Message) (*keyEntry, error) {
+	e := k.lookup(msg.KeyID, msg.SenderID, msg.ReceiverID)
+	if e == nil {
+		return nil, fmt.Errorf("unknown key %q for P%d-P%d", msg.KeyID, msg.SenderID, msg.ReceiverID)
+	}
+	if now := k.now(); !e.active(now) {
+		return nil, fmt.Errorf("key %q is not valid at %s", e.ID, now.Format(time.RFC3339))
+	}
+	return e, nil
+}
+
+func computeMAC(secret []byte, msg *message.Message) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(msg.CanonicalBytes())
+	return mac.Sum(nil)
+}
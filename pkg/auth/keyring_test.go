@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+var (
+	secretA = strings.Repeat("ab", 32)
+	secretB = strings.Repeat("cd", 32)
+	day     = 24 * time.Hour
+	t0      = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func testMessage() message.Message {
+	return message.NewMessage(1, 0, 3, "hi", []int{2, 0, 1},
+		[]vectorclock.VectorEntry{{TargetProcessID: 2, Timestamp: []int{1, 1, 0}}})
+}
+
+func newTestKeyring(t *testing.T, now time.Time, keys ...Key) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.now = func() time.Time { return now }
+	return k
+}
+
+func TestSignVerifyDetectsTampering(t *testing.T) {
+	k := newTestKeyring(t, t0, Key{ID: "k1", Secret: secretA})
+
+	tamper := map[string]func(*message.Message){
+		"timestamp": func(m *message.Message) { m.Timestamp[0] = 0 },
+		"vector_p":  func(m *message.Message) { m.VectorP[0].Timestamp[1] = 9 },
+		"drop entry": func(m *message.Message) {
+			m.VectorP = nil
+		},
+		"content":  func(m *message.Message) { m.Content = "bye" },
+		"sender":   func(m *message.Message) { m.SenderID = 2 },
+		"seq":      func(m *message.Message) { m.SeqNum++ },
+		"key id":   func(m *message.Message) { m.KeyID = "k2" },
+		"unsigned": func(m *message.Message) { m.MAC = nil },
+	}
+	for name, mutate := range tamper {
+		t.Run(name, func(t *testing.T) {
+			msg := testMessage()
+			if err := k.Sign(&msg); err != nil {
+				t.Fatal(err)
+			}
+			if err := k.Verify(&msg); err != nil {
+				t.Fatalf("untampered message rejected: %v", err)
+			}
+			mutate(&msg)
+			if err := k.Verify(&msg); !errors.Is(err, message.ErrBadMAC) {
+				t.Fatalf("Verify() = %v, want ErrBadMAC", err)
+			}
+		})
+	}
+}
+
+func TestPairKeysAreIndependent(t *testing.T) {
+	k := newTestKeyring(t, t0, Key{ID: "k1", Secret: secretA})
+	a, b := k.keys[0].pairSecret(0, 1), k.keys[0].pairSecret(1, 0)
+	if string(a) != string(b) {
+		t.Fatal("pair key depends on direction")
+	}
+	if string(a) == string(k.keys[0].pairSecret(0, 2)) {
+		t.Fatal("different pairs share a key")
+	}
+
+	// Key riêng cho P0-P1 không dùng được cho P1-P2
+	k = newTestKeyring(t, t0, Key{ID: "k01", Secret: secretA, Peers: []int{0, 1}})
+	msg := testMessage()
+	if err := k.Sign(&msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.ReceiverID = 2
+	if err := k.Sign(&msg); err == nil {
+		t.Fatal("signed P1-P2 with a P0-P1 key")
+	}
+}
+
+func TestKeyRotationOverlap(t *testing.T) {
+	old := Key{ID: "old", Secret: secretA, NotAfter: t0.Add(2 * day)}
+	next := Key{ID: "new", Secret: secretB, NotBefore: t0.Add(day)}
+
+	// Trước khi key mới có hiệu lực: ký bằng key cũ
+	msg := testMessage()
+	if err := newTestKeyring(t, t0, old, next).Sign(&msg); err != nil || msg.KeyID != "old" {
+		t.Fatalf("Sign() = %v, key %q, want old", err, msg.KeyID)
+	}
+
+	// Trong khoảng chồng nhau: ký bằng key mới, vẫn nhận message ký bằng key cũ
+	overlap := newTestKeyring(t, t0.Add(36*time.Hour), old, next)
+	if err := overlap.Verify(&msg); err != nil {
+		t.Fatalf("old key rejected during overlap: %v", err)
+	}
+	fresh := testMessage()
+	if err := overlap.Sign(&fresh); err != nil || fresh.KeyID != "new" {
+		t.Fatalf("Sign() = %v, key %q, want new", err, fresh.KeyID)
+	}
+
+	// Sau NotAfter của key cũ: từ chối
+	if err := newTestKeyring(t, t0.Add(3*day), old, next).Verify(&msg); !errors.Is(err, message.ErrBadMAC) {
+		t.Fatalf("expired key accepted: %v", err)
+	}
+}
+
+func TestNewKeyringRejectsBadConfig(t *testing.T) {
+	cases := map[string][]Key{
+		"no keys":      nil,
+		"missing id":   {{Secret: secretA}},
+		"not hex":      {{ID: "k", Secret: "zz"}},
+		"short secret": {{ID: "k", Secret: "abcd"}},
+		"bad peers":    {{ID: "k", Secret: secretA, Peers: []int{1, 1}}},
+		"empty window": {{ID: "k", Secret: secretA, NotBefore: t0, NotAfter: t0}},
+		"duplicate":    {{ID: "k", Secret: secretA}, {ID: "k", Secret: secretB, Peers: []int{0, 1}}},
+	}
+	for name, keys := range cases {
+		if _, err := NewKeyring(keys); err == nil {
+			t.Errorf("%s: NewKeyring succeeded", name)
+		}
+	}
+}
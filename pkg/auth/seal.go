@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// payloadAEAD tạo AES-256-GCM cho cặp (a, b). Key mã hóa được derive riêng
+// từ secret của cặp để không dùng chung một key cho cả HMAC và AEAD.
+func (e *keyEntry) payloadAEAD(a, b int) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, e.pairSecret(a, b))
+	mac.Write([]byte("ses-payload"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal mã hóa msg.Content vào msg.Ciphertext bằng key hiện hành của cặp
+// sender/receiver. Metadata (Timestamp, VectorP, ...) là associated data:
+// không bị mã hóa nhưng sửa bất kỳ trường nào cũng làm Open thất bại.
+// Gọi Seal trước Sign vì MAC phủ cả Ciphertext.
+func (k *Keyring) Seal(msg *message.Message) error {
+	e := k.signingKey(msg.SenderID, msg.ReceiverID, k.now())
+	if e == nil {
+		return fmt.Errorf("no active key for P%d-P%d", msg.SenderID, msg.ReceiverID)
+	}
+	return seal(e, msg)
+}
+
+// Protect mã hóa msg bằng payloadKeys rồi ký bằng keyring (nil = tắt) với
+// cùng một key, chọn một lần: KeyID nằm trong associated data nên nếu Seal
+// và Sign tự chọn key và một lần xoay key rơi vào giữa, ciphertext và MAC
+// sẽ gắn với hai KeyID khác nhau và receiver từ chối message.
+func Protect(msg *message.Message, keyring, payloadKeys *Keyring) error {
+	pick := payloadKeys
+	if pick == nil {
+		pick = keyring
+	}
+	if pick == nil {
+		return nil
+	}
+	e := pick.signingKey(msg.SenderID, msg.ReceiverID, pick.now())
+	if e == nil {
+		return fmt.Errorf("no active key for P%d-P%d", msg.SenderID, msg.ReceiverID)
+	}
+	if payloadKeys != nil {
+		if err := seal(e, msg); err != nil {
+			return err
+		}
+	}
+	if keyring != nil {
+		signer := keyring.lookup(e.ID, msg.SenderID, msg.ReceiverID)
+		if signer == nil {
+			return fmt.Errorf("key %q for P%d-P%d is not in the signing keyring", e.ID, msg.SenderID, msg.ReceiverID)
+		}
+		sign(signer, msg)
+	}
+	return nil
+}
+
+func seal(e *keyEntry, msg *message.Message) error {
+	aead, err := e.payloadAEAD(msg.SenderID, msg.ReceiverID)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	plaintext := []byte(msg.Content)
+	msg.KeyID = e.ID
+	msg.Content = ""
+	msg.Nonce = nonce
+	msg.Ciphertext = aead.Seal(nil, nonce, plaintext, msg.AssociatedData())
+	return nil
+}
+
+// Authenticate kiểm tra message đến: payload phải được mã hóa bằng key đang
+// có hiệu lực và metadata không bị sửa. Lỗi trả về là
+// *message.ValidationError với Kind = message.ErrBadCiphertext.
+func (k *Keyring) Authenticate(msg *message.Message) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &message.ValidationError{MessageID: msg.ID, Kind: message.ErrBadCiphertext, Detail: fmt.Sprintf(format, args...)}
+	}
+	if !msg.Sealed() || msg.Content != "" {
+		return invalid("payload is not encrypted")
+	}
+	e, err := k.activeKey(msg)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	if _, err := open(e, msg); err != nil {
+		return invalid("%v", err)
+	}
+	return nil
+}
+
+// Open giải mã msg (đã qua Authenticate) và đặt lại Content.
+// Không kiểm tra cửa sổ hiệu lực: message có thể nằm trong buffer đến sau
+// khi key hết hạn.
+func (k *Keyring) Open(msg *message.Message) error {
+	if !msg.Sealed() {
+		return nil
+	}
+	e := k.lookup(msg.KeyID, msg.SenderID, msg.ReceiverID)
+	if e == nil {
+		return fmt.Errorf("unknown key %q for P%d-P%d", msg.KeyID, msg.SenderID, msg.ReceiverID)
+	}
+	plaintext, err := open(e, msg)
+	if err != nil {
+		return err
+	}
+	msg.Content = string(plaintext)
+	msg.Nonce, msg.Ciphertext = nil, nil
+	return nil
+}
+
+func open(e *keyEntry, msg *message.Message) ([]byte, error) {
+	aead, err := e.payloadAEAD(msg.SenderID, msg.ReceiverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("nonce has %d bytes, want %d", len(msg.Nonce), aead.NonceSize())
+	}
+	plaintext, err := aead.Open(nil, msg.Nonce, msg.Ciphertext, msg.AssociatedData())
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed (key %q): %w", e.ID, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	k := newTestKeyring(t, t0, Key{ID: "k1", Secret: secretA})
+	msg := testMessage()
+	if err := k.Seal(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "" || !msg.Sealed() {
+		t.Fatalf("sealed message still has plaintext: %+v", msg)
+	}
+	if err := k.Authenticate(&msg); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if err := k.Open(&msg); err != nil || msg.Content != "hi" || msg.Sealed() {
+		t.Fatalf("Open() = %v, content %q", err, msg.Content)
+	}
+}
+
+func TestAuthenticateRejectsTampering(t *testing.T) {
+	k := newTestKeyring(t, t0, Key{ID: "k1", Secret: secretA})
+
+	tamper := map[string]func(*message.Message){
+		"timestamp":  func(m *message.Message) { m.Timestamp[1] = 7 },
+		"vector_p":   func(m *message.Message) { m.VectorP[0].TargetProcessID = 0 },
+		"ciphertext": func(m *message.Message) { m.Ciphertext[0] ^= 1 },
+		"nonce":      func(m *message.Message) { m.Nonce = m.Nonce[1:] },
+		"plaintext":  func(m *message.Message) { m.Content = "injected" },
+		"unsealed":   func(m *message.Message) { m.Content, m.Nonce, m.Ciphertext = "hi", nil, nil },
+	}
+	for name, mutate := range tamper {
+		t.Run(name, func(t *testing.T) {
+			msg := testMessage()
+			if err := k.Seal(&msg); err != nil {
+				t.Fatal(err)
+			}
+			mutate(&msg)
+			if err := k.Authenticate(&msg); !errors.Is(err, message.ErrBadCiphertext) {
+				t.Fatalf("Authenticate() = %v, want ErrBadCiphertext", err)
+			}
+		})
+	}
+}
+
+func TestSealThenSign(t *testing.T) {
+	k := newTestKeyring(t, t0, Key{ID: "k1", Secret: secretA})
+	msg := testMessage()
+	if err := k.Seal(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Sign(&msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Ciphertext[0] ^= 1
+	if err := k.Verify(&msg); !errors.Is(err, message.ErrBadMAC) {
+		t.Fatalf("MAC does not cover ciphertext: %v", err)
+	}
+}
+
+// Key mới có hiệu lực giữa lúc mã hóa và lúc ký: Protect chỉ chọn key một
+// lần nên ciphertext và MAC cùng gắn với một KeyID
+func TestProtectUsesOneKeyAcrossRotation(t *testing.T) {
+	old := Key{ID: "old", Secret: secretA}
+	next := Key{ID: "new", Secret: secretB, NotBefore: t0.Add(time.Hour)}
+	k := newTestKeyring(t, t0, old, next)
+	calls := 0
+	k.now = func() time.Time {
+		calls++
+		if calls == 1 {
+			return t0
+		}
+		return t0.Add(2 * time.Hour)
+	}
+
+	msg := testMessage()
+	if err := Protect(&msg, k, k); err != nil {
+		t.Fatal(err)
+	}
+	if msg.KeyID != "old" {
+		t.Fatalf("KeyID = %q, want old", msg.KeyID)
+	}
+	if err := k.Authenticate(&msg); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if err := k.Verify(&msg); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Package bench chạy một cluster trong cùng chương trình dưới một workload
+// và đo throughput, độ trễ deliver, tỉ lệ buffer, overhead piggyback và tài
+// nguyên sử dụng, để so sánh các lựa chọn thuật toán/codec giữa các commit.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/process"
+	"github.com/NationalWind/ses-project/pkg/transport"
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+// DefaultTimeout là thời gian tối đa chờ mọi message được deliver
+const DefaultTimeout = 2 * time.Minute
+
+// Config mô tả một lần chạy benchmark
+type Config struct {
+	Processes int
+	Workload  workload.Config // Messages và Rate phải khác 0
+	Seed      int64           // process i dùng seed + i
+
+	// Transport tạo transport cho process id, nil = TCP
+	Transport     func(id int) transport.Transport
+	TransportName string
+
+	Timeout time.Duration // 0 = DefaultTimeout
+}
+
+// Latency là phân bố thời gian từ lúc gửi đến lúc deliver, tính bằng ms
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Resources là CPU và memory của cả chương trình trong lúc chạy. Các trường
+// từ getrusage bằng 0 trên hệ điều hành không hỗ trợ.
+type Resources struct {
+	UserCPUSeconds   float64 `json:"user_cpu_seconds,omitempty"`
+	SystemCPUSeconds float64 `json:"system_cpu_seconds,omitempty"`
+	CPUPercent       float64 `json:"cpu_percent,omitempty"` // 100 = một core
+	MaxRSSBytes      int64   `json:"max_rss_bytes,omitempty"`
+	AllocBytes       uint64  `json:"alloc_bytes"` // tổng số byte Go heap đã cấp phát
+}
+
+// Report là kết quả một lần chạy
+type Report struct {
+	Commit    string    `json:"commit,omitempty"`
+	GoVersion string    `json:"go_version"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	CPUs      int       `json:"cpus"`
+	StartedAt time.Time `json:"started_at"`
+
+	Processes int             `json:"processes"`
+	Transport string          `json:"transport"`
+	Workload  workload.Config `json:"workload"`
+	Seed      int64           `json:"seed"`
+
+	Sent             int       `json:"sent"`
+	Delivered        int       `json:"delivered"`
+	DurationSeconds  float64   `json:"duration_seconds"`
+	Throughput       float64   `json:"throughput_per_second"` // message deliver mỗi giây
+	Latency          Latency   `json:"latency_ms"`
+	BufferedFraction float64   `json:"buffered_fraction"`   // tỉ lệ message phải vào buffer
+	PiggybackBytes   float64   `json:"avg_piggyback_bytes"` // tm + V_M, mã hóa JSON
+	MaxVectorP       int       `json:"max_vector_p_entries"`
+	Resources        Resources `json:"resources"`
+}
+
+// Run chạy workload trên cluster mới và trả về report khi mọi message đã
+// được deliver
+func Run(cfg Config) (*Report, error) {
+	if cfg.Processes < 2 {
+		return nil, fmt.Errorf("bench needs at least 2 processes, got %d", cfg.Processes)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = func(int) transport.Transport { return transport.TCP{} }
+		cfg.TransportName = "tcp"
+	}
+
+	cluster := process.NewCluster(cfg.Processes, io.Discard)
+	for _, p := range cluster.Processes {
+		g, err := workload.New(cfg.Workload, p.ID, cfg.Processes, cfg.Seed+int64(p.ID))
+		if err != nil {
+			return nil, err
+		}
+		p.SetWorkload(g)
+	}
+	if err := cluster.Listen(cfg.Transport); err != nil {
+		return nil, err
+	}
+	defer cluster.Close()
+
+	report := newReport(cfg)
+	var memBefore, memAfter runtime.MemStats
+	runtime.ReadMemStats(&memBefore)
+	usageBefore := readUsage()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for _, p := range cluster.Processes {
+		wg.Add(1)
+		go func(p *process.Process) {
+			defer wg.Done()
+			p.RunWorkload()
+		}(p)
+	}
+	wg.Wait()
+	if err := cluster.WaitIdle(cfg.Timeout); err != nil {
+		return nil, err
+	}
+
+	elapsed := time.Since(start)
+	usageAfter := readUsage()
+	runtime.ReadMemStats(&memAfter)
+
+	report.DurationSeconds = elapsed.Seconds()
+	report.Resources = usageAfter.since(usageBefore, elapsed)
+	report.Resources.AllocBytes = memAfter.TotalAlloc - memBefore.TotalAlloc
+	report.measure(cluster)
+	return report, nil
+}
+
+func newReport(cfg Config) *Report {
+	r := &Report{
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		StartedAt: time.Now().UTC().Truncate(time.Second),
+		Processes: cfg.Processes,
+		Transport: cfg.TransportName,
+		Workload:  cfg.Workload,
+		Seed:      cfg.Seed,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		dirty := false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				r.Commit = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if dirty && r.Commit != "" {
+			r.Commit += "-dirty"
+		}
+	}
+	return r
+}
+
+// measure tính các chỉ số từ state cuối cùng của cluster
+func (r *Report) measure(cluster *process.Cluster) {
+	var latencies []time.Duration
+	var piggyback, buffered int
+	for _, p := range cluster.Processes {
+		stats := p.GetStats()
+		for _, n := range stats["sent_messages"].(map[int]int) {
+			r.Sent += n
+		}
+		buffered += stats["buffered_total"].(int)
+		r.MaxVectorP = max(r.MaxVectorP, len(p.VectorClock.GetEntries()))
+
+		for _, msg := range p.DeliveredMessages() {
+			piggyback += piggybackSize(msg)
+			r.MaxVectorP = max(r.MaxVectorP, len(msg.VectorP))
+		}
+		latencies = append(latencies, p.Latencies()...)
+	}
+
+	r.Delivered = len(latencies)
+	if r.Delivered == 0 {
+		return
+	}
+	r.Throughput = float64(r.Delivered) / r.DurationSeconds
+	r.BufferedFraction = float64(buffered) / float64(r.Delivered)
+	r.PiggybackBytes = float64(piggyback) / float64(r.Delivered)
+	r.Latency = summarize(latencies)
+}
+
+// piggybackSize là số byte tm và V_M chiếm trong message đã mã hóa
+func piggybackSize(msg message.Message) int {
+	tm, _ := json.Marshal(msg.Timestamp)
+	vm, _ := json.Marshal(msg.VectorP)
+	return len(tm) + len(vm)
+}
+
+// summarize tính mean và các percentile (nearest-rank) theo ms
+func summarize(latencies []time.Duration) Latency {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, d := range latencies {
+		total += d
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return Latency{
+		Mean: ms(total / time.Duration(len(latencies))),
+		P50:  ms(percentile(latencies, 50)),
+		P90:  ms(percentile(latencies, 90)),
+		P99:  ms(percentile(latencies, 99)),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
+
+// percentile trả về giá trị nhỏ nhất mà ít nhất p% số mẫu không vượt quá
+// (sorted đã sắp xếp tăng dần, khác rỗng)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
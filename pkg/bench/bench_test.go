@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+func TestRunReportsEveryDelivery(t *testing.T) {
+	report, err := Run(Config{
+		Processes: 3,
+		Workload:  workload.Config{Arrivals: "poisson", Messages: 20, Rate: 60000, ChainProbability: 0.2, ChainDepth: 2},
+		Seed:      1,
+		Timeout:   10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chuỗi request/response sinh thêm message ngoài 3 × 20 message đầu
+	if report.Delivered != report.Sent || report.Sent < 60 {
+		t.Fatalf("sent %d, delivered %d", report.Sent, report.Delivered)
+	}
+	l := report.Latency
+	if l.P50 <= 0 || l.P50 > l.P90 || l.P90 > l.P99 || l.P99 > l.Max {
+		t.Fatalf("latency percentiles out of order: %+v", l)
+	}
+	if report.Throughput <= 0 || report.PiggybackBytes <= 0 || report.MaxVectorP > 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.BufferedFraction < 0 || report.BufferedFraction > 1 {
+		t.Fatalf("buffered fraction %v", report.BufferedFraction)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Delivered != report.Delivered {
+		t.Fatalf("JSON round trip: %v, %+v", err, decoded)
+	}
+
+	buf.Reset()
+	if err := report.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "| Throughput |") || !strings.Contains(buf.String(), "3 processes over tcp") {
+		t.Fatalf("markdown:\n%s", buf.String())
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for p, want := range map[float64]time.Duration{50: 50, 90: 90, 99: 99, 100: 100, 0: 1} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+	if got := percentile([]time.Duration{7}, 99); got != 7 {
+		t.Errorf("percentile of one sample = %v", got)
+	}
+}
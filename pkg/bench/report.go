@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteJSON ghi report dạng JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown ghi report dạng bảng Markdown
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	commit := r.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+	wl := r.Workload
+	rows := [][2]string{
+		{"Commit", "`" + commit + "`"},
+		{"Started", r.StartedAt.Format("2006-01-02 15:04:05 MST")},
+		{"Environment", fmt.Sprintf("%s, %s/%s, %d CPUs", r.GoVersion, r.OS, r.Arch, r.CPUs)},
+		{"Cluster", fmt.Sprintf("%d processes over %s", r.Processes, r.Transport)},
+		{"Workload", fmt.Sprintf("%s arrivals, %s destinations, %d messages/process at %.0f/min, seed %d",
+			wl.Arrivals, orDefault(wl.Destinations, "uniform"), wl.Messages, wl.Rate, r.Seed)},
+		{"Messages", fmt.Sprintf("%d sent, %d delivered", r.Sent, r.Delivered)},
+		{"Duration", fmt.Sprintf("%.2f s", r.DurationSeconds)},
+		{"Throughput", fmt.Sprintf("%.1f msg/s", r.Throughput)},
+		{"Latency (ms)", fmt.Sprintf("mean %.2f, p50 %.2f, p90 %.2f, p99 %.2f, max %.2f",
+			r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)},
+		{"Buffered", fmt.Sprintf("%.1f%%", 100*r.BufferedFraction)},
+		{"Piggyback", fmt.Sprintf("%.1f bytes/message", r.PiggybackBytes)},
+		{"Max V_P", fmt.Sprintf("%d entries", r.MaxVectorP)},
+		{"CPU", r.cpu()},
+		{"Memory", r.memory()},
+	}
+
+	if _, err := fmt.Fprintf(w, "# SES benchmark\n\n| Metric | Value |\n|--------|-------|\n"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "| %s | %s |\n", row[0], row[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Report) cpu() string {
+	res := r.Resources
+	if res.UserCPUSeconds == 0 && res.SystemCPUSeconds == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f s user, %.2f s system (%.0f%% of one core)",
+		res.UserCPUSeconds, res.SystemCPUSeconds, res.CPUPercent)
+}
+
+func (r *Report) memory() string {
+	alloc := fmt.Sprintf("%.1f MiB allocated", float64(r.Resources.AllocBytes)/(1<<20))
+	if r.Resources.MaxRSSBytes == 0 {
+		return alloc
+	}
+	return fmt.Sprintf("%s, max RSS %.1f MiB", alloc, float64(r.Resources.MaxRSSBytes)/(1<<20))
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
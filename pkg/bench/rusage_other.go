@@ -0,0 +1,15 @@
+//go:build !unix
+
+package bench
+
+import "time"
+
+// usage rỗng: getrusage không có trên hệ điều hành này, report chỉ có
+// AllocBytes
+type usage struct{}
+
+func readUsage() usage { return usage{} }
+
+func (u usage) since(before usage, elapsed time.Duration) Resources {
+	return Resources{}
+}
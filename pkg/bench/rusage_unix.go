@@ -0,0 +1,43 @@
+//go:build unix
+
+package bench
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// usage là CPU và memory của chương trình tại một thời điểm
+type usage struct {
+	user, system time.Duration
+	maxRSS       int64 // byte
+}
+
+func readUsage() usage {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return usage{}
+	}
+	// ru_maxrss tính bằng KB trên Linux/BSD, bằng byte trên macOS
+	rss := int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		rss *= 1024
+	}
+	return usage{
+		user:   time.Duration(ru.Utime.Nano()),
+		system: time.Duration(ru.Stime.Nano()),
+		maxRSS: rss,
+	}
+}
+
+// since là tài nguyên đã dùng từ before đến u, trong khoảng thời gian elapsed
+func (u usage) since(before usage, elapsed time.Duration) Resources {
+	user, system := u.user-before.user, u.system-before.system
+	return Resources{
+		UserCPUSeconds:   user.Seconds(),
+		SystemCPUSeconds: system.Seconds(),
+		CPUPercent:       100 * (user + system).Seconds() / elapsed.Seconds(),
+		MaxRSSBytes:      u.maxRSS,
+	}
+}
@@ -0,0 +1,295 @@
+// Package config đọc, điền giá trị mặc định, áp dụng biến môi trường và kiểm
+// tra file cấu hình của cluster.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/auth"
+	"github.com/NationalWind/ses-project/pkg/topology"
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+const (
+	// DefaultPath là file config khi không có --config hay SES_CONFIG
+	DefaultPath = "config/config.json"
+	// DefaultCertDir là thư mục certificate của gencerts và TLS
+	DefaultCertDir = "certs"
+	// DefaultSocketDir là thư mục socket khi transport.type = unix
+	DefaultSocketDir = "sockets"
+	// DefaultBasePort là port của P0 khi config không liệt kê processes
+	DefaultBasePort = 8000
+)
+
+type Config struct {
+	NumProcesses       int              `json:"num_processes"` // 0 = số phần tử của processes
+	MessagesPerProcess int              `json:"messages_per_process"`
+	MessagesPerMinute  int              `json:"messages_per_minute"`
+	BufferLimit        int              `json:"buffer_limit"`    // 0 = không giới hạn
+	OverflowPolicy     string           `json:"overflow_policy"` // reject | spill | fail
+	Seed               int64            `json:"seed"`            // 0 = lấy theo thời gian
+	Record             bool             `json:"record"`          // ghi lại thứ tự message đến để replay
+	Trace              bool             `json:"trace"`           // ghi span OTLP/JSON của mọi message
+	Transport          TransportConfig  `json:"transport"`
+	Topology           topology.Config  `json:"topology"`
+	TLS                TLSConfig        `json:"tls"`
+	Auth               AuthConfig       `json:"auth"`
+	Encryption         EncryptionConfig `json:"encryption"`
+	Workload           workload.Config  `json:"workload"` // arrivals rỗng = gửi đều cho mọi process
+	Log                LogConfig        `json:"log"`
+	HLC                HLCConfig        `json:"hlc"`
+	Processes          []ProcessConfig  `json:"processes"` // rỗng = localhost, port 8000 + id
+
+	// Số message total order mỗi process multicast khi --send (0 = không)
+	TotalOrderMessages int `json:"total_order_messages"`
+}
+
+// TransportConfig chọn cách các process kết nối với nhau
+type TransportConfig struct {
+	Type         string  `json:"type"`          // tcp (mặc định) | unix | udp
+	SocketDir    string  `json:"socket_dir"`    // thư mục socket khi type = unix
+	LoopbackOnly bool    `json:"loopback_only"` // tcp: chỉ listen/dial trên loopback
+	UDPLoss      float64 `json:"udp_loss"`      // udp: xác suất bỏ datagram, để thử retransmission
+}
+
+// TLSConfig bật mutual TLS giữa các process
+type TLSConfig struct {
+	Enabled bool   `json:"enabled"`
+	CAFile  string `json:"ca_file"`
+}
+
+// AuthConfig bật HMAC cho mọi message, dùng shared key thay cho TLS
+type AuthConfig struct {
+	Enabled bool       `json:"enabled"`
+	Keys    []auth.Key `json:"keys"`
+}
+
+// EncryptionConfig bật mã hóa Content end-to-end, dùng key trong auth.keys
+type EncryptionConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// HLCConfig cấu hình hybrid logical clock dùng để sắp log
+type HLCConfig struct {
+	// MaxOffset là độ lệch giờ tối đa giữa các process, dạng duration của Go
+	// ("500ms"): HLC nhận được đi trước giờ local hơn mức này bị giới hạn
+	// lại. Rỗng hoặc "0" = không giới hạn.
+	MaxOffset string `json:"max_offset"`
+}
+
+// HLCMaxOffset trả về hlc.max_offset (0 nếu không đặt hoặc không hợp lệ,
+// xem Validate)
+func (c *Config) HLCMaxOffset() time.Duration {
+	d, _ := parseOffset(c.HLC.MaxOffset)
+	return d
+}
+
+func parseOffset(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("want a duration such as \"500ms\", got %q", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative (0 = no limit), got %v", d)
+	}
+	return d, nil
+}
+
+// LogConfig chọn level và format cho từng nơi nhận log (sink)
+type LogConfig struct {
+	File    LogSink   `json:"file"`    // process_N.log trong thư mục run
+	Console LogSink   `json:"console"` // stdout của ses run
+	Sinks   []LogSink `json:"sinks"`   // các sink thêm
+}
+
+// LogSink là một nơi nhận log của process
+type LogSink struct {
+	// Path chỉ dùng trong log.sinks: stdout, stderr hoặc file. "{id}" được
+	// thay bằng ID process; đường dẫn tương đối nằm trong thư mục run.
+	Path   string `json:"path,omitempty"`
+	Level  string `json:"level"`  // debug | info | warn | error | off
+	Format string `json:"format"` // line | text | json
+}
+
+type ProcessConfig struct {
+	ID       int    `json:"id"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	CertFile string `json:"cert_file,omitempty"` // mặc định p<ID>.pem cạnh tls.ca_file
+	KeyFile  string `json:"key_file,omitempty"`  // mặc định p<ID>-key.pem cạnh tls.ca_file
+	Admin    string `json:"admin,omitempty"`     // host:port của admin endpoint, rỗng = tắt
+}
+
+// Default trả về config với mọi giá trị mặc định. Processes để trống vì
+// phụ thuộc vào num_processes (xem Load).
+func Default() *Config {
+	return &Config{
+		MessagesPerProcess: 150,
+		MessagesPerMinute:  100,
+		OverflowPolicy:     "reject",
+		Transport:          TransportConfig{Type: "tcp", SocketDir: DefaultSocketDir},
+		Topology:           topology.Config{Type: "full"},
+		TLS:                TLSConfig{CAFile: DefaultCertDir + "/ca.pem"},
+		Log: LogConfig{
+			File:    LogSink{Level: "debug", Format: "line"},
+			Console: LogSink{Level: "info", Format: "line"},
+		},
+	}
+}
+
+// Load đọc config từ path (JSON, YAML hoặc TOML, chọn theo phần mở rộng):
+// trường không có trong file giữ giá trị mặc định, sau đó biến môi trường
+// SES_* (xem ApplyEnv) ghi đè, rồi kiểm tra toàn bộ. Trường không có trong
+// schema là lỗi.
+func Load(path string) (*Config, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseAs(data, format, os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse là Load với nội dung file JSON và biến môi trường cho trước
+func Parse(data []byte, environ []string) (*Config, error) {
+	return ParseAs(data, FormatJSON, environ)
+}
+
+// ParseAs là Parse cho file định dạng format. YAML và TOML được dịch sang
+// JSON trước nên có cùng ngữ nghĩa.
+func ParseAs(data []byte, format Format, environ []string) (*Config, error) {
+	c := Default()
+	if format == FormatJSON {
+		if err := decodeJSON(data, c); err != nil {
+			return nil, describeJSONError(data, err)
+		}
+	} else {
+		tree, err := parseTree(data, format)
+		if err != nil {
+			return nil, err
+		}
+		js, err := json.Marshal(tree)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeJSON(js, c); err != nil {
+			return nil, describeFieldError(err)
+		}
+	}
+	// Danh sách processes mặc định được tạo trước ApplyEnv để
+	// SES_PROCESSES_2_PORT sửa được nó, với số process sau khi đã áp dụng
+	// SES_NUM_PROCESSES
+	if len(c.Processes) == 0 {
+		n := c.NumProcesses
+		if v, ok := envValue(environ, EnvPrefix+"NUM_PROCESSES"); ok {
+			if env, err := strconv.Atoi(v); err == nil {
+				n = env
+			}
+		}
+		for id := 0; id < n; id++ {
+			c.Processes = append(c.Processes, ProcessConfig{ID: id, Address: "localhost", Port: DefaultBasePort + id})
+		}
+	}
+	if err := ApplyEnv(c, environ); err != nil {
+		return nil, err
+	}
+	if c.NumProcesses == 0 {
+		c.NumProcesses = len(c.Processes)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return c, nil
+}
+
+func decodeJSON(data []byte, c *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(c)
+}
+
+// describeJSONError thêm dòng và cột vào lỗi cú pháp/kiểu của JSON
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	var syntax *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		offset = syntax.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	line, col := 1, 1
+	for _, b := range data[:min(int(offset), len(data))] {
+		if b == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("line %d, column %d: %w", line, col, err)
+}
+
+// describeFieldError viết lại lỗi kiểu của JSON đã dịch từ YAML/TOML theo
+// tên trường, vì vị trí trong JSON trung gian không có ý nghĩa với người dùng
+func describeFieldError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("%s: cannot use a %s as %s", typeErr.Field, typeErr.Value, typeErr.Type)
+	}
+	return err
+}
+
+// Process trả về config của process id
+func (c *Config) Process(id int) (ProcessConfig, bool) {
+	for _, pc := range c.Processes {
+		if pc.ID == id {
+			return pc, true
+		}
+	}
+	return ProcessConfig{}, false
+}
+
+// Peers trả về địa chỉ host:port của mọi process khác id
+func (c *Config) Peers(id int) map[int]string {
+	peers := make(map[int]string)
+	for _, pc := range c.Processes {
+		if pc.ID != id {
+			peers[pc.ID] = fmt.Sprintf("%s:%d", pc.Address, pc.Port)
+		}
+	}
+	return peers
+}
+
+// WorkloadConfig là config.workload với messages và rate mặc định lấy từ
+// messages_per_process và messages_per_minute, để tổng traffic như cách gửi
+// cũ
+func (c *Config) WorkloadConfig() workload.Config {
+	w := c.Workload
+	if w.Messages == 0 {
+		w.Messages = c.MessagesPerProcess * (c.NumProcesses - 1)
+	}
+	if w.Rate == 0 {
+		w.Rate = float64(c.MessagesPerMinute)
+	}
+	return w
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadShippedConfig(t *testing.T) {
+	c, err := Load("../../config/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NumProcesses != len(c.Processes) {
+		t.Fatalf("num_processes %d, %d processes", c.NumProcesses, len(c.Processes))
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	c, err := Parse([]byte(`{"num_processes": 3}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MessagesPerMinute != 100 || c.OverflowPolicy != "reject" || c.Transport.Type != "tcp" || c.TLS.CAFile != "certs/ca.pem" {
+		t.Fatalf("defaults not applied: %+v", c)
+	}
+	if len(c.Processes) != 3 || c.Processes[2] != (ProcessConfig{ID: 2, Address: "localhost", Port: 8002}) {
+		t.Fatalf("processes = %+v", c.Processes)
+	}
+	if peers := c.Peers(0); len(peers) != 2 || peers[1] != "localhost:8001" {
+		t.Fatalf("peers = %v", peers)
+	}
+
+	// num_processes mặc định là số process được liệt kê
+	c, err = Parse([]byte(`{"processes": [{"id": 0, "address": "a", "port": 1}, {"id": 1, "address": "b", "port": 1}]}`), nil)
+	if err != nil || c.NumProcesses != 2 {
+		t.Fatalf("Parse() = %+v, %v", c, err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := Default()
+	err := json.Unmarshal([]byte(`{
+		"num_processes": 4,
+		"messages_per_minute": 0,
+		"overflow_policy": "drop",
+		"log": {"console": {"level": "loud"}, "sinks": [{"format": "xml"}]},
+		"hlc": {"max_offset": "-1s"},
+		"processes": [
+			{"id": 0, "address": "localhost", "port": 8000, "admin": "localhost"},
+			{"id": 0, "address": "localhost", "port": 8001},
+			{"id": 2, "address": "localhost", "port": 8000}
+		]
+	}`), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Validate()
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe *FieldError
+		if errors.As(e, &fe) {
+			fields = append(fields, fe.Field)
+		}
+	}
+	want := []string{"processes", "messages_per_minute", "overflow_policy", "processes[0].admin", "processes[1].id", "processes[2].port",
+		"log.console.level", "log.sinks[0].path", "log.sinks[0].format", "hlc.max_offset"}
+	if strings.Join(fields, " ") != strings.Join(want, " ") {
+		t.Fatalf("fields = %v, want %v\n%v", fields, want, err)
+	}
+}
+
+func TestUnknownFieldAndSyntaxErrors(t *testing.T) {
+	if _, err := Parse([]byte(`{"num_processes": 2, "mesages_per_process": 5}`), nil); err == nil || !strings.Contains(err.Error(), "mesages_per_process") {
+		t.Fatalf("unknown field: %v", err)
+	}
+	if _, err := Parse([]byte("{\n  \"num_processes\": 2,\n  \"seed\": \"x\"\n}"), nil); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("type error: %v", err)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	c, err := Parse([]byte(`{"num_processes": 3}`), []string{
+		"HOME=/root",
+		"SES_CONFIG=ignored.json",
+		"SES_MESSAGES_PER_MINUTE=600",
+		"SES_TLS_ENABLED=true",
+		"SES_TRANSPORT_UDP_LOSS=0.25",
+		"SES_TRANSPORT_TYPE=udp",
+		"SES_PROCESSES=[{\"id\":0,\"address\":\"h0\",\"port\":1},{\"id\":1,\"address\":\"h1\",\"port\":2},{\"id\":2,\"address\":\"h2\",\"port\":3}]",
+		"SES_PROCESSES_1_PORT=9001",
+		"SES_WORKLOAD_ARRIVALS=poisson",
+		"SES_WORKLOAD_HOT_SPOTS=[1, 2]",
+		"SES_AUTH_KEYS=[{\"id\":\"k\",\"secret\":\"00112233445566778899aabbccddeeff\",\"not_after\":\"2030-01-01T00:00:00Z\"}]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MessagesPerMinute != 600 || !c.TLS.Enabled || c.Transport.Type != "udp" || c.Transport.UDPLoss != 0.25 {
+		t.Fatalf("scalar overrides not applied: %+v", c)
+	}
+	if c.Processes[1] != (ProcessConfig{ID: 1, Address: "h1", Port: 9001}) || c.Processes[2].Address != "h2" {
+		t.Fatalf("processes = %+v", c.Processes)
+	}
+	if c.Workload.Arrivals != "poisson" || len(c.Workload.HotSpots) != 2 {
+		t.Fatalf("workload = %+v", c.Workload)
+	}
+	if len(c.Auth.Keys) != 1 || !c.Auth.Keys[0].NotAfter.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("keys = %+v", c.Auth.Keys)
+	}
+
+	// Không có processes trong file: danh sách mặc định (theo num_processes
+	// sau khi ghi đè) vẫn sửa được từng phần tử
+	for _, tc := range []struct {
+		env  []string
+		want int
+	}{
+		{[]string{"SES_PROCESSES_2_PORT=9002"}, 3},
+		{[]string{"SES_NUM_PROCESSES=5", "SES_PROCESSES_4_PORT=9002"}, 5},
+	} {
+		c, err := Parse([]byte(`{"num_processes": 3}`), tc.env)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.env, err)
+		}
+		last := c.Processes[len(c.Processes)-1]
+		if c.NumProcesses != tc.want || len(c.Processes) != tc.want || last.Port != 9002 || c.Processes[1].Port != DefaultBasePort+1 {
+			t.Fatalf("%v: num_processes=%d processes=%+v", tc.env, c.NumProcesses, c.Processes)
+		}
+	}
+
+	for env, want := range map[string]string{
+		"SES_NUM_PROCESES=3":        "does not match any config field",
+		"SES_PROCESSES_7_PORT=1":    "does not match any config field",
+		"SES_RECORD=yes":            "want true or false",
+		"SES_BUFFER_LIMIT=lots":     "want an integer",
+		"SES_TOPOLOGY_EDGES=[[0,1]": "want JSON",
+	} {
+		if _, err := Parse([]byte(`{"num_processes": 3}`), []string{env}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", env, err, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix đứng trước tên mọi biến môi trường ghi đè config
+const EnvPrefix = "SES_"
+
+// EnvConfigPath là biến môi trường chọn file config (thay cho --config)
+const EnvConfigPath = "SES_CONFIG"
+
+// ApplyEnv ghi đè config bằng các biến SES_* trong environ (dạng KEY=value).
+// Tên biến là đường dẫn JSON viết hoa, nối bằng "_", ví dụ:
+//
+//	SES_NUM_PROCESSES=4
+//	SES_TRANSPORT_TYPE=unix
+//	SES_PROCESSES_2_PORT=9002      (phần tử thứ 2 của processes)
+//	SES_AUTH_KEYS='[{"id": "k1", "secret": "..."}]'
+//
+// Trường số, bool và string nhận giá trị dạng text; struct, slice và thời
+// gian nhận JSON (thời gian dạng RFC 3339). Biến SES_* không khớp trường
+// nào là lỗi, để lỗi chính tả không bị bỏ qua.
+func ApplyEnv(c *Config, environ []string) error {
+	type override struct{ name, value string }
+	var overrides []override
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) || name == EnvConfigPath {
+			continue
+		}
+		overrides = append(overrides, override{name, value})
+	}
+	// Trường cha trước trường con: SES_PROCESSES thay cả danh sách, sau đó
+	// SES_PROCESSES_0_PORT mới sửa phần tử
+	sort.Slice(overrides, func(i, j int) bool {
+		if len(overrides[i].name) != len(overrides[j].name) {
+			return len(overrides[i].name) < len(overrides[j].name)
+		}
+		return overrides[i].name < overrides[j].name
+	})
+
+	for _, o := range overrides {
+		field, ok := lookup(reflect.ValueOf(c).Elem(), strings.TrimPrefix(o.name, EnvPrefix))
+		if !ok {
+			return fmt.Errorf("%s does not match any config field", o.name)
+		}
+		if err := setValue(field, o.value); err != nil {
+			return fmt.Errorf("%s=%q: %w", o.name, o.value, err)
+		}
+	}
+	return nil
+}
+
+// envValue trả về giá trị của biến name trong environ
+func envValue(environ []string, name string) (string, bool) {
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// lookup tìm trường có đường dẫn name (viết hoa, nối bằng "_") trong v
+func lookup(v reflect.Value, name string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			key := strings.ToUpper(tag)
+			if name == key {
+				return v.Field(i), true
+			}
+			if rest, ok := strings.CutPrefix(name, key+"_"); ok {
+				if field, ok := lookup(v.Field(i), rest); ok {
+					return field, true
+				}
+			}
+		}
+	case reflect.Slice:
+		index, rest, _ := strings.Cut(name, "_")
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= v.Len() {
+			return reflect.Value{}, false
+		}
+		if rest == "" {
+			return v.Index(i), true
+		}
+		return lookup(v.Index(i), rest)
+	}
+	return reflect.Value{}, false
+}
+
+// setValue gán text cho trường v
+func setValue(v reflect.Value, text string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(text))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("want true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("want an integer")
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("want a number")
+		}
+		v.SetFloat(f)
+	default:
+		// Giải mã vào giá trị mới để slice được thay hẳn, không trộn với cũ
+		fresh := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(text), fresh.Interface()); err != nil {
+			return fmt.Errorf("want JSON: %w", err)
+		}
+		v.Set(fresh.Elem())
+	}
+	return nil
+}
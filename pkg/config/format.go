@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Format là định dạng file config
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatOf chọn định dạng theo phần mở rộng của path
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("%s: unknown config format (want .json, .yaml, .yml or .toml)", path)
+}
+
+// ParseFormat chuyển tên định dạng ("json", "yaml", "yml", "toml") thành Format
+func ParseFormat(name string) (Format, error) {
+	return FormatOf("." + name)
+}
+
+// Convert dịch file config data từ định dạng from sang to. Thứ tự các trường
+// được giữ nguyên, comment thì không. Kết quả được kiểm tra là load ra đúng
+// config như data.
+func Convert(data []byte, from, to Format) ([]byte, error) {
+	tree, err := parseTree(data, from)
+	if err != nil {
+		return nil, err
+	}
+	out, err := encodeTree(tree, to)
+	if err != nil {
+		return nil, err
+	}
+
+	want, err := ParseAs(data, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	got, err := ParseAs(out, to, nil)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		return nil, fmt.Errorf("%s output does not load back to the same config (%v)", to, err)
+	}
+	return out, nil
+}
+
+// object là một mapping giữ thứ tự key, để convert không xáo trộn file.
+// Cây config gồm *object, []any, string, bool, json.Number và nil.
+type object struct {
+	members []member
+}
+
+type member struct {
+	key   string
+	value any
+}
+
+func (o *object) get(key string) (any, bool) {
+	for _, m := range o.members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return nil, false
+}
+
+// add thêm key mới, false nếu key đã có
+func (o *object) add(key string, value any) bool {
+	if _, ok := o.get(key); ok {
+		return false
+	}
+	o.members = append(o.members, member{key, value})
+	return true
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o.members {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func parseTree(data []byte, format Format) (any, error) {
+	switch format {
+	case FormatJSON:
+		tree, err := parseJSONTree(data)
+		if err != nil {
+			return nil, describeJSONError(data, err)
+		}
+		return tree, nil
+	case FormatYAML:
+		return parseYAML(data)
+	case FormatTOML:
+		return parseTOML(data)
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+func encodeTree(tree any, format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(tree); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case FormatYAML:
+		return encodeYAML(tree)
+	case FormatTOML:
+		return encodeTOML(tree)
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+// parseJSONTree đọc JSON thành cây giữ thứ tự key
+func parseJSONTree(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tree, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return tree, nil
+}
+
+func readJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			if !obj.add(key.(string), value) {
+				return nil, fmt.Errorf("duplicate key %q", key)
+			}
+		}
+		_, err = dec.Token()
+		return obj, err
+	default: // '['
+		list := []any{}
+		for dec.More() {
+			value, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	}
+}
+
+// syntaxError là lỗi cú pháp YAML/TOML tại một dòng
+func syntaxError(line int, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
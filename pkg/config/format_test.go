@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleJSON dùng các trường lồng nhau, list số và thời gian
+const sampleJSON = `{
+  "num_processes": 3,
+  "seed": 42,
+  "transport": {"type": "udp", "udp_loss": 0.05},
+  "topology": {"type": "graph", "edges": [[0, 1], [1, 2]]},
+  "auth": {
+    "enabled": true,
+    "keys": [
+      {"id": "k1", "secret": "00112233445566778899aabbccddeeff", "peers": [0, 2], "not_after": "2030-01-01T00:00:00Z"},
+      {"id": "k #2", "secret": "ffeeddccbbaa99887766554433221100"}
+    ]
+  },
+  "workload": {"arrivals": "poisson", "destinations": "hotspot", "hot_spots": [1], "rate": 1.5e3},
+  "processes": [
+    {"id": 0, "address": "10.0.0.1", "port": 9000},
+    {"id": 1, "address": "10.0.0.2", "port": 9000},
+    {"id": 2, "address": "10.0.0.3", "port": 9000}
+  ]
+}`
+
+func TestConvertRoundTrip(t *testing.T) {
+	shipped, err := os.ReadFile("../../config/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range [][]byte{shipped, []byte(sampleJSON)} {
+		want, err := Parse(input, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, format := range []Format{FormatYAML, FormatTOML, FormatJSON} {
+			out, err := Convert(input, FormatJSON, format)
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			got, err := ParseAs(out, format, nil)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s does not round trip (%v):\n%s", format, err, out)
+			}
+			back, err := Convert(out, format, FormatJSON)
+			if err != nil {
+				t.Fatalf("%s back to JSON: %v", format, err)
+			}
+			if got, _ := Parse(back, nil); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s → JSON changed the config:\n%s", format, back)
+			}
+		}
+	}
+}
+
+func TestHandWrittenYAMLAndTOML(t *testing.T) {
+	want, err := Parse([]byte(sampleJSON), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yaml := `---
+# cluster thử nghiệm
+num_processes: 3
+seed: 42   # cố định
+transport: {type: udp, udp_loss: .05}
+topology:
+  type: "graph"
+  edges:
+  - [0, 1]
+  - [1,
+     2]
+auth:
+  enabled: yes-no-maybe
+  keys:
+    - id: k1
+      secret: '00112233445566778899aabbccddeeff'
+      peers: [0, 2]
+      not_after: 2030-01-01T00:00:00Z
+    - {id: "k #2", secret: ffeeddccbbaa99887766554433221100}
+workload:
+  arrivals: poisson
+  destinations: hotspot
+  hot_spots:
+    - 1
+  rate: 1500
+processes:
+  - id: 0
+    address: 10.0.0.1
+    port: 9000
+  - {id: 1, address: 10.0.0.2, port: 0x2328}
+  - id: 2
+    address: "10.0.0.3"
+    port: 9_000
+`
+	// enabled phải là bool; sửa lại để so với JSON
+	if _, err := ParseAs([]byte(yaml), FormatYAML, nil); err == nil || !strings.Contains(err.Error(), "auth.enabled") {
+		t.Fatalf("string for a bool field: %v", err)
+	}
+	yaml = strings.Replace(yaml, "yes-no-maybe", "true", 1)
+	yaml = strings.Replace(yaml, "9_000", "9000", 1)
+	got, err := ParseAs([]byte(yaml), FormatYAML, nil)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("YAML: %v\ngot  %+v\nwant %+v", err, got, want)
+	}
+
+	toml := `# cluster thử nghiệm
+num_processes = 3
+seed = 4_2
+transport = { type = "udp", udp_loss = 5e-2 }
+
+[topology]
+type = 'graph'
+edges = [
+  [0, 1],
+  [1, 2], # cạnh cuối
+]
+
+[auth]
+enabled = true
+
+[[auth.keys]]
+id = "k1"
+secret = "00112233445566778899aabbccddeeff"
+peers = [0, 2]
+not_after = 2030-01-01T00:00:00Z
+
+[[auth.keys]]
+"id" = "k #2"
+secret = """
+ffeeddccbbaa99887766554433221100"""
+
+[workload]
+arrivals = "poisson"
+destinations = "hotspot"
+hot_spots = [1]
+rate = 1500.0
+
+[[processes]]
+id = 0
+address = "10.0.0.1"
+port = 9000
+
+[[processes]]
+id = 1
+address = "10.0.0.2"
+port = 0x2328
+
+[[processes]]
+id = 2
+address = "10.0.0.3"
+port = 9000
+`
+	got, err = ParseAs([]byte(toml), FormatTOML, nil)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("TOML: %v\ngot  %+v\nwant %+v", err, got, want)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	for _, tc := range []struct {
+		format     Format
+		data, want string
+	}{
+		{FormatYAML, "num_processes: 3\n  seed: 1\n", "line 2: unexpected indentation"},
+		{FormatYAML, "seed: 1\nseed: 2\n", `line 2: duplicate key "seed"`},
+		{FormatYAML, "base: &anchor 1\n", "line 1: unsupported YAML syntax"},
+		{FormatYAML, "edges: [[0, 1]\n", "line 1: unterminated flow collection"},
+		{FormatYAML, "num_processes: 2\nmesages_per_process: 5\n", "mesages_per_process"},
+		{FormatTOML, "seed = 1\n\nseed = 2\n", "line 3: duplicate key seed"},
+		{FormatTOML, "[tls]\nenabled = true\n[tls]\n", "line 3: table tls is already defined"},
+		{FormatTOML, "transport = { type = \"udp\" }\n[transport]\n", "line 2: table transport is already defined"},
+		{FormatTOML, "seed = 1 2\n", "line 1: expected end of line"},
+		{FormatTOML, "seed = \"x\"\n", "seed: cannot use a string as int64"},
+	} {
+		_, err := ParseAs([]byte(tc.data), tc.format, nil)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s %q: err = %v, want %q", tc.format, tc.data, err, tc.want)
+		}
+	}
+
+	if _, err := FormatOf("config.ini"); err == nil {
+		t.Error("FormatOf accepted .ini")
+	}
+	if f, err := FormatOf("cluster.YML"); err != nil || f != FormatYAML {
+		t.Errorf("FormatOf(cluster.YML) = %q, %v", f, err)
+	}
+}
@@ -0,0 +1,618 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bộ đọc TOML 1.0 cho file config: bảng [a.b], mảng bảng [[a]], key có dấu
+// chấm, inline table, mảng nhiều dòng, mọi kiểu chuỗi và số. Ngày giờ được
+// giữ dạng chuỗi RFC 3339 (như trong JSON).
+
+type tomlParser struct {
+	data    string
+	pos     int
+	root    *object
+	current *object
+	// explicit đánh dấu bảng đã có header [..] hoặc tạo bởi key có dấu chấm,
+	// inline đánh dấu inline table (không được mở rộng thêm)
+	explicit map[*object]bool
+	inline   map[*object]bool
+}
+
+func parseTOML(data []byte) (any, error) {
+	p := &tomlParser{data: string(data), root: &object{}, explicit: map[*object]bool{}, inline: map[*object]bool{}}
+	p.current = p.root
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.data) {
+			return p.root, nil
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(p.data[p.pos:], "[["):
+			err = p.header(true)
+		case p.data[p.pos] == '[':
+			err = p.header(false)
+		default:
+			err = p.keyValue(p.current)
+		}
+		if err == nil {
+			err = p.endOfLine()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) fail(format string, args ...any) error {
+	return syntaxError(strings.Count(p.data[:min(p.pos, len(p.data))], "\n")+1, format, args...)
+}
+
+// skipBlank bỏ khoảng trắng và comment, cả xuống dòng nếu newlines
+func (p *tomlParser) skipBlank(newlines bool) {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '\n' || c == '\r':
+			if !newlines {
+				return
+			}
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank(false)
+	if p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+		return p.fail("expected end of line, found %q", p.rest())
+	}
+	return nil
+}
+
+// rest là phần còn lại của dòng hiện tại, để báo lỗi
+func (p *tomlParser) rest() string {
+	line, _, _ := strings.Cut(p.data[p.pos:], "\n")
+	return line
+}
+
+// header đọc [a.b] hoặc [[a.b]] và chuyển bảng hiện tại
+func (p *tomlParser) header(array bool) error {
+	open := 1
+	if array {
+		open = 2
+	}
+	p.pos += open
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(p.data[p.pos:], "]]"[:open]) {
+		return p.fail("expected %q after table name", "]]"[:open])
+	}
+	p.pos += open
+
+	parent, err := p.table(p.root, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	existing, ok := parent.get(last)
+	if array {
+		if !ok {
+			existing = []any{}
+			parent.add(last, existing)
+		}
+		list, isList := existing.([]any)
+		if !isList || p.inlineList(parent, last) {
+			return p.fail("%s is not an array of tables", strings.Join(keys, "."))
+		}
+		p.current = &object{}
+		p.set(parent, last, append(list, p.current))
+		return nil
+	}
+
+	if !ok {
+		p.current = &object{}
+		parent.add(last, p.current)
+	} else if obj, isObj := existing.(*object); isObj && !p.explicit[obj] && !p.inline[obj] {
+		p.current = obj
+	} else {
+		return p.fail("table %s is already defined", strings.Join(keys, "."))
+	}
+	p.explicit[p.current] = true
+	return nil
+}
+
+// inlineList: mảng đã được gán bằng "key = [...]" thì không dùng [[key]] được
+func (p *tomlParser) inlineList(parent *object, key string) bool {
+	for _, m := range parent.members {
+		if m.key == key {
+			list := m.value.([]any)
+			for _, item := range list {
+				if obj, ok := item.(*object); !ok || p.inline[obj] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (p *tomlParser) set(o *object, key string, value any) {
+	for i := range o.members {
+		if o.members[i].key == key {
+			o.members[i].value = value
+		}
+	}
+}
+
+// table đi theo keys từ o, tạo bảng còn thiếu. Với mảng bảng, dùng phần tử
+// cuối. headers = false khi đi theo key có dấu chấm: không được đi qua mảng
+// bảng, và bảng tạo ra không được khai báo lại bằng header.
+func (p *tomlParser) table(o *object, keys []string, headers bool) (*object, error) {
+	for i, key := range keys {
+		value, ok := o.get(key)
+		if !ok {
+			next := &object{}
+			o.add(key, next)
+			if !headers {
+				p.explicit[next] = true
+			}
+			o = next
+			continue
+		}
+		switch v := value.(type) {
+		case *object:
+			if p.inline[v] {
+				return nil, p.fail("cannot extend table %s", strings.Join(keys[:i+1], "."))
+			}
+			o = v
+		case []any:
+			last, isObj := any(nil), false
+			if len(v) > 0 {
+				last = v[len(v)-1]
+				_, isObj = last.(*object)
+			}
+			if !headers || !isObj || p.inline[last.(*object)] {
+				return nil, p.fail("%s is not a table", strings.Join(keys[:i+1], "."))
+			}
+			o = last.(*object)
+		default:
+			return nil, p.fail("%s is already a value", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return o, nil
+}
+
+// keyValue đọc "a.b = value" vào bảng o
+func (p *tomlParser) keyValue(o *object) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	if p.pos >= len(p.data) || p.data[p.pos] != '=' {
+		return p.fail("expected \"=\" after key %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+
+	parent, err := p.table(o, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	if !parent.add(keys[len(keys)-1], value) {
+		return p.fail("duplicate key %s", strings.Join(keys, "."))
+	}
+	return nil
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// key đọc key có thể có dấu chấm: a."b c".d
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank(false)
+		var key string
+		if p.pos < len(p.data) && (p.data[p.pos] == '"' || p.data[p.pos] == '\'') {
+			value, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			key = value
+		} else if m := tomlBareKey.FindString(p.data[p.pos:]); m != "" {
+			key = m
+			p.pos += len(m)
+		} else {
+			return nil, p.fail("expected a key, found %q", p.rest())
+		}
+		keys = append(keys, key)
+		p.skipBlank(false)
+		if p.pos >= len(p.data) || p.data[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+var (
+	tomlDateTime = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[-+]\d{2}:\d{2})?)?|^\d{2}:\d{2}:\d{2}(\.\d+)?`)
+	tomlNumber   = regexp.MustCompile(`^[-+0-9a-zA-Z_.]+`)
+	tomlInt      = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloat    = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+)
+
+func (p *tomlParser) value() (any, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.fail("missing value")
+	}
+	rest := p.data[p.pos:]
+	switch {
+	case rest[0] == '"' || rest[0] == '\'':
+		return p.str()
+	case rest[0] == '[':
+		return p.array()
+	case rest[0] == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(rest, "true") && !tomlBareKey.MatchString(rest[4:]):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false") && !tomlBareKey.MatchString(rest[5:]):
+		p.pos += 5
+		return false, nil
+	}
+	if m := tomlDateTime.FindString(rest); m != "" {
+		p.pos += len(m)
+		return strings.Replace(m, " ", "T", 1), nil
+	}
+
+	token := tomlNumber.FindString(rest)
+	p.pos += len(token)
+	plain := strings.ReplaceAll(token, "_", "")
+	switch {
+	case tomlInt.MatchString(token):
+		n, err := strconv.ParseInt(plain, 10, 64)
+		if err != nil {
+			return nil, p.fail("integer %s out of range", token)
+		}
+		return json.Number(strconv.FormatInt(n, 10)), nil
+	case len(plain) > 2 && plain[0] == '0' && strings.IndexByte("xob", plain[1]) >= 0:
+		n, err := strconv.ParseInt(plain, 0, 64)
+		if err != nil {
+			return nil, p.fail("invalid integer %s", token)
+		}
+		return json.Number(strconv.FormatInt(n, 10)), nil
+	case tomlFloat.MatchString(token):
+		f, err := strconv.ParseFloat(plain, 64)
+		if err != nil {
+			return nil, p.fail("invalid number %s", token)
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	case strings.Contains(token, "inf") || strings.Contains(token, "nan"):
+		return nil, p.fail("%s cannot be represented in a config", token)
+	}
+	return nil, p.fail("invalid value %q", p.rest())
+}
+
+func (p *tomlParser) array() (any, error) {
+	p.pos++
+	list := []any{}
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.data) {
+			return nil, p.fail("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		p.skipBlank(true)
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.data) || p.data[p.pos] != ']' {
+			return nil, p.fail("expected \",\" or \"]\" in array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (any, error) {
+	p.pos++
+	obj := &object{}
+	p.skipBlank(false)
+	if p.pos < len(p.data) && p.data[p.pos] == '}' {
+		p.pos++
+		p.inline[obj] = true
+		return obj, nil
+	}
+	for {
+		if err := p.keyValue(obj); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if p.pos >= len(p.data) {
+			return nil, p.fail("unterminated inline table")
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.markInline(obj)
+			return obj, nil
+		default:
+			return nil, p.fail("expected \",\" or \"}\" in inline table")
+		}
+	}
+}
+
+// markInline đánh dấu inline table và mọi bảng con của nó là đóng
+func (p *tomlParser) markInline(v any) {
+	switch v := v.(type) {
+	case *object:
+		p.inline[v] = true
+		for _, m := range v.members {
+			p.markInline(m.value)
+		}
+	case []any:
+		for _, item := range v {
+			p.markInline(item)
+		}
+	}
+}
+
+// str đọc mọi dạng chuỗi TOML: basic, literal và bản nhiều dòng của chúng
+func (p *tomlParser) str() (string, error) {
+	quote := p.data[p.pos]
+	multi := strings.HasPrefix(p.data[p.pos:], strings.Repeat(string(quote), 3))
+	if multi {
+		p.pos += 3
+		// Xuống dòng ngay sau dấu mở bị bỏ
+		if strings.HasPrefix(p.data[p.pos:], "\r\n") {
+			p.pos += 2
+		} else if strings.HasPrefix(p.data[p.pos:], "\n") {
+			p.pos++
+		}
+	} else {
+		p.pos++
+	}
+
+	var b strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case multi && strings.HasPrefix(p.data[p.pos:], strings.Repeat(string(quote), 3)):
+			p.pos += 3
+			// Tối đa hai dấu nháy ngay trước dấu đóng thuộc về chuỗi
+			for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] == quote; i++ {
+				b.WriteByte(quote)
+				p.pos++
+			}
+			return b.String(), nil
+		case !multi && c == quote:
+			p.pos++
+			return b.String(), nil
+		case !multi && c == '\n':
+			return "", p.fail("newline in string")
+		case c == '\\' && quote == '"':
+			p.pos++
+			if err := p.escape(&b, multi); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.fail("unterminated string")
+}
+
+// escape đọc escape sau "\" trong chuỗi "..."
+func (p *tomlParser) escape(b *strings.Builder, multi bool) error {
+	if p.pos >= len(p.data) {
+		return p.fail("unterminated string")
+	}
+	simple := map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\'}
+	c := p.data[p.pos]
+	if r, ok := simple[c]; ok {
+		b.WriteByte(r)
+		p.pos++
+		return nil
+	}
+	if digits := map[byte]int{'u': 4, 'U': 8}[c]; digits > 0 && p.pos+digits < len(p.data) {
+		code, err := strconv.ParseUint(p.data[p.pos+1:p.pos+1+digits], 16, 32)
+		if err == nil {
+			b.WriteRune(rune(code))
+			p.pos += 1 + digits
+			return nil
+		}
+	}
+	// "\" cuối dòng trong chuỗi nhiều dòng nối dòng sau, bỏ khoảng trắng
+	if multi && strings.TrimLeft(p.rest(), " \t\r") == "" {
+		for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+			p.pos++
+		}
+		return nil
+	}
+	return p.fail("invalid escape \\%c", c)
+}
+
+// encodeTOML ghi cây config dạng TOML: giá trị đơn trước, rồi các bảng con
+// [a.b] và mảng bảng [[a]]. null không có trong TOML nên bị bỏ qua, giống
+// như không khai báo trường đó.
+func encodeTOML(tree any) ([]byte, error) {
+	root, ok := tree.(*object)
+	if !ok {
+		return nil, fmt.Errorf("TOML needs a table at the top level")
+	}
+	var b bytes.Buffer
+	if err := writeTOMLTable(&b, root, nil); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeTOMLTable(b *bytes.Buffer, o *object, path []string) error {
+	for _, m := range o.members {
+		if m.value == nil || tomlIsTable(m.value) || tomlIsTableArray(m.value) {
+			continue
+		}
+		value, err := tomlInline(m.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, m.key), "."), err)
+		}
+		b.WriteString(tomlKey(m.key) + " = " + value + "\n")
+	}
+
+	for _, m := range o.members {
+		sub := append(append([]string(nil), path...), m.key)
+		name := tomlPath(sub)
+		switch {
+		case tomlIsTable(m.value):
+			tomlSeparate(b)
+			b.WriteString("[" + name + "]\n")
+			if err := writeTOMLTable(b, m.value.(*object), sub); err != nil {
+				return err
+			}
+		case tomlIsTableArray(m.value):
+			for _, item := range m.value.([]any) {
+				tomlSeparate(b)
+				b.WriteString("[[" + name + "]]\n")
+				if err := writeTOMLTable(b, item.(*object), sub); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tomlSeparate thêm dòng trống trước header, trừ ở đầu file
+func tomlSeparate(b *bytes.Buffer) {
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+}
+
+func tomlIsTable(v any) bool {
+	obj, ok := v.(*object)
+	return ok && len(obj.members) > 0
+}
+
+func tomlIsTableArray(v any) bool {
+	list, ok := v.([]any)
+	if !ok || len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(*object); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlInline(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", fmt.Errorf("null cannot be written in TOML")
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return string(v), nil
+	case string:
+		return tomlQuote(v), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := tomlInline(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case *object:
+		items := make([]string, 0, len(v.members))
+		for _, m := range v.members {
+			if m.value == nil {
+				continue
+			}
+			s, err := tomlInline(m.value)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(m.key)+" = "+s)
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+func tomlPath(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = tomlKey(key)
+	}
+	return strings.Join(quoted, ".")
+}
+
+func tomlKey(key string) string {
+	if tomlBareKey.FindString(key) == key && key != "" {
+		return key
+	}
+	return tomlQuote(key)
+}
+
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/NationalWind/ses-project/pkg/auth"
+	"github.com/NationalWind/ses-project/pkg/process"
+	"github.com/NationalWind/ses-project/pkg/topology"
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+// FieldError là một lỗi trong config. Field là đường dẫn JSON của trường,
+// ví dụ "processes[2].port".
+type FieldError struct {
+	Field   string
+	Problem string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Problem
+}
+
+// Validate kiểm tra toàn bộ config và trả về mọi lỗi tìm được (errors.Join
+// của các *FieldError), nil nếu hợp lệ
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	n := c.NumProcesses
+	if n < 2 {
+		fail("num_processes", "need at least 2 processes, got %d", n)
+	}
+	if len(c.Processes) != n {
+		fail("processes", "lists %d processes but num_processes is %d", len(c.Processes), n)
+	}
+	if c.MessagesPerProcess < 0 {
+		fail("messages_per_process", "must not be negative, got %d", c.MessagesPerProcess)
+	}
+	if c.TotalOrderMessages < 0 {
+		fail("total_order_messages", "must not be negative, got %d", c.TotalOrderMessages)
+	}
+	if c.MessagesPerMinute <= 0 {
+		fail("messages_per_minute", "must be positive, got %d", c.MessagesPerMinute)
+	}
+	if c.BufferLimit < 0 {
+		fail("buffer_limit", "must not be negative (0 = unlimited), got %d", c.BufferLimit)
+	}
+	if _, err := process.ParseOverflowPolicy(c.OverflowPolicy); err != nil {
+		fail("overflow_policy", "%v", err)
+	}
+
+	byID := make(map[int]int)
+	byAddress := make(map[string]int)
+	byAdmin := make(map[string]int)
+	for i, pc := range c.Processes {
+		field := fmt.Sprintf("processes[%d]", i)
+		if pc.ID < 0 || pc.ID >= n {
+			fail(field+".id", "P%d out of range (0..%d)", pc.ID, n-1)
+		} else if j, ok := byID[pc.ID]; ok {
+			fail(field+".id", "P%d already used by processes[%d]", pc.ID, j)
+		} else {
+			byID[pc.ID] = i
+		}
+		if pc.Address == "" {
+			fail(field+".address", "missing")
+		}
+		if pc.Admin != "" {
+			if _, port, err := net.SplitHostPort(pc.Admin); err != nil {
+				fail(field+".admin", "want host:port, got %q", pc.Admin)
+			} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				fail(field+".admin", "port must be in 1..65535, got %q", port)
+			} else if j, ok := byAdmin[pc.Admin]; ok {
+				fail(field+".admin", "%s already used by processes[%d]", pc.Admin, j)
+			} else {
+				byAdmin[pc.Admin] = i
+			}
+		}
+		// Unix socket đặt tên theo ID, port không được dùng
+		if c.Transport.Type == "unix" {
+			continue
+		}
+		if pc.Port < 1 || pc.Port > 65535 {
+			fail(field+".port", "must be in 1..65535, got %d", pc.Port)
+			continue
+		}
+		address := fmt.Sprintf("%s:%d", pc.Address, pc.Port)
+		if j, ok := byAddress[address]; ok {
+			fail(field+".port", "%s already used by processes[%d]", address, j)
+		} else {
+			byAddress[address] = i
+		}
+	}
+
+	checkSink := func(field string, s LogSink) {
+		if _, err := process.ParseLogLevel(s.Level); err != nil {
+			fail(field+".level", "%v", err)
+		}
+		if _, err := process.ParseLogFormat(s.Format); err != nil {
+			fail(field+".format", "%v", err)
+		}
+	}
+	checkSink("log.file", c.Log.File)
+	checkSink("log.console", c.Log.Console)
+	for i, s := range c.Log.Sinks {
+		field := fmt.Sprintf("log.sinks[%d]", i)
+		if s.Path == "" {
+			fail(field+".path", "missing (stdout, stderr or a file)")
+		}
+		checkSink(field, s)
+	}
+
+	if _, err := parseOffset(c.HLC.MaxOffset); err != nil {
+		fail("hlc.max_offset", "%v", err)
+	}
+
+	switch c.Transport.Type {
+	case "", "tcp", "unix":
+	case "udp":
+		if c.Transport.UDPLoss < 0 || c.Transport.UDPLoss >= 1 {
+			fail("transport.udp_loss", "must be in [0, 1), got %v", c.Transport.UDPLoss)
+		}
+	default:
+		fail("transport.type", "unknown transport %q (want tcp, unix or udp)", c.Transport.Type)
+	}
+	if c.Transport.Type == "unix" && c.Transport.SocketDir == "" {
+		fail("transport.socket_dir", "missing")
+	}
+
+	// Các phần dưới cần num_processes hợp lệ
+	if n < 2 {
+		return errors.Join(errs...)
+	}
+	if _, err := topology.New(c.Topology, n); err != nil {
+		fail("topology", "%v", err)
+	}
+	if c.TLS.Enabled && c.TLS.CAFile == "" {
+		fail("tls.ca_file", "missing")
+	}
+	if c.Auth.Enabled || c.Encryption.Enabled {
+		if _, err := auth.NewKeyring(c.Auth.Keys); err != nil {
+			fail("auth.keys", "%v", err)
+		}
+		for i, key := range c.Auth.Keys {
+			for _, id := range key.Peers {
+				if id >= n {
+					fail(fmt.Sprintf("auth.keys[%d].peers", i), "P%d out of range (0..%d)", id, n-1)
+				}
+			}
+		}
+	}
+	if c.Workload.Enabled() {
+		if _, err := workload.New(c.WorkloadConfig(), 0, n, 0); err != nil {
+			fail("workload", "%v", err)
+		}
+	}
+	return errors.Join(errs...)
+}
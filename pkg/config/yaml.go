@@ -0,0 +1,520 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bộ đọc YAML cho file config: mapping và sequence dạng block, flow ([a, b],
+// {k: v}), scalar plain/'...'/"..." và comment. Anchor, tag, block scalar
+// (| >) và nhiều document không được hỗ trợ.
+
+type yamlLine struct {
+	num    int // số dòng, đếm từ 1
+	indent int
+	text   string // đã bỏ thụt đầu dòng và comment
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(text)
+		if strings.HasPrefix(text, "\t") {
+			return nil, syntaxError(i+1, "tabs are not allowed for indentation")
+		}
+		text = strings.TrimRight(stripYAMLComment(text), " \t")
+		switch {
+		case text == "":
+			continue
+		case text == "---" && indent == 0 && len(p.lines) == 0:
+			continue
+		case (text == "---" || text == "...") && indent == 0:
+			return nil, syntaxError(i+1, "only one YAML document is supported")
+		case strings.HasPrefix(text, "%"):
+			return nil, syntaxError(i+1, "YAML directives are not supported")
+		}
+		p.lines = append(p.lines, yamlLine{i + 1, indent, text})
+	}
+	if len(p.lines) == 0 {
+		return &object{}, nil
+	}
+
+	tree, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, syntaxError(p.lines[p.pos].num, "unexpected indentation")
+	}
+	return tree, nil
+}
+
+// stripYAMLComment bỏ "# ..." nằm ngoài chuỗi trong dấu nháy
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:-", s[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// block đọc node bắt đầu ở dòng hiện tại, có thụt đầu dòng indent
+func (p *yamlParser) block(indent int) (any, error) {
+	line := p.lines[p.pos]
+	if isYAMLItem(line.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok, err := splitYAMLKey(line); err != nil {
+		return nil, err
+	} else if ok {
+		return p.mapping(indent)
+	}
+	return p.inline(line.text)
+}
+
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	obj := &object{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isYAMLItem(line.text) {
+			return nil, syntaxError(line.num, "expected a key, found a list item")
+		}
+		key, rest, ok, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, syntaxError(line.num, "expected \"key: value\"")
+		}
+
+		var value any
+		if rest == "" {
+			p.pos++
+			value, err = p.nested(indent, true)
+		} else {
+			value, err = p.inline(rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !obj.add(key, value) {
+			return nil, syntaxError(line.num, "duplicate key %q", key)
+		}
+		if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+			return nil, syntaxError(p.lines[p.pos].num, "unexpected indentation")
+		}
+	}
+	return obj, nil
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	list := []any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+
+		var value any
+		var err error
+		if rest == "" {
+			p.pos++
+			value, err = p.nested(indent, false)
+		} else {
+			// "- key: value" mở một mapping có thụt đầu dòng bằng vị trí của key
+			p.lines[p.pos] = yamlLine{line.num, indent + len(line.text) - len(rest), rest}
+			value, err = p.block(p.lines[p.pos].indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+			return nil, syntaxError(p.lines[p.pos].num, "unexpected indentation")
+		}
+	}
+	return list, nil
+}
+
+// nested đọc giá trị nằm ở các dòng sau "key:" hoặc "-". Với key, sequence
+// được phép có cùng thụt đầu dòng với key.
+func (p *yamlParser) nested(indent int, afterKey bool) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (afterKey && next.indent == indent && isYAMLItem(next.text)) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+// inline đọc giá trị nằm trên dòng hiện tại. Flow collection được phép kéo
+// dài sang các dòng sau.
+func (p *yamlParser) inline(text string) (any, error) {
+	line := p.lines[p.pos]
+	p.pos++
+	if text[0] != '[' && text[0] != '{' {
+		value, rest, err := yamlScalar(text, false)
+		if err == nil && rest != "" {
+			err = fmt.Errorf("unexpected %q after value", rest)
+		}
+		if err != nil {
+			return nil, syntaxError(line.num, "%v", err)
+		}
+		return value, nil
+	}
+
+	for {
+		value, rest, err := yamlFlow(text)
+		if err == nil && rest != "" {
+			err = fmt.Errorf("unexpected %q after value", rest)
+		}
+		if err == nil {
+			return value, nil
+		}
+		if err != errYAMLIncomplete || p.pos >= len(p.lines) {
+			return nil, syntaxError(line.num, "%v", err)
+		}
+		text += " " + p.lines[p.pos].text
+		p.pos++
+	}
+}
+
+// splitYAMLKey tách "key: rest". ok = false nếu dòng không phải một cặp key
+func splitYAMLKey(line yamlLine) (key, rest string, ok bool, err error) {
+	text := line.text
+	if text[0] == '"' || text[0] == '\'' {
+		value, after, err := yamlQuoted(text)
+		if err != nil {
+			return "", "", false, syntaxError(line.num, "%v", err)
+		}
+		if !strings.HasPrefix(after, ":") || (len(after) > 1 && after[1] != ' ') {
+			return "", "", false, nil
+		}
+		return value.(string), strings.TrimLeft(after[1:], " "), true, nil
+	}
+	if strings.IndexByte("[{", text[0]) >= 0 {
+		return "", "", false, nil
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false, nil
+		}
+		i = len(text) - 1
+	}
+	key = strings.TrimRight(text[:i], " ")
+	if strings.IndexByte("&*!|>?", key[0]) >= 0 {
+		return "", "", false, syntaxError(line.num, "unsupported YAML syntax %q", key)
+	}
+	return key, strings.TrimLeft(text[i+1:], " "), true, nil
+}
+
+var errYAMLIncomplete = fmt.Errorf("unterminated flow collection")
+
+// yamlFlow đọc một giá trị flow ở đầu s và trả về phần còn lại
+func yamlFlow(s string) (any, string, error) {
+	s = strings.TrimLeft(s, " ")
+	if s == "" {
+		return nil, "", errYAMLIncomplete
+	}
+	switch s[0] {
+	case '[':
+		list := []any{}
+		s = strings.TrimLeft(s[1:], " ")
+		for {
+			if s == "" {
+				return nil, "", errYAMLIncomplete
+			}
+			if s[0] == ']' {
+				return list, strings.TrimLeft(s[1:], " "), nil
+			}
+			value, rest, err := yamlFlow(s)
+			if err != nil {
+				return nil, "", err
+			}
+			list = append(list, value)
+			if s, err = yamlFlowSeparator(rest, ']'); err != nil {
+				return nil, "", err
+			}
+		}
+	case '{':
+		obj := &object{}
+		s = strings.TrimLeft(s[1:], " ")
+		for {
+			if s == "" {
+				return nil, "", errYAMLIncomplete
+			}
+			if s[0] == '}' {
+				return obj, strings.TrimLeft(s[1:], " "), nil
+			}
+			key, rest, err := yamlScalar(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+			rest = strings.TrimLeft(rest, " ")
+			if !strings.HasPrefix(rest, ":") {
+				return nil, "", fmt.Errorf("expected \":\" after key %v", key)
+			}
+			value, rest, err := yamlFlow(rest[1:])
+			if err != nil {
+				return nil, "", err
+			}
+			if !obj.add(fmt.Sprint(key), value) {
+				return nil, "", fmt.Errorf("duplicate key %q", fmt.Sprint(key))
+			}
+			if s, err = yamlFlowSeparator(rest, '}'); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	return yamlScalar(s, true)
+}
+
+// yamlFlowSeparator bỏ dấu phẩy sau một phần tử flow
+func yamlFlowSeparator(s string, end byte) (string, error) {
+	s = strings.TrimLeft(s, " ")
+	switch {
+	case s == "":
+		return "", errYAMLIncomplete
+	case s[0] == ',':
+		return strings.TrimLeft(s[1:], " "), nil
+	case s[0] == end:
+		return s, nil
+	}
+	return "", fmt.Errorf("expected \",\" or %q, found %q", end, s)
+}
+
+// yamlScalar đọc một scalar ở đầu s. Trong flow, scalar plain dừng ở , ] }
+// và ":".
+func yamlScalar(s string, flow bool) (any, string, error) {
+	if s[0] == '"' || s[0] == '\'' {
+		value, rest, err := yamlQuoted(s)
+		return value, strings.TrimLeft(rest, " "), err
+	}
+	if strings.IndexByte("&*!|>%@`", s[0]) >= 0 {
+		return nil, "", fmt.Errorf("unsupported YAML syntax %q", s)
+	}
+	end := len(s)
+	if flow {
+		for i := 0; i < len(s); i++ {
+			if strings.IndexByte(",]}", s[i]) >= 0 || (s[i] == ':' && (i+1 == len(s) || strings.IndexByte(" ,]}", s[i+1]) >= 0)) {
+				end = i
+				break
+			}
+		}
+	}
+	return resolveYAML(strings.TrimRight(s[:end], " ")), s[end:], nil
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolveYAML đổi scalar plain thành null, bool, số hoặc chuỗi (core schema)
+func resolveYAML(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlInt.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	if yamlFloat.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return s
+}
+
+// yamlQuoted đọc chuỗi '...' hoặc "..." ở đầu s
+func yamlQuoted(s string) (any, string, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == quote:
+			return b.String(), s[i+1:], nil
+		case c == '\\' && quote == '"':
+			if i+1 >= len(s) {
+				return nil, "", fmt.Errorf("unterminated string")
+			}
+			n, err := yamlEscape(&b, s[i+1:])
+			if err != nil {
+				return nil, "", err
+			}
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("unterminated string")
+}
+
+// yamlEscape ghi ký tự của escape ở đầu s (sau "\") và trả về số byte đã đọc
+func yamlEscape(b *strings.Builder, s string) (int, error) {
+	simple := map[byte]string{
+		'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+		'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	}
+	if r, ok := simple[s[0]]; ok {
+		b.WriteString(r)
+		return 1, nil
+	}
+	digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+	if digits == 0 || len(s) < 1+digits {
+		return 0, fmt.Errorf("invalid escape \\%c", s[0])
+	}
+	code, err := strconv.ParseUint(s[1:1+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, fmt.Errorf("invalid escape \\%s", s[:1+digits])
+	}
+	b.WriteRune(rune(code))
+	return 1 + digits, nil
+}
+
+// encodeYAML ghi cây config dạng YAML block, list số ghi dạng flow
+func encodeYAML(tree any) ([]byte, error) {
+	var b bytes.Buffer
+	if obj, ok := tree.(*object); !ok || len(obj.members) == 0 {
+		b.WriteString(yamlFlowString(tree) + "\n")
+		return b.Bytes(), nil
+	}
+	writeYAMLBlock(&b, tree, 0)
+	return b.Bytes(), nil
+}
+
+func writeYAMLBlock(b *bytes.Buffer, v any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case *object:
+		for _, m := range v.members {
+			b.WriteString(pad + yamlString(m.key) + ":")
+			writeYAMLValue(b, m.value, indent+2)
+		}
+	case []any:
+		for _, item := range v {
+			if !yamlIsBlock(item) {
+				b.WriteString(pad + "- " + yamlFlowString(item) + "\n")
+				continue
+			}
+			// Phần tử block viết ở indent+2, dòng đầu nằm ngay sau "- "
+			var sub bytes.Buffer
+			writeYAMLBlock(&sub, item, indent+2)
+			b.WriteString(pad + "- ")
+			b.Write(sub.Bytes()[indent+2:])
+		}
+	}
+}
+
+// writeYAMLValue ghi giá trị sau "key:"
+func writeYAMLValue(b *bytes.Buffer, v any, indent int) {
+	if !yamlIsBlock(v) {
+		b.WriteString(" " + yamlFlowString(v) + "\n")
+		return
+	}
+	b.WriteString("\n")
+	writeYAMLBlock(b, v, indent)
+}
+
+// yamlIsBlock: object khác rỗng và list có chứa object được viết dạng block
+func yamlIsBlock(v any) bool {
+	switch v := v.(type) {
+	case *object:
+		return len(v.members) > 0
+	case []any:
+		for _, item := range v {
+			if yamlIsBlock(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func yamlFlowString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return string(v)
+	case string:
+		return yamlString(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = yamlFlowString(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *object:
+		items := make([]string, len(v.members))
+		for i, m := range v.members {
+			items[i] = yamlString(m.key) + ": " + yamlFlowString(m.value)
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
+
+// yamlString viết chuỗi dạng plain nếu đọc lại vẫn ra đúng chuỗi đó, ngược
+// lại dùng "..."
+func yamlString(s string) string {
+	plain := s != "" && strings.IndexByte("-?:,[]{}#&*!|>'\"%@` ", s[0]) < 0 &&
+		!strings.HasSuffix(s, " ") && !strings.HasSuffix(s, ":") &&
+		!strings.Contains(s, ": ") && !strings.Contains(s, " #") &&
+		strings.IndexAny(s, ",[]{}") < 0 && resolveYAML(s) == s
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			plain = false
+		}
+	}
+	if plain {
+		return s
+	}
+	return strconv.Quote(s)
+}
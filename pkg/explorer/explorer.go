@@ -0,0 +1,471 @@
+// Package explorer duyệt toàn bộ các thứ tự send/arrive có thể xảy ra với một
+// cấu hình nhỏ (3–4 process, vài message) và kiểm tra thuật toán SES trong
+// từng interleaving:
+//   - causal delivery: không process nào deliver m trước m' nếu send(m') → send(m)
+//   - liveness: khi mọi message đã đến, không còn message nào nằm trong buffer
+//
+// Không gian trạng thái được duyệt theo BFS nên counterexample tìm được là
+// trace ngắn nhất dẫn đến vi phạm.
+package explorer
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+// Spec mô tả chương trình gửi của mỗi process
+type Spec struct {
+	NumProcesses int
+	// Programs[p] là danh sách target mà Pp gửi đến, theo thứ tự
+	Programs [][]int
+}
+
+// ParseSpec đọc spec dạng "P0->P1 P0->P2 P1->P2" (phân cách bằng space hoặc
+// dấu phẩy). Message được đặt tên m1, m2, ... theo thứ tự xuất hiện; các send
+// của cùng một process giữ đúng thứ tự này.
+func ParseSpec(s string) (Spec, error) {
+	var spec Spec
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\n' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return spec, fmt.Errorf("empty spec")
+	}
+
+	type send struct{ from, to int }
+	var sends []send
+	for _, f := range fields {
+		parts := strings.Split(f, "->")
+		if len(parts) != 2 {
+			return spec, fmt.Errorf("invalid send %q (want Pi->Pj)", f)
+		}
+		from, err := parseProcess(parts[0])
+		if err != nil {
+			return spec, err
+		}
+		to, err := parseProcess(parts[1])
+		if err != nil {
+			return spec, err
+		}
+		if from == to {
+			return spec, fmt.Errorf("invalid send %q: process cannot send to itself", f)
+		}
+		sends = append(sends, send{from, to})
+		if from >= spec.NumProcesses {
+			spec.NumProcesses = from + 1
+		}
+		if to >= spec.NumProcesses {
+			spec.NumProcesses = to + 1
+		}
+	}
+
+	spec.Programs = make([][]int, spec.NumProcesses)
+	for _, snd := range sends {
+		spec.Programs[snd.from] = append(spec.Programs[snd.from], snd.to)
+	}
+	return spec, nil
+}
+
+func parseProcess(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "P"))
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid process %q", s)
+	}
+	return id, nil
+}
+
+// CanDeliverFunc có cùng chữ ký với VectorClock.CanDeliver
+type CanDeliverFunc func(vc *vectorclock.VectorClock, senderID int, tm []int, vm []vectorclock.VectorEntry) (bool, string)
+
+// Options điều chỉnh việc duyệt
+type Options struct {
+	// MaxStates giới hạn số trạng thái được duyệt (0 = DefaultMaxStates)
+	MaxStates int
+	// CanDeliver thay cho VectorClock.CanDeliver, dùng để kiểm tra chính
+	// explorer với một điều kiện deliver sai. nil = VectorClock.CanDeliver
+	CanDeliver CanDeliverFunc
+}
+
+// DefaultMaxStates là giới hạn mặc định số trạng thái
+const DefaultMaxStates = 2000000
+
+type ViolationKind string
+
+const (
+	ViolationCausal   ViolationKind = "causal-order"
+	ViolationLiveness ViolationKind = "liveness"
+)
+
+// Violation là một vi phạm cùng trace ngắn nhất dẫn đến nó
+type Violation struct {
+	Kind        ViolationKind
+	Description string
+	Trace       []string
+}
+
+// Report là kết quả của Explore
+type Report struct {
+	Spec          Spec
+	Messages      int
+	States        int
+	Transitions   int
+	Interleavings *big.Int // số interleaving đầy đủ (chỉ tính khi không có vi phạm)
+	Violation     *Violation
+}
+
+// Explore duyệt mọi interleaving của spec
+func Explore(spec Spec, opts Options) (*Report, error) {
+	if opts.MaxStates <= 0 {
+		opts.MaxStates = DefaultMaxStates
+	}
+	if opts.CanDeliver == nil {
+		opts.CanDeliver = func(vc *vectorclock.VectorClock, senderID int, tm []int, vm []vectorclock.VectorEntry) (bool, string) {
+			return vc.CanDeliver(senderID, tm, vm)
+		}
+	}
+
+	m := newModel(spec, opts.CanDeliver)
+	report := &Report{Spec: spec, Messages: len(m.msgs)}
+
+	root := &node{state: m.initial()}
+	root.key = root.state.key()
+	visited := map[string]*node{root.key: root}
+	queue := []*node{root}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		events := m.enabled(n.state)
+		if len(events) == 0 {
+			if desc := m.stuck(n.state); desc != "" {
+				report.States = len(visited)
+				report.Violation = &Violation{Kind: ViolationLiveness, Description: desc, Trace: n.trace()}
+				return report, nil
+			}
+			continue
+		}
+
+		for _, ev := range events {
+			next, desc, violation := m.apply(n.state, ev)
+			report.Transitions++
+			if violation != "" {
+				report.States = len(visited)
+				report.Violation = &Violation{
+					Kind:        ViolationCausal,
+					Description: violation,
+					Trace:       append(n.trace(), desc),
+				}
+				return report, nil
+			}
+
+			key := next.key()
+			n.succ = append(n.succ, key)
+			if visited[key] != nil {
+				continue
+			}
+			if len(visited) >= opts.MaxStates {
+				return report, fmt.Errorf("state space exceeds %d states", opts.MaxStates)
+			}
+			child := &node{state: next, key: key, parent: n, desc: desc}
+			visited[key] = child
+			queue = append(queue, child)
+		}
+		// Trạng thái đã mở rộng xong, chỉ cần giữ cạnh để đếm interleaving
+		n.state = nil
+	}
+
+	report.States = len(visited)
+	report.Interleavings = countPaths(root.key, visited, map[string]*big.Int{})
+	return report, nil
+}
+
+// node là một trạng thái trong BFS
+type node struct {
+	state  *state
+	key    string
+	parent *node
+	desc   string // event dẫn từ parent đến node này
+	succ   []string
+}
+
+func (n *node) trace() []string {
+	var trace []string
+	for ; n.parent != nil; n = n.parent {
+		trace = append(trace, n.desc)
+	}
+	for i, j := 0, len(trace)-1; i < j; i, j = i+1, j-1 {
+		trace[i], trace[j] = trace[j], trace[i]
+	}
+	return trace
+}
+
+// countPaths đếm số đường đi từ key đến các trạng thái kết thúc
+func countPaths(key string, visited map[string]*node, memo map[string]*big.Int) *big.Int {
+	if c, ok := memo[key]; ok {
+		return c
+	}
+	n := visited[key]
+	count := new(big.Int)
+	if len(n.succ) == 0 {
+		count.SetInt64(1)
+	}
+	for _, s := range n.succ {
+		count.Add(count, countPaths(s, visited, memo))
+	}
+	memo[key] = count
+	return count
+}
+
+// msgInfo là thông tin tĩnh của một message trong spec
+type msgInfo struct {
+	name     string
+	sender   int
+	receiver int
+}
+
+// sentMsg là nội dung message sau khi đã gửi (phụ thuộc interleaving)
+type sentMsg struct {
+	tm     []int
+	vm     []vectorclock.VectorEntry
+	causal []int // vector clock chuẩn lúc gửi, dùng làm ground truth cho →
+}
+
+type procState struct {
+	vc        *vectorclock.VectorClock
+	causal    []int // vector clock chuẩn (Fidge/Mattern) của process
+	pc        int   // số send đã thực hiện
+	buffer    []int
+	delivered []int
+}
+
+type state struct {
+	procs    []procState
+	sent     []*sentMsg // nil = chưa gửi; không bị sửa sau khi gửi nên có thể share
+	inFlight []int      // message đã gửi nhưng chưa đến, sắp xếp tăng dần
+}
+
+func (s *state) clone() *state {
+	c := &state{
+		procs:    make([]procState, len(s.procs)),
+		sent:     append([]*sentMsg(nil), s.sent...),
+		inFlight: append([]int(nil), s.inFlight...),
+	}
+	for i, p := range s.procs {
+		c.procs[i] = procState{
+			vc:        p.vc.Clone(),
+			causal:    append([]int(nil), p.causal...),
+			pc:        p.pc,
+			buffer:    append([]int(nil), p.buffer...),
+			delivered: append([]int(nil), p.delivered...),
+		}
+	}
+	return c
+}
+
+func (s *state) key() string {
+	var b strings.Builder
+	for i, p := range s.procs {
+		fmt.Fprintf(&b, "P%d{%s|%v|%d|%v|%v}", i, p.vc, p.causal, p.pc, p.buffer, p.delivered)
+	}
+	for i, m := range s.sent {
+		if m != nil {
+			fmt.Fprintf(&b, "m%d{%v|%v}", i, m.tm, m.vm)
+		}
+	}
+	fmt.Fprintf(&b, "F%v", s.inFlight)
+	return b.String()
+}
+
+type eventKind int
+
+const (
+	eventSend eventKind = iota
+	eventArrive
+)
+
+type event struct {
+	kind eventKind
+	proc int // eventSend: process gửi
+	msg  int // eventArrive: message đến
+}
+
+type model struct {
+	spec       Spec
+	msgs       []msgInfo
+	index      [][]int // index[p][k] = message thứ k mà Pp gửi
+	canDeliver CanDeliverFunc
+}
+
+func newModel(spec Spec, canDeliver CanDeliverFunc) *model {
+	m := &model{spec: spec, index: make([][]int, spec.NumProcesses), canDeliver: canDeliver}
+	// Đặt tên message theo thứ tự (process, vị trí trong chương trình)
+	for p, program := range spec.Programs {
+		for _, target := range program {
+			m.index[p] = append(m.index[p], len(m.msgs))
+			m.msgs = append(m.msgs, msgInfo{
+				name:     fmt.Sprintf("m%d", len(m.msgs)+1),
+				sender:   p,
+				receiver: target,
+			})
+		}
+	}
+	return m
+}
+
+func (m *model) initial() *state {
+	s := &state{
+		procs: make([]procState, m.spec.NumProcesses),
+		sent:  make([]*sentMsg, len(m.msgs)),
+	}
+	for i := range s.procs {
+		s.procs[i] = procState{
+			vc:     vectorclock.NewVectorClock(i, m.spec.NumProcesses),
+			causal: make([]int, m.spec.NumProcesses),
+		}
+	}
+	return s
+}
+
+func (m *model) enabled(s *state) []event {
+	var events []event
+	for p := range s.procs {
+		if s.procs[p].pc < len(m.spec.Programs[p]) {
+			events = append(events, event{kind: eventSend, proc: p})
+		}
+	}
+	for _, msg := range s.inFlight {
+		events = append(events, event{kind: eventArrive, msg: msg})
+	}
+	return events
+}
+
+// apply thực hiện event trên bản sao của s.
+// violation khác rỗng nếu event làm vi phạm causal delivery.
+func (m *model) apply(s *state, ev event) (next *state, desc string, violation string) {
+	next = s.clone()
+
+	if ev.kind == eventSend {
+		p := &next.procs[ev.proc]
+		idx := m.index[ev.proc][p.pc]
+		info := m.msgs[idx]
+		p.pc++
+
+		tm, vm := p.vc.PrepareToSend(info.receiver)
+		p.causal[ev.proc]++
+		next.sent[idx] = &sentMsg{tm: tm, vm: vm, causal: append([]int(nil), p.causal...)}
+		next.inFlight = insertSorted(next.inFlight, idx)
+
+		return next, fmt.Sprintf("P%d sends %s to P%d (tm=%v, V_M=%s)",
+			info.sender, info.name, info.receiver, tm, message.FormatVectorP(vm)), ""
+	}
+
+	info := m.msgs[ev.msg]
+	next.inFlight = removeSorted(next.inFlight, ev.msg)
+	p := &next.procs[info.receiver]
+	sent := next.sent[ev.msg]
+
+	ok, reason := m.canDeliver(p.vc, info.sender, sent.tm, sent.vm)
+	if !ok {
+		p.buffer = append(p.buffer, ev.msg)
+		return next, fmt.Sprintf("%s arrives at P%d: BUFFERED (%s)", info.name, info.receiver, reason), ""
+	}
+
+	desc = fmt.Sprintf("%s arrives at P%d: DELIVERED", info.name, info.receiver)
+	if violation = m.deliver(next, info.receiver, ev.msg); violation != "" {
+		return next, desc, violation
+	}
+
+	// Thử deliver lại buffer cho đến khi không còn message nào deliver được
+	var released []string
+	for progress := true; progress; {
+		progress = false
+		for i, idx := range p.buffer {
+			bm := next.sent[idx]
+			if ok, _ := m.canDeliver(p.vc, m.msgs[idx].sender, bm.tm, bm.vm); ok {
+				p.buffer = append(p.buffer[:i:i], p.buffer[i+1:]...)
+				released = append(released, m.msgs[idx].name)
+				if violation = m.deliver(next, info.receiver, idx); violation != "" {
+					return next, desc + ", releases " + strings.Join(released, ", "), violation
+				}
+				progress = true
+				break
+			}
+		}
+	}
+	if len(released) > 0 {
+		desc += ", releases " + strings.Join(released, ", ")
+	}
+	return next, desc, ""
+}
+
+// deliver deliver message idx tại process q và kiểm tra causal delivery
+func (m *model) deliver(s *state, q int, idx int) string {
+	p := &s.procs[q]
+	sent := s.sent[idx]
+
+	for other, info := range m.msgs {
+		if other == idx || info.receiver != q || s.sent[other] == nil || contains(p.delivered, other) {
+			continue
+		}
+		if vectorclock.HappenedBefore(s.sent[other].causal, sent.causal) {
+			return fmt.Sprintf("P%d delivered %s before %s, but send(%s) → send(%s)",
+				q, m.msgs[idx].name, info.name, info.name, m.msgs[idx].name)
+		}
+	}
+
+	p.vc.DeliverMessage(m.msgs[idx].sender, sent.tm, sent.vm)
+	for j := range p.causal {
+		if sent.causal[j] > p.causal[j] {
+			p.causal[j] = sent.causal[j]
+		}
+	}
+	p.causal[q]++
+	p.delivered = append(p.delivered, idx)
+	return ""
+}
+
+// stuck mô tả các message còn nằm trong buffer ở trạng thái kết thúc
+func (m *model) stuck(s *state) string {
+	var stuck []string
+	for q, p := range s.procs {
+		for _, idx := range p.buffer {
+			stuck = append(stuck, fmt.Sprintf("%s at P%d", m.msgs[idx].name, q))
+		}
+	}
+	if len(stuck) == 0 {
+		return ""
+	}
+	return "messages buffered forever: " + strings.Join(stuck, ", ")
+}
+
+func insertSorted(s []int, v int) []int {
+	i := sort.SearchInts(s, v)
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func removeSorted(s []int, v int) []int {
+	i := sort.SearchInts(s, v)
+	if i < len(s) && s[i] == v {
+		return append(s[:i], s[i+1:]...)
+	}
+	return s
+}
+
+func contains(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
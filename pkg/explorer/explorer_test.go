@@ -0,0 +1,91 @@
+package explorer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+func explore(t *testing.T, spec string, opts Options) *Report {
+	t.Helper()
+	s, err := ParseSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Explore(s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestSESHoldsInEveryInterleaving(t *testing.T) {
+	specs := []string{
+		// Kịch bản kinh điển: m1 và m3 cùng đến P2, send(m1) → send(m3)
+		"P0->P2 P0->P1 P1->P2",
+		"P0->P1 P0->P2 P1->P2 P2->P0",
+		"P0->P1 P0->P2 P0->P3 P1->P3 P2->P3",
+		"P0->P1 P1->P2 P2->P3 P0->P3 P3->P0",
+	}
+	for _, spec := range specs {
+		t.Run(spec, func(t *testing.T) {
+			report := explore(t, spec, Options{})
+			if report.Violation != nil {
+				t.Fatalf("violation: %s\n%s", report.Violation.Description,
+					strings.Join(report.Violation.Trace, "\n"))
+			}
+			if report.Interleavings.Sign() <= 0 {
+				t.Fatalf("no interleavings counted: %+v", report)
+			}
+		})
+	}
+}
+
+func TestInterleavingCount(t *testing.T) {
+	// Hai send độc lập: 4 event, mỗi arrival phải sau send của nó → 4!/2/2 = 6
+	report := explore(t, "P0->P1 P2->P1", Options{})
+	if got := report.Interleavings.Int64(); got != 6 {
+		t.Fatalf("interleavings = %d, want 6", got)
+	}
+}
+
+func TestFindsMinimalCausalCounterexample(t *testing.T) {
+	alwaysDeliver := func(*vectorclock.VectorClock, int, []int, []vectorclock.VectorEntry) (bool, string) {
+		return true, "no check"
+	}
+	report := explore(t, "P0->P2 P0->P1 P1->P2", Options{CanDeliver: alwaysDeliver})
+
+	v := report.Violation
+	if v == nil || v.Kind != ViolationCausal {
+		t.Fatalf("violation = %+v, want causal-order", v)
+	}
+	// send m1, send m2, m2 đến P1, P1 gửi m3, m3 đến P2 trước m1
+	if len(v.Trace) != 5 {
+		t.Fatalf("trace length = %d, want 5:\n%s", len(v.Trace), strings.Join(v.Trace, "\n"))
+	}
+	if !strings.Contains(v.Description, "P2 delivered m3 before m1") {
+		t.Fatalf("description = %q", v.Description)
+	}
+}
+
+func TestFindsLivenessViolation(t *testing.T) {
+	neverDeliver := func(*vectorclock.VectorClock, int, []int, []vectorclock.VectorEntry) (bool, string) {
+		return false, "never"
+	}
+	report := explore(t, "P0->P1", Options{CanDeliver: neverDeliver})
+	if report.Violation == nil || report.Violation.Kind != ViolationLiveness {
+		t.Fatalf("violation = %+v, want liveness", report.Violation)
+	}
+	if len(report.Violation.Trace) != 2 {
+		t.Fatalf("trace = %v", report.Violation.Trace)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	for _, spec := range []string{"", "P0", "P0->P0", "P0->Px", "P0->P1->P2"} {
+		if _, err := ParseSpec(spec); err == nil {
+			t.Errorf("ParseSpec(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Package hlc là Hybrid Logical Clock (Kulkarni et al., 2014): timestamp gồm
+// thời gian vật lý lớn nhất process đã biết và một bộ đếm logic. Nếu sự kiện
+// e xảy ra trước f thì HLC(e) < HLC(f), trong khi HLC vẫn gần với giờ thật,
+// nên log của nhiều máy sắp theo HLC vừa đúng quan hệ nhân quả vừa dễ đọc.
+package hlc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timestamp là một giá trị HLC. Giá trị zero nhỏ hơn mọi timestamp khác.
+type Timestamp struct {
+	Wall    int64 `json:"wall"`    // nanosecond Unix, giờ vật lý lớn nhất đã biết
+	Logical int32 `json:"logical"` // phân biệt các sự kiện cùng Wall
+}
+
+// IsZero cho biết t chưa được đặt
+func (t Timestamp) IsZero() bool {
+	return t == Timestamp{}
+}
+
+// Compare trả về -1, 0 hoặc 1 khi t nhỏ hơn, bằng hoặc lớn hơn u
+func (t Timestamp) Compare(u Timestamp) int {
+	switch {
+	case t.Wall < u.Wall:
+		return -1
+	case t.Wall > u.Wall:
+		return 1
+	case t.Logical < u.Logical:
+		return -1
+	case t.Logical > u.Logical:
+		return 1
+	}
+	return 0
+}
+
+// Before cho biết t < u
+func (t Timestamp) Before(u Timestamp) bool {
+	return t.Compare(u) < 0
+}
+
+// Time là phần giờ vật lý của t
+func (t Timestamp) Time() time.Time {
+	return time.Unix(0, t.Wall)
+}
+
+// String viết t thành "<giây>.<nanosecond>,<logical>", ví dụ
+// "1706713445.123456789,2". Parse đọc lại được.
+func (t Timestamp) String() string {
+	sec, nsec := t.Wall/int64(time.Second), t.Wall%int64(time.Second)
+	return fmt.Sprintf("%d.%09d,%d", sec, nsec, t.Logical)
+}
+
+// Parse đọc timestamp do String viết
+func Parse(s string) (Timestamp, error) {
+	wall, logical, ok := strings.Cut(s, ",")
+	sec, nsec, ok2 := strings.Cut(wall, ".")
+	if !ok || !ok2 || len(nsec) != 9 {
+		return Timestamp{}, fmt.Errorf("bad HLC timestamp %q (want seconds.nanoseconds,logical)", s)
+	}
+	secs, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("bad HLC timestamp %q: %w", s, err)
+	}
+	nsecs, err := strconv.ParseInt(nsec, 10, 64)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("bad HLC timestamp %q: %w", s, err)
+	}
+	l, err := strconv.ParseInt(logical, 10, 32)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("bad HLC timestamp %q: %w", s, err)
+	}
+	return Timestamp{Wall: secs*int64(time.Second) + nsecs, Logical: int32(l)}, nil
+}
+
+// Clock là HLC của một process. Dùng được từ nhiều goroutine.
+type Clock struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	last      Timestamp
+	maxOffset time.Duration // 0 = không giới hạn
+}
+
+// NewClock tạo clock đọc giờ vật lý từ now (nil = time.Now), không giới
+// hạn độ lệch giờ (xem SetMaxOffset)
+func NewClock(now func() time.Time) *Clock {
+	if now == nil {
+		now = time.Now
+	}
+	return &Clock{now: now}
+}
+
+// SetMaxOffset đặt độ lệch giờ tối đa giữa các process (0 = không giới
+// hạn): timestamp remote đi trước giờ vật lý local hơn d bị Update giới hạn
+// lại, để một đồng hồ chạy nhanh hoặc giả mạo không kéo HLC ra xa giờ thật
+func (c *Clock) SetMaxOffset(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxOffset = d
+}
+
+// Check trả về lỗi nếu remote đi trước giờ vật lý local hơn max offset,
+// tức Update sẽ giới hạn nó lại
+func (c *Clock) Check(remote Timestamp) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pt := c.now().UnixNano()
+	if ahead := time.Duration(remote.Wall - pt); c.maxOffset > 0 && remote.Wall > pt && ahead > c.maxOffset {
+		return fmt.Errorf("HLC %v is %v ahead of local time (max offset %v)", remote, ahead, c.maxOffset)
+	}
+	return nil
+}
+
+// Now tạo timestamp cho một sự kiện local hoặc gửi message: lớn hơn mọi
+// timestamp clock đã trả về hoặc đã nhận
+func (c *Clock) Now() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pt := c.now().UnixNano()
+	if pt > c.last.Wall {
+		c.last = Timestamp{Wall: pt}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update gộp timestamp remote của message nhận được và trả về timestamp của
+// sự kiện nhận, lớn hơn cả remote và mọi timestamp trước đó của clock.
+// Với max offset, remote đi trước giờ vật lý quá mức đó (xem Check) bị
+// giới hạn ở giờ vật lý + max offset, nên HLC không rời xa giờ thật.
+func (c *Clock) Update(remote Timestamp) Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pt := c.now().UnixNano()
+	if limit := pt + int64(c.maxOffset); c.maxOffset > 0 && remote.Wall > limit {
+		remote = Timestamp{Wall: limit}
+	}
+	switch {
+	case pt > c.last.Wall && pt > remote.Wall:
+		c.last = Timestamp{Wall: pt}
+	case remote.Wall > c.last.Wall:
+		c.last = Timestamp{Wall: remote.Wall, Logical: remote.Logical + 1}
+	case c.last.Wall > remote.Wall:
+		c.last.Logical++
+	default: // cùng Wall
+		c.last.Logical = max(c.last.Logical, remote.Logical) + 1
+	}
+	return c.last
+}
+
+// Last trả về timestamp gần nhất clock đã tạo, zero nếu chưa có
+func (c *Clock) Last() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
@@ -0,0 +1,87 @@
+package hlc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fakeTime là đồng hồ vật lý điều khiển được trong test
+type fakeTime struct{ t time.Time }
+
+func (f *fakeTime) now() time.Time { return f.t }
+
+func TestClockMonotonicAndCausal(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	pa, pb := &fakeTime{base}, &fakeTime{base.Add(-100 * time.Millisecond)} // b chậm 100ms
+	a, b := NewClock(pa.now), NewClock(pb.now)
+
+	// Giờ vật lý đứng yên: logical tăng
+	s1, s2 := a.Now(), a.Now()
+	if s1 != (Timestamp{base.UnixNano(), 0}) || s2 != (Timestamp{base.UnixNano(), 1}) {
+		t.Fatalf("a.Now() = %v, %v", s1, s2)
+	}
+
+	// b chậm hơn nhưng nhận message của a: timestamp vẫn lớn hơn lúc gửi
+	r := b.Update(s2)
+	if !s2.Before(r) || r != (Timestamp{base.UnixNano(), 2}) {
+		t.Fatalf("b.Update(%v) = %v", s2, r)
+	}
+	if s3 := b.Now(); !r.Before(s3) {
+		t.Fatalf("b.Now() = %v after %v", s3, r)
+	}
+
+	// Khi giờ vật lý của b vượt lên, HLC quay về giờ vật lý, logical = 0
+	pb.t = base.Add(time.Second)
+	if got := b.Now(); got != (Timestamp{base.Add(time.Second).UnixNano(), 0}) {
+		t.Fatalf("b.Now() = %v", got)
+	}
+
+	// Message cũ đến sau: chỉ logical tăng
+	last := b.Last()
+	if got := b.Update(s1); got != (Timestamp{last.Wall, last.Logical + 1}) {
+		t.Fatalf("b.Update(old) = %v, last %v", got, last)
+	}
+}
+
+// Peer có đồng hồ chạy nhanh (hoặc giả mạo Wall) không kéo được HLC ra xa
+// giờ thật quá max offset
+func TestClockBoundsRemoteOffset(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	const maxOffset = 500 * time.Millisecond
+	c := NewClock((&fakeTime{base}).now)
+	if err := c.Check(Timestamp{Wall: base.Add(time.Hour).UnixNano()}); err != nil {
+		t.Fatalf("Check without max offset = %v", err)
+	}
+	c.SetMaxOffset(maxOffset)
+
+	near := Timestamp{Wall: base.Add(maxOffset / 2).UnixNano()}
+	if err := c.Check(near); err != nil {
+		t.Fatalf("Check(%v) = %v", near, err)
+	}
+	for _, far := range []Timestamp{{Wall: base.Add(time.Hour).UnixNano()}, {Wall: math.MaxInt64}} {
+		if err := c.Check(far); err == nil {
+			t.Fatalf("Check(%v) accepted", far)
+		}
+		limit := base.Add(maxOffset).UnixNano()
+		if got := c.Update(far); got.Wall > limit {
+			t.Fatalf("Update(%v) = %v, beyond local time + max offset", far, got)
+		}
+	}
+}
+
+func TestTimestampStringParse(t *testing.T) {
+	ts := Timestamp{Wall: 1706713445000000042, Logical: 3}
+	if got := ts.String(); got != "1706713445.000000042,3" {
+		t.Fatalf("String() = %q", got)
+	}
+	back, err := Parse(ts.String())
+	if err != nil || back != ts {
+		t.Fatalf("Parse = %v, %v", back, err)
+	}
+	for _, bad := range []string{"", "1706713445,3", "1706713445.42,3", "1706713445.000000042"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package message
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// canonicalVersion đứng đầu CanonicalBytes, đổi khi format thay đổi
+const canonicalVersion = "ses-msg-v4"
+
+// CanonicalBytes là encoding cố định của mọi trường trừ MAC, dùng để tính
+// HMAC. Không dùng JSON vì cùng một message có thể encode ra nhiều chuỗi
+// byte khác nhau (thứ tự key, khoảng trắng, time zone của PhysicalTS).
+func (m *Message) CanonicalBytes() []byte {
+	var buf bytes.Buffer
+	putString(&buf, canonicalVersion)
+	putString(&buf, m.ID)
+	putInt(&buf, int64(m.SenderID))
+	putInt(&buf, int64(m.ReceiverID))
+	putString(&buf, m.Content)
+	putInts(&buf, m.Timestamp)
+	putInt(&buf, int64(len(m.VectorP)))
+	for _, entry := range m.VectorP {
+		putInt(&buf, int64(entry.TargetProcessID))
+		putInts(&buf, entry.Timestamp)
+	}
+	putInt(&buf, m.PhysicalTS.UnixNano())
+	putInt(&buf, m.HLC.Wall)
+	putInt(&buf, int64(m.HLC.Logical))
+	putInt(&buf, int64(m.SeqNum))
+	putString(&buf, m.KeyID)
+	putBytes(&buf, m.Nonce)
+	putBytes(&buf, m.Ciphertext)
+	putString(&buf, string(m.Kind))
+	putInt(&buf, m.Lamport)
+	if m.Chain == nil {
+		putInt(&buf, 0)
+	} else {
+		putInt(&buf, 1)
+		putString(&buf, m.Chain.Chain)
+		putInt(&buf, int64(m.Chain.Origin))
+		putInt(&buf, int64(m.Chain.Remaining))
+	}
+	return buf.Bytes()
+}
+
+// AssociatedData là metadata của message (mọi trường trừ payload và MAC),
+// được xác thực cùng Ciphertext khi mã hóa Content bằng AEAD
+func (m *Message) AssociatedData() []byte {
+	meta := *m
+	meta.Content, meta.Nonce, meta.Ciphertext = "", nil, nil
+	return meta.CanonicalBytes()
+}
+
+func putInt(buf *bytes.Buffer, x int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(x))
+	buf.Write(b[:])
+}
+
+// putString ghi độ dài trước nội dung để "ab"+"c" khác "a"+"bc"
+func putString(buf *bytes.Buffer, s string) {
+	putInt(buf, int64(len(s)))
+	buf.WriteString(s)
+}
+
+func putBytes(buf *bytes.Buffer, b []byte) {
+	putInt(buf, int64(len(b)))
+	buf.Write(b)
+}
+
+func putInts(buf *bytes.Buffer, v []int) {
+	putInt(buf, int64(len(v)))
+	for _, x := range v {
+		putInt(buf, int64(x))
+	}
+}
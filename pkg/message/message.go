@@ -3,25 +3,63 @@ package message
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
+	"github.com/NationalWind/ses-project/pkg/hlc"
 	"github.com/NationalWind/ses-project/pkg/vectorclock"
 )
 
 // Message trong SES theo slide
 // Bao gồm: nội dung, tm (timestamp), V_M (vector entries)
 type Message struct {
-	ID         string                    `json:"id"`          // Unique message ID
-	SenderID   int                       `json:"sender_id"`   // ID of sender
-	ReceiverID int                       `json:"receiver_id"` // ID of receiver
-	Content    string                    `json:"content"`     // Message content
-	Timestamp  []int                     `json:"timestamp"`   // tm: vector timestamp khi gửi
-	VectorP    []vectorclock.VectorEntry `json:"vector_p"`    // V_P: các cặp (process_id, timestamp)
-	PhysicalTS time.Time                 `json:"physical_ts"` // Physical timestamp (for logging)
-	SeqNum     int                       `json:"seq_num"`     // Sequence number
+	ID         string                    `json:"id"`               // Unique message ID
+	SenderID   int                       `json:"sender_id"`        // ID of sender
+	ReceiverID int                       `json:"receiver_id"`      // ID of receiver
+	Content    string                    `json:"content"`          // Message content
+	Timestamp  []int                     `json:"timestamp"`        // tm: vector timestamp khi gửi
+	VectorP    []vectorclock.VectorEntry `json:"vector_p"`         // V_P: các cặp (process_id, timestamp)
+	PhysicalTS time.Time                 `json:"physical_ts"`      // Giờ của sender, chỉ so sánh được trên cùng một máy
+	HLC        hlc.Timestamp             `json:"hlc"`              // Hybrid logical clock lúc gửi, sắp log theo nhân quả
+	SeqNum     int                       `json:"seq_num"`          // Sequence number
+	KeyID      string                    `json:"key_id,omitempty"` // Key dùng để tính MAC (nếu bật HMAC)
+	MAC        []byte                    `json:"mac,omitempty"`    // HMAC trên CanonicalBytes()
+	Nonce      []byte                    `json:"nonce,omitempty"`
+	Ciphertext []byte                    `json:"ciphertext,omitempty"` // Content đã mã hóa (Content rỗng)
+	Hops       int                       `json:"hops,omitempty"`       // Số lần được relay, chỉ để chặn vòng lặp (không nằm trong MAC)
+	Kind       Kind                      `json:"kind,omitempty"`       // rỗng = message SES (causal order)
+	Lamport    int64                     `json:"lamport,omitempty"`    // Lamport timestamp của message total order
+	Chain      *Hop                      `json:"chain,omitempty"`      // khác nil: message trong chuỗi request/response
 }
 
+// Hop là vị trí của message trong chuỗi request/response (xem package
+// workload). Process deliver message có Remaining > 0 gửi tiếp một hop, nên
+// mỗi hop phụ thuộc nhân quả vào mọi hop trước đó. Hop cuối quay về Origin.
+type Hop struct {
+	Chain     string `json:"id"`        // ID của chuỗi, ví dụ "P0-C3"
+	Origin    int    `json:"origin"`    // process mở đầu chuỗi
+	Remaining int    `json:"remaining"` // số hop còn lại sau message này
+}
+
+func (h Hop) String() string {
+	return fmt.Sprintf("chain %s origin=%d remaining=%d", h.Chain, h.Origin, h.Remaining)
+}
+
+// Kind chọn cách message được deliver
+type Kind string
+
+const (
+	// KindCausal là message SES, deliver theo thứ tự nhân quả
+	KindCausal Kind = ""
+	// KindTotal là message multicast đến mọi process, được deliver theo
+	// cùng một thứ tự (Lamport, SenderID) ở mọi nơi
+	KindTotal Kind = "total"
+	// KindTotalAck là ack của KindTotal, gửi đến mọi process để báo
+	// Lamport clock của sender đã vượt qua message đó
+	KindTotalAck Kind = "total-ack"
+)
+
 type Status string
 
 const (
@@ -78,12 +116,52 @@ func (m *Message) Encode(conn net.Conn) error {
 	return json.NewEncoder(conn).Encode(m)
 }
 
+// MaxMessageSize giới hạn số byte đọc cho một message, tránh peer gửi
+// payload vô hạn làm hết memory
+const MaxMessageSize = 1 << 20
+
 func DecodeMessage(conn net.Conn) (Message, error) {
 	var msg Message
-	err := json.NewDecoder(conn).Decode(&msg)
+	err := json.NewDecoder(io.LimitReader(conn, MaxMessageSize)).Decode(&msg)
 	return msg, err
 }
 
+// Ack là phản hồi của receiver cho mỗi message nhận qua connection.
+// Accepted = false nghĩa là receiver đang quá tải (buffer đầy): sender phải
+// gửi lại message sau ít nhất RetryAfter và giảm tốc độ gửi.
+// Invalid = true nghĩa là message không hợp lệ, gửi lại cũng vô ích.
+type Ack struct {
+	MessageID  string        `json:"message_id"`
+	Accepted   bool          `json:"accepted"`
+	Invalid    bool          `json:"invalid,omitempty"`
+	Duplicate  bool          `json:"duplicate,omitempty"` // Invalid vì đã nhận trước đó, ví dụ gửi lại sau khi mất ack
+	Reason     string        `json:"reason,omitempty"`
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+}
+
+func (a *Ack) Encode(conn net.Conn) error {
+	return json.NewEncoder(conn).Encode(a)
+}
+
+func DecodeAck(conn net.Conn) (Ack, error) {
+	var ack Ack
+	err := json.NewDecoder(conn).Decode(&ack)
+	return ack, err
+}
+
+// Sealed cho biết Content đang được mã hóa trong Ciphertext
+func (m *Message) Sealed() bool {
+	return len(m.Ciphertext) > 0
+}
+
+// PayloadSummary mô tả payload cho log mà không lộ nội dung mã hóa
+func (m *Message) PayloadSummary() string {
+	if m.Sealed() {
+		return fmt.Sprintf("%d bytes ciphertext", len(m.Ciphertext))
+	}
+	return fmt.Sprintf("%d bytes", len(m.Content))
+}
+
 // Helper để format V_P cho logging
 func FormatVectorP(vp []vectorclock.VectorEntry) string {
 	if len(vp) == 0 {
@@ -0,0 +1,129 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Các loại lỗi khi kiểm tra message nhận từ peer.
+// Dùng errors.Is(err, ErrSenderOutOfRange) để phân loại.
+var (
+	ErrSenderOutOfRange = errors.New("sender out of range")
+	ErrWrongReceiver    = errors.New("wrong receiver")
+	ErrBadTimestamp     = errors.New("bad timestamp")
+	ErrBadVectorEntry   = errors.New("bad vector entry")
+	ErrDuplicate        = errors.New("duplicate message")
+	ErrSenderMismatch   = errors.New("sender identity mismatch")
+	ErrBadMAC           = errors.New("bad MAC")
+	ErrBadCiphertext    = errors.New("bad ciphertext")
+	ErrBadRoute         = errors.New("bad route")
+	ErrBadKind          = errors.New("bad kind")
+	ErrBadSeqNum        = errors.New("bad sequence number")
+	ErrBadChain         = errors.New("bad chain hop")
+	ErrBadHLC           = errors.New("bad HLC timestamp")
+)
+
+// ValidationError cho biết message bị từ chối vì trường nào
+type ValidationError struct {
+	MessageID string
+	Kind      error // một trong các Err* ở trên
+	Detail    string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid message %q: %v: %s", e.MessageID, e.Kind, e.Detail)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Kind
+}
+
+// Validate kiểm tra message đến process receiverID trong hệ thống có
+// numProcesses process. Message từ peer không được tin tưởng: SenderID sai
+// sẽ làm panic VectorClock, timestamp sai độ dài làm hỏng tP.
+func (m *Message) Validate(numProcesses int, receiverID int) error {
+	invalid := func(kind error, format string, args ...interface{}) error {
+		return &ValidationError{MessageID: m.ID, Kind: kind, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	if m.SenderID < 0 || m.SenderID >= numProcesses {
+		return invalid(ErrSenderOutOfRange, "sender_id=%d, want 0..%d", m.SenderID, numProcesses-1)
+	}
+	if m.SenderID == receiverID {
+		return invalid(ErrSenderOutOfRange, "sender_id=%d is the receiver itself", m.SenderID)
+	}
+	if m.ReceiverID != receiverID {
+		return invalid(ErrWrongReceiver, "receiver_id=%d, this is P%d", m.ReceiverID, receiverID)
+	}
+	// Logical = MaxInt32 làm bộ đếm của receiver tràn khi Update
+	if m.HLC.Wall < 0 || m.HLC.Logical < 0 || m.HLC.Logical == math.MaxInt32 {
+		return invalid(ErrBadHLC, "hlc=%v", m.HLC)
+	}
+	// SeqNum đếm từ 1 theo từng sender, dùng để phát hiện trùng lặp
+	if m.Kind != KindTotalAck && m.SeqNum < 1 {
+		return invalid(ErrBadSeqNum, "seq_num=%d, want >= 1", m.SeqNum)
+	}
+	if h := m.Chain; h != nil {
+		if m.Kind != KindCausal {
+			return invalid(ErrBadChain, "%s message carries a chain hop", m.Kind)
+		}
+		if h.Chain == "" || h.Origin < 0 || h.Origin >= numProcesses || h.Remaining < 0 {
+			return invalid(ErrBadChain, "%v", *h)
+		}
+	}
+	switch m.Kind {
+	case KindCausal:
+	case KindTotal, KindTotalAck:
+		// Total order chỉ dùng Lamport timestamp, không có vector clock
+		if m.Lamport <= 0 {
+			return invalid(ErrBadTimestamp, "lamport=%d, want > 0", m.Lamport)
+		}
+		if len(m.Timestamp) != 0 || len(m.VectorP) != 0 {
+			return invalid(ErrBadKind, "%s message carries a vector clock", m.Kind)
+		}
+		return nil
+	default:
+		return invalid(ErrBadKind, "unknown kind %q", m.Kind)
+	}
+	if err := checkVector(m.Timestamp, numProcesses); err != "" {
+		return invalid(ErrBadTimestamp, "timestamp %s", err)
+	}
+
+	seen := make(map[int]bool, len(m.VectorP))
+	for i, entry := range m.VectorP {
+		if entry.TargetProcessID < 0 || entry.TargetProcessID >= numProcesses {
+			return invalid(ErrBadVectorEntry, "vector_p[%d]: target P%d out of range", i, entry.TargetProcessID)
+		}
+		if seen[entry.TargetProcessID] {
+			return invalid(ErrBadVectorEntry, "vector_p[%d]: duplicate entry for P%d", i, entry.TargetProcessID)
+		}
+		seen[entry.TargetProcessID] = true
+		if err := checkVector(entry.Timestamp, numProcesses); err != "" {
+			return invalid(ErrBadVectorEntry, "vector_p[%d] (P%d): timestamp %s", i, entry.TargetProcessID, err)
+		}
+	}
+	return nil
+}
+
+func checkVector(v []int, numProcesses int) string {
+	if len(v) != numProcesses {
+		return fmt.Sprintf("has length %d, want %d", len(v), numProcesses)
+	}
+	for j, x := range v {
+		if x < 0 {
+			return fmt.Sprintf("has negative component [%d]=%d", j, x)
+		}
+	}
+	return ""
+}
+
+// RejectKind trả về tên ngắn của loại lỗi, dùng làm key cho metrics
+func RejectKind(err error) string {
+	for _, kind := range []error{ErrSenderOutOfRange, ErrWrongReceiver, ErrBadTimestamp, ErrBadVectorEntry, ErrDuplicate, ErrSenderMismatch, ErrBadMAC, ErrBadCiphertext, ErrBadRoute, ErrBadKind, ErrBadSeqNum, ErrBadChain, ErrBadHLC} {
+		if errors.Is(err, kind) {
+			return kind.Error()
+		}
+	}
+	return "malformed"
+}
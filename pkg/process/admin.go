@@ -0,0 +1,120 @@
+package process
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/NationalWind/ses-project/pkg/hlc"
+	"github.com/NationalWind/ses-project/pkg/transport"
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+// Stats là snapshot statistics của process, dạng JSON của admin endpoint
+type Stats struct {
+	ID               int                       `json:"id"`
+	LocalTime        []int                     `json:"local_time"`
+	VectorP          []vectorclock.VectorEntry `json:"vector_p"`
+	HLC              hlc.Timestamp             `json:"hlc"`
+	SentMessages     map[int]int               `json:"sent_messages"`
+	ReceivedMessages map[int]int               `json:"received_messages"`
+	Delivered        int                       `json:"delivered"`
+	Buffered         int                       `json:"buffered"`
+	BufferedTotal    int                       `json:"buffered_total"`
+	Spilled          int                       `json:"spilled"`
+	Rejected         int                       `json:"rejected"`
+	Forwarded        int                       `json:"forwarded"`
+	ChainsCompleted  int                       `json:"chains_completed"`
+	TotalDelivered   int                       `json:"total_order_delivered"` // message Multicast đã deliver
+	InvalidMessages  map[string]int            `json:"invalid_messages"`
+	Transport        map[string]int64          `json:"transport"`
+}
+
+// Stats trả về snapshot statistics hiện tại
+func (p *Process) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := Stats{
+		ID:               p.ID,
+		LocalTime:        p.VectorClock.GetLocalTime(),
+		VectorP:          p.VectorClock.GetEntries(),
+		HLC:              p.Clock.Last(),
+		SentMessages:     make(map[int]int, len(p.SentMsgCount)),
+		ReceivedMessages: make(map[int]int, len(p.ReceivedMsgCount)),
+		Delivered:        len(p.DeliveredMsgs),
+		Buffered:         p.MessageBuffer.Len(),
+		BufferedTotal:    p.BufferedMsgCount,
+		Spilled:          p.MessageBuffer.Spilled(),
+		Rejected:         p.RejectedMsgCount,
+		Forwarded:        p.ForwardedMsgCount,
+		ChainsCompleted:  p.ChainsCompleted,
+		TotalDelivered:   p.totalDelivered(),
+		InvalidMessages:  p.copyInvalidCounts(),
+		Transport:        transport.Stats(p.transport),
+	}
+	for id, n := range p.SentMsgCount {
+		s.SentMessages[id] = n
+	}
+	for id, n := range p.ReceivedMsgCount {
+		s.ReceivedMessages[id] = n
+	}
+	return s
+}
+
+// TotalSent là tổng số message đã gửi
+func (s Stats) TotalSent() int {
+	return sum(s.SentMessages)
+}
+
+// TotalReceived là tổng số message đã nhận
+func (s Stats) TotalReceived() int {
+	return sum(s.ReceivedMessages)
+}
+
+func sum(counts map[int]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+// AdminHandler trả về HTTP handler của admin endpoint:
+//
+//	GET /stats          statistics dạng JSON (Stats)
+//	GET /buffer         các message trong buffer ([]Blocked)
+//	GET /log?after=SEQ  các dòng log gần nhất có Seq > SEQ ([]LogLine)
+//	GET /healthz        "ok" khi process đang chạy
+func (p *Process) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, p.Stats())
+	})
+	mux.HandleFunc("GET /buffer", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, p.BufferedMessages())
+	})
+	mux.HandleFunc("GET /log", func(w http.ResponseWriter, r *http.Request) {
+		after := 0
+		if s := r.URL.Query().Get("after"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(w, "after must be a number", http.StatusBadRequest)
+				return
+			}
+			after = n
+		}
+		writeJSON(w, p.recent.after(after))
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
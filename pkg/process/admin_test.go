@@ -0,0 +1,68 @@
+package process
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminStats(t *testing.T) {
+	c := NewCluster(3, io.Discard)
+	m1, _ := c.Send(1, 0, "a")
+	m2, _ := c.Send(1, 0, "b")
+	c.Arrive(m2)
+
+	server := httptest.NewServer(c.Processes[0].AdminHandler())
+	defer server.Close()
+	client := NewAdminClient(server.URL, time.Second)
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ID != 0 || stats.Buffered != 1 || stats.ReceivedMessages[1] != 1 || stats.TotalReceived() != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	blocked, err := client.Buffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocked) != 1 || blocked[0].Message.ID != "P1-P0-M2" || blocked[0].Reason() != "waiting for tP[1] >= 1 (now 0)" {
+		t.Fatalf("buffer = %+v", blocked)
+	}
+	lines, err := client.Log(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lines); n == 0 || !strings.Contains(lines[n-1].Line, "BUFFERED: P1-P0-M2") {
+		t.Fatalf("log = %+v", lines)
+	}
+	seq := lines[len(lines)-1].Seq
+
+	c.Arrive(m1)
+	if s := c.Processes[0].Stats(); s.Delivered != 2 || s.Buffered != 0 || s.BufferedTotal != 1 {
+		t.Fatalf("after release: %+v", s)
+	}
+	if s := c.Processes[1].Stats(); s.TotalSent() != 2 {
+		t.Fatalf("sender: %+v", s)
+	}
+	if lines, err := client.Log(seq); err != nil || len(lines) == 0 || lines[0].Seq != seq+1 {
+		t.Fatalf("log after %d = %+v, %v", seq, lines, err)
+	}
+}
+
+func TestRecentLogKeepsNewestLines(t *testing.T) {
+	l := &recentLog{}
+	for i := 1; i <= maxRecent+5; i++ {
+		l.Write([]byte("line\n"))
+	}
+	lines := l.after(0)
+	if len(lines) != maxRecent || lines[0].Seq != 6 || lines[len(lines)-1].Seq != maxRecent+5 {
+		t.Fatalf("kept %d lines, %d..%d", len(lines), lines[0].Seq, lines[len(lines)-1].Seq)
+	}
+	if got := l.after(maxRecent + 3); len(got) != 2 || got[0].Line != "line" {
+		t.Fatalf("after = %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AdminClient đọc admin endpoint (xem AdminHandler) của một process đang chạy
+type AdminClient struct {
+	base   string
+	client http.Client
+}
+
+// NewAdminClient tạo client cho admin endpoint addr (host:port hoặc URL),
+// mỗi request chờ tối đa timeout
+func NewAdminClient(addr string, timeout time.Duration) *AdminClient {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &AdminClient{base: strings.TrimSuffix(addr, "/"), client: http.Client{Timeout: timeout}}
+}
+
+// Stats đọc GET /stats
+func (c *AdminClient) Stats() (Stats, error) {
+	var stats Stats
+	err := c.get("/stats", &stats)
+	return stats, err
+}
+
+// Buffer đọc GET /buffer
+func (c *AdminClient) Buffer() ([]Blocked, error) {
+	var blocked []Blocked
+	err := c.get("/buffer", &blocked)
+	return blocked, err
+}
+
+// Log đọc các dòng log gần nhất có Seq > after
+func (c *AdminClient) Log(after int) ([]LogLine, error) {
+	var lines []LogLine
+	err := c.get(fmt.Sprintf("/log?after=%d", after), &lines)
+	return lines, err
+}
+
+func (c *AdminClient) get(path string, v interface{}) error {
+	resp, err := c.client.Get(c.base + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
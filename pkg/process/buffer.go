@@ -0,0 +1,277 @@
+package process
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// bufferedMessage là một message đang nằm trong buffer
+// need: giá trị tP[component] cần đạt tới để message có thể được xét lại
+type bufferedMessage struct {
+	msg       message.Message
+	seq       uint64 // thứ tự vào buffer, giữ FIFO khi deliver
+	component int
+	need      int
+
+	// Message đã spill ra disk: msg chỉ còn ID, nội dung nằm ở spill file
+	spilled bool
+	offset  int64
+	length  int
+}
+
+// waitQueue là min-heap theo need cho một component của tP
+type waitQueue []*bufferedMessage
+
+func (q waitQueue) Len() int { return len(q) }
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].need != q[j].need {
+		return q[i].need < q[j].need
+	}
+	return q[i].seq < q[j].seq
+}
+func (q waitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *waitQueue) Push(x interface{}) { *q = append(*q, x.(*bufferedMessage)) }
+func (q *waitQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// readyQueue là min-heap theo seq, chứa các message vừa được đánh thức
+type readyQueue []*bufferedMessage
+
+func (q readyQueue) Len() int            { return len(q) }
+func (q readyQueue) Less(i, j int) bool  { return q[i].seq < q[j].seq }
+func (q readyQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *readyQueue) Push(x interface{}) { *q = append(*q, x.(*bufferedMessage)) }
+func (q *readyQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// MessageBuffer lưu các message chưa deliver được, đánh index theo
+// dependency đang chặn chúng: (component j, giá trị t[j] cần đạt).
+// Khi tP[j] tăng, chỉ các message chờ trên component j với need <= tP[j]
+// được đánh thức, thay vì quét lại toàn bộ buffer.
+type MessageBuffer struct {
+	waiting []waitQueue
+	size    int
+	nextSeq uint64
+
+	// Spill ra disk khi số message trong memory vượt quá memLimit
+	spill    *spillFile
+	memLimit int
+	inMemory int
+}
+
+// NewMessageBuffer tạo buffer rỗng cho hệ thống có numProcesses process
+func NewMessageBuffer(numProcesses int) *MessageBuffer {
+	return &MessageBuffer{
+		waiting: make([]waitQueue, numProcesses),
+	}
+}
+
+// Len trả về số message đang nằm trong buffer
+func (b *MessageBuffer) Len() int {
+	return b.size
+}
+
+// Spilled trả về số message đang nằm trên disk
+func (b *MessageBuffer) Spilled() int {
+	return b.size - b.inMemory
+}
+
+// EnableSpill cho phép buffer giữ tối đa memLimit message trong memory,
+// phần còn lại được ghi vào file path
+func (b *MessageBuffer) EnableSpill(path string, memLimit int) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	b.spill = &spillFile{file: f}
+	b.memLimit = memLimit
+	return nil
+}
+
+// Close đóng và xóa spill file (nếu có)
+func (b *MessageBuffer) Close() {
+	if b.spill != nil {
+		b.spill.file.Close()
+		os.Remove(b.spill.file.Name())
+		b.spill = nil
+	}
+}
+
+// Add đưa message vào buffer, chờ đến khi tP[component] >= need
+func (b *MessageBuffer) Add(msg message.Message, component int, need int) error {
+	b.nextSeq++
+	return b.push(&bufferedMessage{msg: msg, seq: b.nextSeq, component: component, need: need})
+}
+
+// requeue đưa lại message đã đánh thức nhưng vẫn bị chặn bởi dependency khác.
+// Giữ nguyên seq để không làm thay đổi thứ tự FIFO.
+func (b *MessageBuffer) requeue(bm *bufferedMessage, component int, need int) error {
+	bm.component = component
+	bm.need = need
+	return b.push(bm)
+}
+
+func (b *MessageBuffer) push(bm *bufferedMessage) error {
+	if b.spill != nil && b.inMemory >= b.memLimit {
+		offset, length, err := b.spill.write(bm.msg)
+		if err != nil {
+			return fmt.Errorf("spill %s: %w", bm.msg.ID, err)
+		}
+		bm.msg = message.Message{ID: bm.msg.ID}
+		bm.spilled, bm.offset, bm.length = true, offset, length
+	} else {
+		b.inMemory++
+	}
+	heap.Push(&b.waiting[bm.component], bm)
+	b.size++
+	return nil
+}
+
+// wake lấy ra mọi message chờ trên component với need <= value.
+// Message đã spill được đọc lại từ disk.
+func (b *MessageBuffer) wake(component int, value int, ready *readyQueue) error {
+	q := &b.waiting[component]
+	for q.Len() > 0 && (*q)[0].need <= value {
+		bm := heap.Pop(q).(*bufferedMessage)
+		b.size--
+		if bm.spilled {
+			msg, err := b.spill.read(bm.offset, bm.length)
+			if err != nil {
+				return fmt.Errorf("load spilled %s: %w", bm.msg.ID, err)
+			}
+			bm.msg, bm.spilled = msg, false
+			b.spill.dead += int64(bm.length)
+		} else {
+			b.inMemory--
+		}
+		heap.Push(ready, bm)
+	}
+	if b.spill == nil {
+		return nil
+	}
+	if b.size == 0 {
+		return b.spill.reset()
+	}
+	if b.spill.dead >= spillCompactMin && b.spill.dead*2 >= b.spill.end {
+		return b.compactSpill()
+	}
+	return nil
+}
+
+// compactSpill dồn các message còn trên disk về đầu spill file và cắt bỏ
+// phần đã đọc lại, để file không lớn mãi khi buffer không bao giờ rỗng
+func (b *MessageBuffer) compactSpill() error {
+	var spilled []*bufferedMessage
+	for _, q := range b.waiting {
+		for _, bm := range q {
+			if bm.spilled {
+				spilled = append(spilled, bm)
+			}
+		}
+	}
+	sort.Slice(spilled, func(i, j int) bool { return spilled[i].offset < spilled[j].offset })
+
+	// Offset mới không bao giờ lớn hơn offset cũ nên copy tại chỗ theo thứ
+	// tự offset không ghi đè message chưa được copy
+	var end int64
+	for _, bm := range spilled {
+		if bm.offset != end {
+			data := make([]byte, bm.length)
+			if _, err := b.spill.file.ReadAt(data, bm.offset); err != nil {
+				return fmt.Errorf("compact spill %s: %w", bm.msg.ID, err)
+			}
+			if _, err := b.spill.file.WriteAt(data, end); err != nil {
+				return fmt.Errorf("compact spill %s: %w", bm.msg.ID, err)
+			}
+			bm.offset = end
+		}
+		end += int64(bm.length)
+	}
+	b.spill.end, b.spill.dead = end, 0
+	return b.spill.file.Truncate(end)
+}
+
+// Messages trả về bản sao các message trong buffer theo thứ tự vào buffer
+func (b *MessageBuffer) Messages() []message.Message {
+	all := b.entries()
+	msgs := make([]message.Message, len(all))
+	for i, bm := range all {
+		msgs[i] = b.load(bm)
+	}
+	return msgs
+}
+
+// load trả về message đầy đủ của bm, đọc từ spill file nếu cần
+func (b *MessageBuffer) load(bm *bufferedMessage) message.Message {
+	if bm.spilled {
+		if msg, err := b.spill.read(bm.offset, bm.length); err == nil {
+			return msg
+		}
+	}
+	return bm.msg
+}
+
+func (b *MessageBuffer) entries() []*bufferedMessage {
+	all := make([]*bufferedMessage, 0, b.size)
+	for _, q := range b.waiting {
+		all = append(all, q...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].seq < all[j].seq })
+	return all
+}
+
+// spillCompactMin là số byte đã đọc lại tối thiểu trước khi compact spill file
+const spillCompactMin = 64 << 10
+
+// spillFile là file append-only chứa các message bị đẩy ra khỏi memory
+type spillFile struct {
+	file *os.File
+	end  int64
+	dead int64 // byte của message đã được đọc lại, chờ compact
+}
+
+func (s *spillFile) write(msg message.Message) (int64, int, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset := s.end
+	if _, err := s.file.WriteAt(data, offset); err != nil {
+		return 0, 0, err
+	}
+	s.end += int64(len(data))
+	return offset, len(data), nil
+}
+
+func (s *spillFile) read(offset int64, length int) (message.Message, error) {
+	var msg message.Message
+	data := make([]byte, length)
+	if _, err := s.file.ReadAt(data, offset); err != nil {
+		return msg, err
+	}
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// reset xóa nội dung file khi buffer đã rỗng
+func (s *spillFile) reset() error {
+	s.end, s.dead = 0, 0
+	return s.file.Truncate(0)
+}
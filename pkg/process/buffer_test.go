@@ -0,0 +1,251 @@
+package process
+
+import (
+	"container/heap"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/NationalWind/ses-project/pkg/hlc"
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+// newQuietProcess tạo process không có sink log nào
+func newQuietProcess(tb testing.TB, id int, numProcesses int) *Process {
+	tb.Helper()
+
+	return &Process{
+		ID:               id,
+		NumProcesses:     numProcesses,
+		VectorClock:      vectorclock.NewVectorClock(id, numProcesses),
+		Clock:            hlc.NewClock(nil),
+		MessageBuffer:    NewMessageBuffer(numProcesses),
+		SentMsgCount:     make(map[int]int),
+		ReceivedMsgCount: make(map[int]int),
+		Logger:           slog.New(multiHandler{}),
+	}
+}
+
+// chainMessages tạo n message P1 → P0, message i phụ thuộc vào việc P0 đã
+// deliver i message từ P1. Trả về theo thứ tự ngược để mọi message trừ
+// message cuối cùng đều bị buffer.
+func chainMessages(n int) []message.Message {
+	msgs := make([]message.Message, n)
+	for i := 0; i < n; i++ {
+		tm := []int{0, i, 0}
+		var vm []vectorclock.VectorEntry
+		if i > 0 {
+			vm = []vectorclock.VectorEntry{{TargetProcessID: 0, Timestamp: []int{0, i, 0}}}
+		}
+		msgs[n-1-i] = message.NewMessage(1, 0, i+1, "chain", tm, vm)
+	}
+	return msgs
+}
+
+func TestBufferDeliversChainInCausalOrder(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+	msgs := chainMessages(100)
+
+	for _, msg := range msgs[:len(msgs)-1] {
+		p.receiveMessage(msg)
+	}
+	if got := p.MessageBuffer.Len(); got != 99 {
+		t.Fatalf("buffered = %d, want 99", got)
+	}
+
+	p.receiveMessage(msgs[len(msgs)-1])
+	if got := p.MessageBuffer.Len(); got != 0 {
+		t.Fatalf("buffered after release = %d, want 0", got)
+	}
+	for i, msg := range p.DeliveredMsgs {
+		if msg.SeqNum != i+1 {
+			t.Fatalf("delivered[%d] = %s, want seq %d", i, msg.ID, i+1)
+		}
+	}
+}
+
+func TestBufferRequeuesOnSecondDependency(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+
+	// Cần tP[1] >= 1 và tP[2] >= 1
+	blocked := message.NewMessage(1, 0, 2, "blocked", []int{0, 1, 1},
+		[]vectorclock.VectorEntry{{TargetProcessID: 0, Timestamp: []int{0, 1, 1}}})
+	p.receiveMessage(blocked)
+	p.receiveMessage(message.NewMessage(1, 0, 1, "from P1", []int{0, 0, 0}, nil))
+
+	if got := p.MessageBuffer.Len(); got != 1 {
+		t.Fatalf("buffered = %d, want 1 (still waiting on P2)", got)
+	}
+
+	p.receiveMessage(message.NewMessage(2, 0, 1, "from P2", []int{0, 0, 0}, nil))
+	if got := p.MessageBuffer.Len(); got != 0 {
+		t.Fatalf("buffered = %d, want 0", got)
+	}
+	if last := p.DeliveredMsgs[len(p.DeliveredMsgs)-1]; last.ID != blocked.ID {
+		t.Fatalf("last delivered = %s, want %s", last.ID, blocked.ID)
+	}
+}
+
+func TestRejectPolicyAppliesBackpressure(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+	p.bufferLimit, p.overflowPolicy = 10, OverflowReject
+	msgs := chainMessages(20)
+
+	for _, msg := range msgs[:10] {
+		if ack := p.receiveMessage(msg); !ack.Accepted {
+			t.Fatalf("%s rejected before buffer was full", msg.ID)
+		}
+	}
+	ack := p.receiveMessage(msgs[10])
+	if ack.Accepted || ack.RetryAfter <= 0 {
+		t.Fatalf("ack = %+v, want rejection with RetryAfter", ack)
+	}
+	if p.RejectedMsgCount != 1 || p.ReceivedMsgCount[1] != 10 {
+		t.Fatalf("rejected=%d received=%d, want 1 and 10", p.RejectedMsgCount, p.ReceivedMsgCount[1])
+	}
+
+	// Message deliver được luôn được nhận, kể cả khi buffer đầy
+	if ack := p.receiveMessage(msgs[19]); !ack.Accepted {
+		t.Fatalf("deliverable message rejected: %+v", ack)
+	}
+}
+
+func TestSpillPolicyKeepsOrder(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+	if err := p.MessageBuffer.EnableSpill(t.TempDir()+"/p0.spill", 5); err != nil {
+		t.Fatal(err)
+	}
+	defer p.MessageBuffer.Close()
+	p.bufferLimit, p.overflowPolicy = 5, OverflowSpill
+
+	msgs := chainMessages(50)
+	for _, msg := range msgs[:len(msgs)-1] {
+		p.receiveMessage(msg)
+	}
+	if got := p.MessageBuffer.Spilled(); got != 44 {
+		t.Fatalf("spilled = %d, want 44", got)
+	}
+
+	p.receiveMessage(msgs[len(msgs)-1])
+	if len(p.DeliveredMsgs) != 50 {
+		t.Fatalf("delivered = %d, want 50", len(p.DeliveredMsgs))
+	}
+	for i, msg := range p.DeliveredMsgs {
+		if msg.SeqNum != i+1 || msg.Content != "chain" {
+			t.Fatalf("delivered[%d] = %+v", i, msg)
+		}
+	}
+}
+
+// Buffer không bao giờ rỗng (message chờ P2 mãi) nhưng message liên tục
+// được spill rồi đọc lại: spill file phải được compact thay vì lớn mãi
+func TestSpillFileIsCompacted(t *testing.T) {
+	b := NewMessageBuffer(3)
+	path := t.TempDir() + "/p0.spill"
+	if err := b.EnableSpill(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	for i := 1; i <= 10; i++ {
+		msg := message.NewMessage(2, 0, i, "stuck", []int{0, 0, i}, nil)
+		if err := b.Add(msg, 2, 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 2000; i++ {
+		msg := message.NewMessage(1, 0, i, "passing through", []int{0, i, 0}, nil)
+		if err := b.Add(msg, 1, i); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.wake(1, i, &readyQueue{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 2*spillCompactMin {
+		t.Fatalf("spill file is %d bytes with 10 messages spilled", info.Size())
+	}
+	ready := &readyQueue{}
+	if err := b.wake(2, 1000, ready); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; ready.Len() > 0; i++ {
+		if bm := heap.Pop(ready).(*bufferedMessage); bm.msg.SeqNum != i || bm.msg.Content != "stuck" {
+			t.Fatalf("after compaction got %+v, want stuck message %d", bm.msg, i)
+		}
+	}
+}
+
+func TestFailPolicyExits(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+	p.bufferLimit, p.overflowPolicy = 1, OverflowFail
+
+	code := -1
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	msgs := chainMessages(3)
+	p.receiveMessage(msgs[0])
+	if code != -1 {
+		t.Fatalf("exited before buffer was full")
+	}
+	p.receiveMessage(msgs[1])
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+}
+
+func BenchmarkIndexedBuffer10k(b *testing.B) {
+	msgs := chainMessages(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p := newQuietProcess(b, 0, 3)
+		b.StartTimer()
+		for _, msg := range msgs {
+			p.receiveMessage(msg)
+		}
+		if p.MessageBuffer.Len() != 0 {
+			b.Fatalf("buffer not drained: %d", p.MessageBuffer.Len())
+		}
+	}
+}
+
+// BenchmarkLinearScanBuffer10k đo cách cũ: quét lại buffer từ đầu sau mỗi
+// lần deliver, dùng để so sánh với BenchmarkIndexedBuffer10k.
+func BenchmarkLinearScanBuffer10k(b *testing.B) {
+	msgs := chainMessages(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vc := vectorclock.NewVectorClock(0, 3)
+		var buffer []message.Message
+		for _, msg := range msgs {
+			if ok, _ := vc.CanDeliver(msg.SenderID, msg.Timestamp, msg.VectorP); !ok {
+				buffer = append(buffer, msg)
+				continue
+			}
+			vc.DeliverMessage(msg.SenderID, msg.Timestamp, msg.VectorP)
+			for delivered := true; delivered; {
+				delivered = false
+				for j := 0; j < len(buffer); j++ {
+					m := buffer[j]
+					if ok, _ := vc.CanDeliver(m.SenderID, m.Timestamp, m.VectorP); ok {
+						vc.DeliverMessage(m.SenderID, m.Timestamp, m.VectorP)
+						buffer = append(buffer[:j], buffer[j+1:]...)
+						delivered = true
+						break
+					}
+				}
+			}
+		}
+		if len(buffer) != 0 {
+			b.Fatalf("buffer not drained: %d", len(buffer))
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/transport"
+)
+
+// Cluster là n process chạy trong cùng một chương trình. Mặc định không qua
+// mạng: message chỉ đến receiver khi được đưa vào bằng Arrive, nên thứ tự
+// đến do caller quyết định hoàn toàn. Sau Listen, các process gửi cho nhau
+// qua transport như khi chạy riêng.
+type Cluster struct {
+	Processes []*Process
+}
+
+// NewCluster tạo n process, log của mọi process ghi vào logOut
+func NewCluster(n int, logOut io.Writer) *Cluster {
+	c := &Cluster{}
+	for id := 0; id < n; id++ {
+		sink := newLineHandler(logOut, fmt.Sprintf("[P%d] ", id), "", slog.LevelDebug)
+		p := newProcess(id, "", 0, n, nil, sink)
+		c.Processes = append(c.Processes, p)
+	}
+	return c
+}
+
+// Listen cho mọi process listen trên loopback, port do OS chọn, bằng
+// transport do newTransport tạo
+func (c *Cluster) Listen(newTransport func(id int) transport.Transport) error {
+	peers := make(map[int]string)
+	for _, p := range c.Processes {
+		p.Address, p.transport, p.peers = "127.0.0.1", newTransport(p.ID), peers
+		if err := p.Start(); err != nil {
+			c.Close()
+			return err
+		}
+		peers[p.ID] = p.listener.Addr().String()
+	}
+	return nil
+}
+
+// WaitIdle chờ đến khi mọi message đã gửi đều được deliver và không còn hop
+// nào của chuỗi request/response đang được gửi
+func (c *Cluster) WaitIdle(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		sent, delivered := c.totals()
+		if sent == delivered {
+			// Hop tiếp theo của chuỗi được đăng ký ngay khi deliver, nên
+			// chờ các hop đó xong rồi kiểm tra lại
+			for _, p := range c.Processes {
+				p.chains.Wait()
+			}
+			if s, d := c.totals(); s == sent && d == delivered {
+				return nil
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout: %d of %d messages delivered", delivered, sent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// totals trả về tổng số message đã gửi và đã deliver trong cluster
+func (c *Cluster) totals() (sent int, delivered int) {
+	for _, p := range c.Processes {
+		p.mu.Lock()
+		for _, n := range p.SentMsgCount {
+			sent += n
+		}
+		delivered += len(p.DeliveredMsgs)
+		p.mu.Unlock()
+	}
+	return sent, delivered
+}
+
+// Close đóng listener và buffer của mọi process
+func (c *Cluster) Close() {
+	for _, p := range c.Processes {
+		p.Close()
+	}
+}
+
+// Send cho from gửi một message đến to: vector clock của from được cập nhật
+// ngay, còn message chỉ đến to khi gọi Arrive
+func (c *Cluster) Send(from, to int, content string) (message.Message, error) {
+	if err := c.check(from); err != nil {
+		return message.Message{}, err
+	}
+	if err := c.check(to); err != nil {
+		return message.Message{}, err
+	}
+	if from == to {
+		return message.Message{}, fmt.Errorf("P%d cannot send to itself", from)
+	}
+	return c.Processes[from].prepare(to, content)
+}
+
+// Arrive đưa msg đến receiver và trả về các quyết định BUFFERED/DELIVERED
+// mà nó gây ra
+func (c *Cluster) Arrive(msg message.Message) (Outcome, error) {
+	if err := c.check(msg.ReceiverID); err != nil {
+		return Outcome{}, err
+	}
+	p := c.Processes[msg.ReceiverID]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, outcome := p.receiveWithOutcome(msg)
+	return outcome, nil
+}
+
+// Delivered trả về ID các message process id đã deliver, theo thứ tự
+func (c *Cluster) Delivered(id int) []string {
+	p := c.Processes[id]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids := make([]string, len(p.DeliveredMsgs))
+	for i, msg := range p.DeliveredMsgs {
+		ids[i] = msg.ID
+	}
+	return ids
+}
+
+// Buffered trả về ID các message đang nằm trong buffer của process id
+func (c *Cluster) Buffered(id int) []string {
+	p := c.Processes[id]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ids []string
+	for _, msg := range p.MessageBuffer.Messages() {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func (c *Cluster) check(id int) error {
+	if id < 0 || id >= len(c.Processes) {
+		return fmt.Errorf("P%d out of range (cluster has %d processes)", id, len(c.Processes))
+	}
+	return nil
+}
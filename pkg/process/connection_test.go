@@ -0,0 +1,343 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/auth"
+	"github.com/NationalWind/ses-project/pkg/hlc"
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/transport"
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+// fuzzConn là net.Conn đọc từ bytes cố định và ghi vào buffer
+type fuzzConn struct {
+	in  *bytes.Reader
+	out bytes.Buffer
+}
+
+func (c *fuzzConn) Read(b []byte) (int, error)         { return c.in.Read(b) }
+func (c *fuzzConn) Write(b []byte) (int, error)        { return c.out.Write(b) }
+func (c *fuzzConn) Close() error                       { return nil }
+func (c *fuzzConn) LocalAddr() net.Addr                { return &net.TCPAddr{} }
+func (c *fuzzConn) RemoteAddr() net.Addr               { return &net.TCPAddr{} }
+func (c *fuzzConn) SetDeadline(t time.Time) error      { return nil }
+func (c *fuzzConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fuzzConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func encode(tb testing.TB, msg message.Message) []byte {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestReceiveRejectsInvalidMessages(t *testing.T) {
+	valid := func() message.Message {
+		return message.NewMessage(1, 0, 1, "hi", []int{0, 0, 0}, nil)
+	}
+	cases := []struct {
+		name   string
+		mutate func(*message.Message)
+		kind   error
+	}{
+		{"sender out of range", func(m *message.Message) { m.SenderID = 7 }, message.ErrSenderOutOfRange},
+		{"negative sender", func(m *message.Message) { m.SenderID = -1 }, message.ErrSenderOutOfRange},
+		{"sender is receiver", func(m *message.Message) { m.SenderID = 0 }, message.ErrSenderOutOfRange},
+		{"wrong receiver", func(m *message.Message) { m.ReceiverID = 2 }, message.ErrWrongReceiver},
+		{"zero seq num", func(m *message.Message) { m.SeqNum = 0 }, message.ErrBadSeqNum},
+		{"negative hlc", func(m *message.Message) { m.HLC.Logical = -1 }, message.ErrBadHLC},
+		{"chain origin out of range", func(m *message.Message) {
+			m.Chain = &message.Hop{Chain: "P1-C1", Origin: 5, Remaining: 1}
+		}, message.ErrBadChain},
+		{"short timestamp", func(m *message.Message) { m.Timestamp = []int{0} }, message.ErrBadTimestamp},
+		{"negative timestamp", func(m *message.Message) { m.Timestamp = []int{0, -1, 0} }, message.ErrBadTimestamp},
+		{"entry target out of range", func(m *message.Message) {
+			m.VectorP = []vectorclock.VectorEntry{{TargetProcessID: 3, Timestamp: []int{0, 0, 0}}}
+		}, message.ErrBadVectorEntry},
+		{"entry wrong length", func(m *message.Message) {
+			m.VectorP = []vectorclock.VectorEntry{{TargetProcessID: 0, Timestamp: []int{9}}}
+		}, message.ErrBadVectorEntry},
+		{"duplicate entry", func(m *message.Message) {
+			m.VectorP = []vectorclock.VectorEntry{
+				{TargetProcessID: 2, Timestamp: []int{0, 0, 0}},
+				{TargetProcessID: 2, Timestamp: []int{1, 0, 0}},
+			}
+		}, message.ErrBadVectorEntry},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := newQuietProcess(t, 0, 3)
+			msg := valid()
+			c.mutate(&msg)
+
+			if err := msg.Validate(3, 0); !errors.Is(err, c.kind) {
+				t.Fatalf("Validate() = %v, want %v", err, c.kind)
+			}
+			ack := p.receiveMessage(msg)
+			if ack.Accepted || !ack.Invalid {
+				t.Fatalf("ack = %+v, want invalid", ack)
+			}
+			if p.InvalidMsgCount[c.kind.Error()] != 1 || len(p.DeliveredMsgs) != 0 {
+				t.Fatalf("invalid=%v delivered=%d", p.InvalidMsgCount, len(p.DeliveredMsgs))
+			}
+		})
+	}
+}
+
+func TestReceiveRejectsDuplicates(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+	msg := message.NewMessage(1, 0, 1, "hi", []int{0, 0, 0}, nil)
+
+	if ack := p.receiveMessage(msg); !ack.Accepted {
+		t.Fatalf("first copy rejected: %+v", ack)
+	}
+	if ack := p.receiveMessage(msg); ack.Accepted || !ack.Invalid {
+		t.Fatalf("duplicate accepted: %+v", ack)
+	}
+	if len(p.DeliveredMsgs) != 1 || p.InvalidMsgCount[message.ErrDuplicate.Error()] != 1 {
+		t.Fatalf("delivered=%d invalid=%v", len(p.DeliveredMsgs), p.InvalidMsgCount)
+	}
+}
+
+// HLC đi trước giờ local quá max offset chỉ bị giới hạn lại: message vẫn
+// được deliver, HLC của process không bị kéo đi
+func TestReceiveClampsHLCFarAhead(t *testing.T) {
+	const maxOffset = 500 * time.Millisecond
+	p := newQuietProcess(t, 0, 3)
+	p.SetHLCMaxOffset(maxOffset)
+	msg := message.NewMessage(1, 0, 1, "from the future", []int{0, 0, 0}, nil)
+	msg.HLC = hlc.Timestamp{Wall: time.Now().Add(time.Hour).UnixNano()}
+
+	if ack := p.receiveMessage(msg); !ack.Accepted {
+		t.Fatalf("ack = %+v, want accepted", ack)
+	}
+	if len(p.DeliveredMsgs) != 1 || p.Clock.Last().Time().After(time.Now().Add(maxOffset)) {
+		t.Fatalf("delivered=%d HLC=%v", len(p.DeliveredMsgs), p.Clock.Last())
+	}
+}
+
+// Trùng lặp theo (SenderID, SeqNum), không theo ID: P2 dùng ID của message
+// P1 không chặn được message thật của P1, và message đến sớm vẫn được nhận
+func TestDuplicatesAreKeyedBySender(t *testing.T) {
+	p := newQuietProcess(t, 0, 3)
+	forged := message.NewMessage(2, 0, 1, "forged", []int{0, 0, 0}, nil)
+	forged.ID = "P1-P0-M2"
+	if ack := p.receiveMessage(forged); !ack.Accepted {
+		t.Fatalf("P2's message rejected: %+v", ack)
+	}
+
+	second := message.NewMessage(1, 0, 2, "second", []int{0, 1, 0}, nil)
+	first := message.NewMessage(1, 0, 1, "first", []int{0, 0, 0}, nil)
+	for _, msg := range []message.Message{second, first} {
+		if ack := p.receiveMessage(msg); !ack.Accepted {
+			t.Fatalf("%s rejected: %+v", msg.ID, ack)
+		}
+	}
+	if ack := p.receiveMessage(second); !ack.Invalid {
+		t.Fatalf("replayed %s accepted: %+v", second.ID, ack)
+	}
+	if len(p.DeliveredMsgs) != 3 {
+		t.Fatalf("delivered = %d, want 3", len(p.DeliveredMsgs))
+	}
+
+	// Quá xa message còn thiếu: sender phải gửi lại sau
+	far := message.NewMessage(1, 0, 3+seqWindowSize, "far", []int{0, 2, 0}, nil)
+	if ack := p.receiveMessage(far); ack.Accepted || ack.Invalid || ack.RetryAfter <= 0 {
+		t.Fatalf("ack = %+v, want retry later", ack)
+	}
+}
+
+// FuzzHandleConnection đưa bytes tùy ý vào handleConnection: không được
+// panic, luôn trả về đúng một ack, và không deliver message không hợp lệ
+func FuzzHandleConnection(f *testing.F) {
+	f.Add(encode(f, message.NewMessage(1, 0, 1, "ok", []int{0, 0, 0}, nil)))
+	f.Add(encode(f, message.NewMessage(2, 0, 1, "dep", []int{0, 0, 1},
+		[]vectorclock.VectorEntry{{TargetProcessID: 0, Timestamp: []int{0, 5, 0}}})))
+	f.Add(encode(f, message.NewMessage(9, 0, 1, "bad sender", []int{0, 0, 0}, nil)))
+	f.Add(encode(f, message.NewMessage(1, 0, 1, "short", []int{0}, nil)))
+	f.Add([]byte(`{"sender_id":1,"receiver_id":0,"timestamp":[0,0,0],"vector_p":[{"TargetProcessID":-4,"Timestamp":[1]}]}`))
+	f.Add([]byte(`{"sender_id":`))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := newQuietProcess(t, 0, 3)
+		conn := &fuzzConn{in: bytes.NewReader(data)}
+		p.handleConnection(conn)
+
+		var ack message.Ack
+		if err := json.Unmarshal(conn.out.Bytes(), &ack); err != nil {
+			t.Fatalf("no ack written: %q", conn.out.String())
+		}
+
+		for _, msg := range p.DeliveredMsgs {
+			if err := msg.Validate(3, 0); err != nil {
+				t.Fatalf("delivered invalid message: %v", err)
+			}
+		}
+		for _, msg := range p.MessageBuffer.Messages() {
+			if err := msg.Validate(3, 0); err != nil {
+				t.Fatalf("buffered invalid message: %v", err)
+			}
+		}
+		if ack.Accepted && ack.Invalid {
+			t.Fatalf("ack both accepted and invalid: %+v", ack)
+		}
+	})
+}
+
+func TestTLSRejectsSpoofedSender(t *testing.T) {
+	dir := t.TempDir()
+	if err := transport.GenerateCerts(dir, 3); err != nil {
+		t.Fatal(err)
+	}
+	tlsFor := func(id int) *transport.TLS {
+		certFile, keyFile := transport.CertFiles(dir, id)
+		tr, err := transport.NewTLS(transport.CAFile(dir), certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	p := newQuietProcess(t, 0, 3)
+	ln, err := tlsFor(0).Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			p.handleConnection(conn)
+		}
+	}()
+
+	// P2 giả làm P1
+	send := func(msg message.Message) message.Ack {
+		conn, err := tlsFor(2).Dial(0, ln.Addr().String(), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if err := msg.Encode(conn); err != nil {
+			t.Fatal(err)
+		}
+		ack, err := message.DecodeAck(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ack
+	}
+
+	if ack := send(message.NewMessage(1, 0, 1, "spoofed", []int{0, 0, 0}, nil)); !ack.Invalid {
+		t.Fatalf("spoofed sender accepted: %+v", ack)
+	}
+	if ack := send(message.NewMessage(2, 0, 1, "honest", []int{0, 0, 0}, nil)); !ack.Accepted {
+		t.Fatalf("honest sender rejected: %+v", ack)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.InvalidMsgCount[message.ErrSenderMismatch.Error()] != 1 || len(p.DeliveredMsgs) != 1 {
+		t.Fatalf("invalid=%v delivered=%d, want 1 sender mismatch and 1 delivered",
+			p.InvalidMsgCount, len(p.DeliveredMsgs))
+	}
+}
+
+func TestReceiveRejectsTamperedMAC(t *testing.T) {
+	keyring, err := auth.NewKeyring([]auth.Key{{ID: "k1", Secret: "000102030405060708090a0b0c0d0e0f"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newQuietProcess(t, 0, 3)
+	p.keyring = keyring
+
+	unsigned := message.NewMessage(1, 0, 1, "hi", []int{0, 0, 0}, nil)
+	tampered := message.NewMessage(2, 0, 1, "hi", []int{0, 0, 0}, nil)
+	keyring.Sign(&tampered)
+	tampered.Timestamp = []int{0, 5, 0} // làm P0 chờ mãi nếu được tin
+
+	for _, msg := range []message.Message{unsigned, tampered} {
+		if ack := p.receiveMessage(msg); !ack.Invalid {
+			t.Fatalf("%s accepted: %+v", msg.ID, ack)
+		}
+	}
+	if p.InvalidMsgCount[message.ErrBadMAC.Error()] != 2 || !reflect.DeepEqual(p.VectorClock.GetLocalTime(), []int{0, 0, 0}) {
+		t.Fatalf("invalid=%v tP=%v", p.InvalidMsgCount, p.VectorClock.GetLocalTime())
+	}
+
+	signed := message.NewMessage(1, 0, 2, "hi", []int{0, 0, 0}, nil)
+	keyring.Sign(&signed)
+	if ack := p.receiveMessage(signed); !ack.Accepted {
+		t.Fatalf("signed message rejected: %+v", ack)
+	}
+}
+
+// Không có key dùng được cho P0-P1: message không được tạo, tP và V_P
+// không đổi, nên không có dependency nào mà P1 phải chờ mãi
+func TestSendWithoutUsableKeyLeavesClockUnchanged(t *testing.T) {
+	keyring, err := auth.NewKeyring([]auth.Key{{ID: "k1", Secret: "000102030405060708090a0b0c0d0e0f",
+		NotAfter: time.Now().Add(-time.Hour)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newQuietProcess(t, 0, 3)
+	p.keyring = keyring
+
+	if _, done, err := p.Send(1, "hi"); err == nil || done != nil {
+		t.Fatalf("Send with expired key = %v, want error", err)
+	}
+	if tP := p.VectorClock.GetLocalTime(); !reflect.DeepEqual(tP, []int{0, 0, 0}) || p.SentMsgCount[1] != 0 {
+		t.Fatalf("tP=%v sent=%d after refused send", tP, p.SentMsgCount[1])
+	}
+}
+
+func TestEncryptedPayloadOnlyDecryptedOnDelivery(t *testing.T) {
+	keyring, err := auth.NewKeyring([]auth.Key{{ID: "k1", Secret: "000102030405060708090a0b0c0d0e0f"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newQuietProcess(t, 0, 3)
+	p.payloadKeys = keyring
+
+	msgs := chainMessages(2)
+	for i := range msgs {
+		msgs[i].Content = "secret"
+		if err := keyring.Seal(&msgs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.receiveMessage(msgs[0])
+	for _, buffered := range p.MessageBuffer.Messages() {
+		if buffered.Content != "" || !buffered.Sealed() {
+			t.Fatalf("buffer holds plaintext: %+v", buffered)
+		}
+	}
+	p.receiveMessage(msgs[1])
+	if len(p.DeliveredMsgs) != 2 {
+		t.Fatalf("delivered = %d, want 2", len(p.DeliveredMsgs))
+	}
+	for _, msg := range p.DeliveredMsgs {
+		if msg.Content != "secret" {
+			t.Fatalf("delivered content = %q, want plaintext", msg.Content)
+		}
+	}
+
+	plain := message.NewMessage(2, 0, 1, "unencrypted", []int{0, 0, 0}, nil)
+	if ack := p.receiveMessage(plain); !ack.Invalid {
+		t.Fatalf("unencrypted message accepted: %+v", ack)
+	}
+}
@@ -0,0 +1,153 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// Send tạo message có nội dung content đến targetID (vector clock được cập
+// nhật ngay, theo thứ tự gọi) rồi gửi trong background. done nhận kết quả
+// gửi, nil khi receiver đã chấp nhận. Nếu targetID đang bị Hold, message
+// được gửi khi Release.
+func (p *Process) Send(targetID int, content string) (msg message.Message, done <-chan error, err error) {
+	if err := p.checkPeer(targetID); err != nil {
+		return message.Message{}, nil, err
+	}
+	msg, err = p.prepare(targetID, content)
+	if err != nil {
+		return message.Message{}, nil, err
+	}
+	result := make(chan error, 1)
+	go func() { result <- p.transmit(targetID, msg, p.flowFor(targetID)) }()
+	return msg, result, nil
+}
+
+// Broadcast gửi content đến mọi process khác: các message được tạo lần
+// lượt theo ID rồi gửi song song. done nhận lỗi của các peer, gộp lại.
+func (p *Process) Broadcast(content string) (done <-chan error) {
+	var pending []<-chan error
+	for target := 0; target < p.NumProcesses; target++ {
+		if target != p.ID {
+			_, d, err := p.Send(target, content)
+			if err != nil {
+				failed := make(chan error, 1)
+				failed <- err
+				d = failed
+			}
+			pending = append(pending, d)
+		}
+	}
+	result := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, d := range pending {
+			errs = append(errs, <-d)
+		}
+		result <- errors.Join(errs...)
+	}()
+	return result
+}
+
+// Hold giữ lại mọi message gửi đến peer (kể cả message đã tạo nhưng chưa
+// gửi xong) cho đến khi Release. Message gửi sau đó từ process này đến
+// process khác vẫn mang dependency vào message bị giữ, nên receiver của
+// chúng phải buffer.
+func (p *Process) Hold(peer int) error {
+	if err := p.checkPeer(peer); err != nil {
+		return err
+	}
+	if !p.flowFor(peer).hold() {
+		return fmt.Errorf("P%d is already held", peer)
+	}
+	p.infof("✋ HOLD: messages to P%d are held until release", peer)
+	return nil
+}
+
+// Release gửi đi các message đang bị giữ cho peer và trả về số message đó
+func (p *Process) Release(peer int) (int, error) {
+	if err := p.checkPeer(peer); err != nil {
+		return 0, err
+	}
+	n, ok := p.flowFor(peer).unhold()
+	if !ok {
+		return 0, fmt.Errorf("P%d is not held", peer)
+	}
+	p.infof("▶️ RELEASE: %d held messages to P%d", n, peer)
+	return n, nil
+}
+
+// releaseHeld bỏ Hold mọi peer khi Close: message đang chờ thấy process đã
+// đóng và dừng thay vì được gửi
+func (p *Process) releaseHeld() {
+	p.mu.Lock()
+	flows := make([]*flowControl, 0, len(p.flow))
+	for _, f := range p.flow {
+		flows = append(flows, f)
+	}
+	p.mu.Unlock()
+	for _, f := range flows {
+		f.unhold()
+	}
+}
+
+// Held trả về các peer đang bị Hold và số message đang chờ gửi đến mỗi peer
+func (p *Process) Held() map[int]int {
+	p.mu.Lock()
+	flows := make(map[int]*flowControl, len(p.flow))
+	for id, f := range p.flow {
+		flows[id] = f
+	}
+	p.mu.Unlock()
+
+	held := make(map[int]int)
+	for id, f := range flows {
+		if on, n := f.holding(); on {
+			held[id] = n
+		}
+	}
+	return held
+}
+
+func (p *Process) checkPeer(id int) error {
+	if id < 0 || id >= p.NumProcesses {
+		return fmt.Errorf("P%d out of range (%d processes)", id, p.NumProcesses)
+	}
+	if id == p.ID {
+		return fmt.Errorf("P%d is this process", id)
+	}
+	return nil
+}
+
+// Blocked là một message trong buffer và dependency đang chặn nó: message
+// chỉ được deliver khi tP[Component] >= Need
+type Blocked struct {
+	Message   message.Message `json:"message"`
+	Component int             `json:"component"`
+	Need      int             `json:"need"`
+	Have      int             `json:"have"` // tP[Component] hiện tại
+}
+
+// Reason mô tả dependency đang chặn message
+func (b Blocked) Reason() string {
+	return fmt.Sprintf("waiting for tP[%d] >= %d (now %d)", b.Component, b.Need, b.Have)
+}
+
+// BufferedMessages trả về các message trong buffer theo thứ tự vào buffer
+func (p *Process) BufferedMessages() []Blocked {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	localTime := p.VectorClock.GetLocalTime()
+	var blocked []Blocked
+	for _, bm := range p.MessageBuffer.entries() {
+		blocked = append(blocked, Blocked{
+			Message:   p.MessageBuffer.load(bm),
+			Component: bm.component,
+			Need:      bm.need,
+			Have:      localTime[bm.component],
+		})
+	}
+	return blocked
+}
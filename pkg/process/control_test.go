@@ -0,0 +1,159 @@
+package process
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/transport"
+)
+
+// P0 giữ message đến P2 lại; message P0 → P1 → P2 đến trước và phải
+// nằm trong buffer của P2 đến khi release
+func TestHoldForcesBuffering(t *testing.T) {
+	c := NewCluster(3, io.Discard)
+	if err := c.Listen(func(int) transport.Transport { return transport.TCP{} }); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p0, p1, p2 := c.Processes[0], c.Processes[1], c.Processes[2]
+
+	if err := p0.Hold(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := p0.Hold(2); err == nil {
+		t.Fatal("second Hold accepted")
+	}
+	_, held, err := p0.Send(2, "held")
+	if err != nil {
+		t.Fatal(err)
+	}
+	send(t, p0, 1, "first")
+	waitFor(t, func() bool { return len(c.Delivered(1)) == 1 })
+	send(t, p1, 2, "second")
+	waitFor(t, func() bool { return p0.Held()[2] == 1 })
+
+	blocked := p2.BufferedMessages()
+	if len(blocked) != 1 || blocked[0].Message.ID != "P1-P2-M1" || blocked[0].Component != 0 || blocked[0].Have != 0 {
+		t.Fatalf("buffered = %+v", blocked)
+	}
+	if n, err := p0.Release(2); err != nil || n != 1 {
+		t.Fatalf("release = %d, %v", n, err)
+	}
+	if err := <-held; err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitIdle(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Delivered(2); !reflect.DeepEqual(got, []string{"P0-P2-M1", "P1-P2-M1"}) {
+		t.Fatalf("P2 delivered %v", got)
+	}
+	if _, err := p0.Release(2); err == nil {
+		t.Fatal("Release of a peer that is not held accepted")
+	}
+	if _, _, err := p0.Send(0, "self"); err == nil {
+		t.Fatal("send to itself accepted")
+	}
+	if err := <-p2.Broadcast("all"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Close khi peer đang bị Hold: message đang chờ dừng với errClosed thay vì
+// chờ Release mãi mãi
+func TestCloseStopsHeldSends(t *testing.T) {
+	c := NewCluster(2, io.Discard)
+	if err := c.Listen(func(int) transport.Transport { return transport.TCP{} }); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p0 := c.Processes[0]
+
+	if err := p0.Hold(1); err != nil {
+		t.Fatal(err)
+	}
+	_, held, err := p0.Send(1, "held")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return p0.Held()[1] == 1 })
+	p0.Close()
+
+	select {
+	case err := <-held:
+		if !errors.Is(err, errClosed) {
+			t.Fatalf("held send = %v, want errClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("held send still waiting after Close")
+	}
+	if held := p0.Held(); len(held) != 0 {
+		t.Fatalf("still held after Close: %v", held)
+	}
+	if got := c.Delivered(1); len(got) != 0 {
+		t.Fatalf("P1 delivered %v after P0 closed", got)
+	}
+}
+
+// Listener của P1 chết giữa chừng: message đã prepare phải được gửi lại đến
+// khi P1 listen lại, nếu không mọi message sau sẽ nằm trong buffer mãi mãi
+func TestSendRetriesUntilPeerListensAgain(t *testing.T) {
+	c := NewCluster(3, io.Discard)
+	if err := c.Listen(func(int) transport.Transport { return transport.TCP{} }); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p0, p1, p2 := c.Processes[0], c.Processes[1], c.Processes[2]
+
+	send(t, p0, 1, "before")
+	_, port, _ := net.SplitHostPort(p1.listener.Addr().String())
+	p1.listener.Close()
+	_, lost, err := p0.Send(1, "while down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Phụ thuộc vào message P0 → P1 đang được gửi lại
+	send(t, p0, 2, "after")
+	time.Sleep(3 * errorRetryAfter)
+
+	p1.Port, _ = strconv.Atoi(port)
+	if err := p1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-lost; err != nil {
+		t.Fatal(err)
+	}
+	send(t, p2, 1, "last")
+	if err := c.WaitIdle(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Delivered(1); !reflect.DeepEqual(got, []string{"P0-P1-M1", "P0-P1-M2", "P2-P1-M1"}) {
+		t.Fatalf("P1 delivered %v", got)
+	}
+}
+
+// send gửi một message và chờ đến khi receiver chấp nhận
+func send(t *testing.T, p *Process, to int, content string) {
+	t.Helper()
+	_, done, err := p.Send(to, content)
+	if err == nil {
+		err = <-done
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package process
+
+import "github.com/NationalWind/ses-project/pkg/message"
+
+// seqWindowSize là số SeqNum tối đa một message được đến sớm hơn các
+// message trước nó từ cùng sender
+const seqWindowSize = 4096
+
+// seqWindow ghi nhận các SeqNum đã nhận từ một sender: mọi seq <= high đã
+// nhận, early chứa các seq > high đến sớm (không quá seqWindowSize). Bộ nhớ
+// chỉ phụ thuộc vào số message đến sớm, không vào số message đã nhận.
+type seqWindow struct {
+	high  int
+	early map[int]bool
+}
+
+func (w *seqWindow) has(seq int) bool {
+	return seq <= w.high || w.early[seq]
+}
+
+// ahead cho biết seq nằm ngoài window: phải chờ các message trước nó
+func (w *seqWindow) ahead(seq int) bool {
+	return seq > w.high+seqWindowSize
+}
+
+func (w *seqWindow) add(seq int) {
+	if seq <= w.high {
+		return
+	}
+	if seq != w.high+1 {
+		if w.early == nil {
+			w.early = make(map[int]bool)
+		}
+		w.early[seq] = true
+		return
+	}
+	w.high++
+	for w.early[w.high+1] {
+		delete(w.early, w.high+1)
+		w.high++
+	}
+}
+
+// window trả về seqWindow của sender msg theo loại message, nil với ack
+// total order (nhận lại ack không làm hỏng state). Trùng lặp được xác định
+// bằng (SenderID, SeqNum) đã được xác thực, không bằng ID do peer tự chọn.
+// p.mu phải đang được giữ.
+func (p *Process) window(msg message.Message) *seqWindow {
+	switch msg.Kind {
+	case message.KindCausal:
+		if p.seen == nil {
+			p.seen = make([]seqWindow, p.NumProcesses)
+		}
+		return &p.seen[msg.SenderID]
+	case message.KindTotal:
+		t := p.totalState()
+		if t.seen == nil {
+			t.seen = make([]seqWindow, p.NumProcesses)
+		}
+		return &t.seen[msg.SenderID]
+	}
+	return nil
+}
+
+// markSeen ghi nhận msg đã được nhận (p.mu phải đang được giữ)
+func (p *Process) markSeen(msg message.Message) {
+	if w := p.window(msg); w != nil {
+		w.add(msg.SeqNum)
+	}
+}
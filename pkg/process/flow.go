@@ -0,0 +1,101 @@
+package process
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// rejectRetryAfter là thời gian receiver yêu cầu sender chờ khi buffer đầy
+	rejectRetryAfter = 200 * time.Millisecond
+	// errorRetryAfter là thời gian chờ tối thiểu trước khi gửi lại sau lỗi mạng
+	errorRetryAfter = 100 * time.Millisecond
+	// maxBackoff giới hạn thời gian sender tự giảm tốc cho một peer
+	maxBackoff = 10 * time.Second
+)
+
+// flowControl điều chỉnh tốc độ gửi đến một peer theo backpressure của peer đó.
+// Mỗi lần bị từ chối: backoff tăng gấp đôi (ít nhất RetryAfter của receiver).
+// Mỗi lần được chấp nhận: backoff giảm một nửa cho đến 0.
+type flowControl struct {
+	mu      sync.Mutex
+	backoff time.Duration
+
+	// Hold: release khác nil khi message đến peer đang bị giữ lại, được
+	// đóng khi Release. waiting là số message đang chờ.
+	release chan struct{}
+	waiting int
+}
+
+func (f *flowControl) onReject(retryAfter time.Duration) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.backoff *= 2
+	if f.backoff < retryAfter {
+		f.backoff = retryAfter
+	}
+	if f.backoff > maxBackoff {
+		f.backoff = maxBackoff
+	}
+	return f.backoff
+}
+
+func (f *flowControl) onAccept() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.backoff /= 2
+	if f.backoff < time.Millisecond {
+		f.backoff = 0
+	}
+}
+
+// delay trả về thời gian chờ thêm trước khi gửi message tiếp theo
+func (f *flowControl) delay() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.backoff
+}
+
+// hold bắt đầu giữ lại message, false nếu đang hold
+func (f *flowControl) hold() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.release != nil {
+		return false
+	}
+	f.release = make(chan struct{})
+	return true
+}
+
+// unhold thả các message đang bị giữ và trả về số message đó,
+// ok = false nếu không hold
+func (f *flowControl) unhold() (n int, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.release == nil {
+		return 0, false
+	}
+	close(f.release)
+	n = f.waiting
+	f.release, f.waiting = nil, 0
+	return n, true
+}
+
+// held trả về channel message phải chờ trước khi gửi, nil nếu không hold
+func (f *flowControl) held() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.release != nil {
+		f.waiting++
+	}
+	return f.release
+}
+
+// holding cho biết peer có đang bị hold không và số message đang chờ
+func (f *flowControl) holding() (bool, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.release != nil, f.waiting
+}
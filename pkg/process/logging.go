@@ -0,0 +1,273 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/hlc"
+)
+
+// LogFormat là cách một sink trình bày từng record
+type LogFormat string
+
+const (
+	// LogFormatLine là dòng log quen thuộc "[P0] 2024/01/31 15:04:05 📤 SENT ...",
+	// định dạng mà ses verify, ses top và grep trong tài liệu đọc được
+	LogFormatLine LogFormat = "line"
+	// LogFormatText là slog.TextHandler: time=... level=... msg=... process=0
+	LogFormatText LogFormat = "text"
+	// LogFormatJSON là slog.JSONHandler, mỗi record một object JSON
+	LogFormatJSON LogFormat = "json"
+)
+
+// LevelOff cao hơn mọi level: sink có level này không nhận record nào
+const LevelOff = slog.Level(1 << 20)
+
+// lineTimeLayout là giờ trong LogFormatLine, giống log.LstdFlags
+const lineTimeLayout = "2006/01/02 15:04:05"
+
+// ParseLogFormat chuyển string trong config thành LogFormat. String rỗng
+// được hiểu là LogFormatLine.
+func ParseLogFormat(s string) (LogFormat, error) {
+	switch LogFormat(s) {
+	case "":
+		return LogFormatLine, nil
+	case LogFormatLine, LogFormatText, LogFormatJSON:
+		return LogFormat(s), nil
+	}
+	return "", fmt.Errorf("unknown log format %q (want line, text or json)", s)
+}
+
+// ParseLogLevel đọc level debug, info, warn, error hoặc off. String rỗng
+// được hiểu là info.
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "off":
+		return LevelOff, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn, error or off)", s)
+}
+
+// NewLogHandler tạo sink ghi log của process id vào w theo format, bỏ qua
+// record dưới level
+func NewLogHandler(w io.Writer, format LogFormat, level slog.Leveler, id int) (slog.Handler, error) {
+	switch format {
+	case LogFormatLine, "":
+		return newLineHandler(w, fmt.Sprintf("[P%d] ", id), lineTimeLayout, level), nil
+	case LogFormatText:
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}).WithAttrs([]slog.Attr{slog.Int("process", id)}), nil
+	case LogFormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}).WithAttrs([]slog.Attr{slog.Int("process", id)}), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want line, text or json)", format)
+}
+
+// lineHandler ghi mỗi record thành một dòng: prefix, giờ (nếu có layout),
+// msg rồi các attr dạng key=value
+type lineHandler struct {
+	mu         *sync.Mutex // chung cho mọi bản sao của handler
+	w          io.Writer
+	prefix     string
+	timeLayout string // rỗng = không ghi giờ
+	level      slog.Leveler
+	attrs      string // attr đã format sẵn từ WithAttrs
+	group      string // tiền tố key từ WithGroup
+}
+
+func newLineHandler(w io.Writer, prefix, timeLayout string, level slog.Leveler) *lineHandler {
+	return &lineHandler{mu: &sync.Mutex{}, w: w, prefix: prefix, timeLayout: timeLayout, level: level}
+}
+
+func (h *lineHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *lineHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	if h.timeLayout != "" && !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(h.timeLayout))
+		b.WriteByte(' ')
+	}
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.group, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *lineHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		appendAttr(&b, h.group, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+func (h *lineHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.group += name + "."
+	return &h2
+}
+
+func appendAttr(b *strings.Builder, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, group+a.Key+".", ga)
+		}
+		return
+	}
+	value := a.Value.String()
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339)
+	}
+	if value == "" || strings.ContainsAny(value, " \"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", group, a.Key, value)
+}
+
+// multiHandler gửi mỗi record đến mọi sink có level phù hợp
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var first error
+	for _, h := range m {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	m2 := make(multiHandler, len(m))
+	for i, h := range m {
+		m2[i] = h.WithAttrs(attrs)
+	}
+	return m2
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	m2 := make(multiHandler, len(m))
+	for i, h := range m {
+		m2[i] = h.WithGroup(name)
+	}
+	return m2
+}
+
+// SetFileLog đổi format và level của file log process_N.log (mặc định
+// LogFormatLine, debug). Phải gọi trước Start.
+func (p *Process) SetFileLog(format LogFormat, level slog.Leveler) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.LogFile == nil {
+		return fmt.Errorf("P%d has no log file", p.ID)
+	}
+	h, err := NewLogHandler(p.LogFile, format, level, p.ID)
+	if err != nil {
+		return err
+	}
+	p.logSinks[0] = h
+	p.rebuildLogger()
+	return nil
+}
+
+// AddLogSink gửi thêm log của process đến h, ví dụ console hoặc file JSON.
+// Phải gọi trước Start.
+func (p *Process) AddLogSink(h slog.Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logSinks = append(p.logSinks, h)
+	p.rebuildLogger()
+}
+
+// rebuildLogger tạo lại Logger từ logSinks và recent (p.mu phải đang được giữ)
+func (p *Process) rebuildLogger() {
+	sinks := multiHandler{newLineHandler(p.recent, fmt.Sprintf("[P%d] ", p.ID), lineTimeLayout, slog.LevelDebug)}
+	for _, h := range p.logSinks {
+		if h != nil {
+			sinks = append(sinks, h)
+		}
+	}
+	p.Logger = slog.New(sinks)
+}
+
+// logf format và ghi một record, chỉ khi có sink nhận level này. Record
+// mang attr hlc là HLC gần nhất của process, để sắp log của mọi process
+// theo thứ tự nhân quả (ses timeline). Level của các loại log trong process:
+//
+//   - Debug: từng message (SENT, RECEIVED, BUFFERED, DELIVERED, FORWARD...)
+//   - Info: mốc của process (khởi tạo, cấu hình, bắt đầu/kết thúc gửi, hoàn tất)
+//   - Warn: message bị từ chối, backpressure, lỗi connection
+//   - Error: lỗi gửi, lỗi giải mã, FATAL
+func (p *Process) logf(level slog.Level, format string, args ...interface{}) {
+	p.logAt(level, p.Clock.Last(), format, args...)
+}
+
+// logAt như logf nhưng record mang HLC ts thay cho HLC gần nhất
+func (p *Process) logAt(level slog.Level, ts hlc.Timestamp, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !p.Logger.Enabled(ctx, level) {
+		return
+	}
+	if ts.IsZero() {
+		p.Logger.Log(ctx, level, fmt.Sprintf(format, args...))
+		return
+	}
+	p.Logger.Log(ctx, level, fmt.Sprintf(format, args...), slog.String("hlc", ts.String()))
+}
+
+func (p *Process) debugf(format string, args ...interface{}) {
+	p.logf(slog.LevelDebug, format, args...)
+}
+
+func (p *Process) infof(format string, args ...interface{}) {
+	p.logf(slog.LevelInfo, format, args...)
+}
+
+func (p *Process) warnf(format string, args ...interface{}) {
+	p.logf(slog.LevelWarn, format, args...)
+}
+
+func (p *Process) errorf(format string, args ...interface{}) {
+	p.logf(slog.LevelError, format, args...)
+}
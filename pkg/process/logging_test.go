@@ -0,0 +1,59 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogSinksFilterByLevel(t *testing.T) {
+	var detailed, quiet bytes.Buffer
+	c := NewCluster(2, &detailed)
+	p := c.Processes[0]
+	sink, err := NewLogHandler(&quiet, LogFormatJSON, slog.LevelInfo, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddLogSink(sink)
+
+	m, _ := c.Send(0, 1, "a")
+	c.Arrive(m)
+	if err := p.Hold(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sink debug nhận mọi dòng, vẫn đúng định dạng cũ
+	if !strings.Contains(detailed.String(), "[P1] 📥 RECEIVED from P0: P0-P1-M1 | tm=[0 0]") ||
+		!strings.Contains(detailed.String(), "[P1] ✅ DELIVERED: P0-P1-M1") {
+		t.Fatalf("detailed log:\n%s", detailed.String())
+	}
+	// Sink info chỉ nhận mốc của P0, không có từng message
+	lines := strings.Split(strings.TrimSpace(quiet.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("quiet log:\n%s", quiet.String())
+	}
+	var record struct {
+		Level   string `json:"level"`
+		Msg     string `json:"msg"`
+		Process int    `json:"process"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Level != "INFO" || record.Process != 0 || !strings.Contains(record.Msg, "HOLD") {
+		t.Fatalf("record = %+v", record)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for s, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn, "off": LevelOff} {
+		if got, err := ParseLogLevel(s); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseLogLevel("loud"); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
@@ -1,61 +1,146 @@
 package process
 
 import (
+	"container/heap"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/NationalWind/ses-project/pkg/auth"
+	"github.com/NationalWind/ses-project/pkg/hlc"
 	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/topology"
+	"github.com/NationalWind/ses-project/pkg/transport"
 	"github.com/NationalWind/ses-project/pkg/vectorclock"
+	"github.com/NationalWind/ses-project/pkg/workload"
 )
 
+// OverflowPolicy quyết định cách xử lý khi buffer đã đầy
+type OverflowPolicy string
+
+const (
+	// OverflowReject từ chối message và báo sender giảm tốc (backpressure)
+	OverflowReject OverflowPolicy = "reject"
+	// OverflowSpill đẩy phần vượt giới hạn ra file trên disk
+	OverflowSpill OverflowPolicy = "spill"
+	// OverflowFail dừng process ngay lập tức với lỗi
+	OverflowFail OverflowPolicy = "fail"
+)
+
+// ParseOverflowPolicy chuyển string trong config thành OverflowPolicy.
+// String rỗng được hiểu là OverflowReject.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch OverflowPolicy(s) {
+	case "":
+		return OverflowReject, nil
+	case OverflowReject, OverflowSpill, OverflowFail:
+		return OverflowPolicy(s), nil
+	}
+	return "", fmt.Errorf("unknown overflow policy %q (want reject, spill or fail)", s)
+}
+
+// exit được gọi khi OverflowFail kích hoạt, thay được trong test
+var exit = os.Exit
+
+// DefaultLogDir là thư mục chứa log, spill và record khi không chọn thư mục khác
+const DefaultLogDir = "logs"
+
 type Process struct {
-	ID               int
-	Address          string
-	Port             int
-	NumProcesses     int
-	VectorClock      *vectorclock.VectorClock
-	MessageBuffer    []message.Message
-	DeliveredMsgs    []message.Message
-	SentMsgCount     map[int]int // Đếm số message đã gửi cho mỗi process
-	ReceivedMsgCount map[int]int // Đếm số message đã nhận từ mỗi process
-	Logger           *log.Logger
-	LogFile          *os.File
-	mu               sync.Mutex
-	listener         net.Listener
-	peers            map[int]string
-}
-
-// NewProcess tạo process mới
-func NewProcess(id int, address string, port int, numProcesses int, peers map[int]string) (*Process, error) {
+	ID                int
+	Address           string
+	Port              int
+	NumProcesses      int
+	VectorClock       *vectorclock.VectorClock
+	Clock             *hlc.Clock // hybrid logical clock: gắn vào message và mọi dòng log
+	MessageBuffer     *MessageBuffer
+	DeliveredMsgs     []message.Message
+	SentMsgCount      map[int]int    // Đếm số message đã gửi cho mỗi process
+	ReceivedMsgCount  map[int]int    // Đếm số message đã nhận từ mỗi process
+	RejectedMsgCount  int            // Số message bị từ chối vì buffer đầy
+	BufferedMsgCount  int            // Số message từng phải vào buffer
+	ForwardedMsgCount int            // Số message relay hộ process khác
+	ChainsCompleted   int            // Số chuỗi request/response đã quay về
+	InvalidMsgCount   map[string]int // Số message không hợp lệ theo loại lỗi
+	Logger            *slog.Logger   // ghi vào mọi sink trong logSinks
+	LogFile           *os.File
+	mu                sync.Mutex
+	listener          net.Listener
+	transport         transport.Transport
+	keyring           *auth.Keyring       // nil = không ký/kiểm tra HMAC
+	payloadKeys       *auth.Keyring       // nil = không mã hóa Content
+	topology          *topology.Topology  // nil = full mesh, gửi thẳng đến đích
+	workload          *workload.Generator // nil = gửi theo SendMessages
+	chains            sync.WaitGroup      // các hop của chuỗi đang được gửi
+	peers             map[int]string
+	bufferLimit       int // 0 = không giới hạn
+	overflowPolicy    OverflowPolicy
+	flow              map[int]*flowControl
+	seed              int64           // seed cho random delay khi gửi
+	logSinks          []slog.Handler  // logSinks[0] là file log, nil nếu không có
+	logDir            string          // thư mục của file spill và record
+	latencies         []time.Duration // thời gian từ lúc gửi đến lúc deliver
+	recorder          *Recorder       // nil = không record
+	tracer            *tracer         // nil = không ghi trace
+	total             *totalOrder     // nil = chưa có message total order nào
+	outcome           *Outcome        // outcome của message đang được xử lý
+	seen              []seqWindow     // SeqNum đã nhận từ mỗi sender, để phát hiện trùng lặp
+	recent            *recentLog      // các dòng log gần nhất
+	closing           chan struct{}   // đóng khi Close, dừng các lần gửi lại
+	closeOnce         sync.Once
+}
+
+// readTimeout giới hạn thời gian đọc một message từ connection,
+// tránh peer mở connection rồi không gửi gì
+var readTimeout = 10 * time.Second
+
+// NewProcess tạo process mới, ghi log vào logDir/process_N.log
+func NewProcess(id int, address string, port int, numProcesses int, peers map[int]string, logDir string) (*Process, error) {
 	logFile, err := os.OpenFile(
-		fmt.Sprintf("logs/process_%d.log", id),
+		filepath.Join(logDir, fmt.Sprintf("process_%d.log", id)),
 		os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
 		0666,
 	)
 	if err != nil {
 		return nil, err
 	}
-	logger := log.New(logFile, fmt.Sprintf("[P%d] ", id), log.LstdFlags)
+	sink, _ := NewLogHandler(logFile, LogFormatLine, slog.LevelDebug, id)
+
+	p := newProcess(id, address, port, numProcesses, peers, sink)
+	p.LogFile = logFile
+	p.logDir = logDir
+	return p, nil
+}
 
+// newProcess khởi tạo state của process, ghi log vào sink
+func newProcess(id int, address string, port int, numProcesses int, peers map[int]string, sink slog.Handler) *Process {
 	p := &Process{
 		ID:               id,
 		Address:          address,
 		Port:             port,
 		NumProcesses:     numProcesses,
 		VectorClock:      vectorclock.NewVectorClock(id, numProcesses),
-		MessageBuffer:    []message.Message{},
+		Clock:            hlc.NewClock(nil),
+		MessageBuffer:    NewMessageBuffer(numProcesses),
 		DeliveredMsgs:    []message.Message{},
 		SentMsgCount:     make(map[int]int),
 		ReceivedMsgCount: make(map[int]int),
-		Logger:           logger,
-		LogFile:          logFile,
+		InvalidMsgCount:  make(map[string]int),
+		logSinks:         []slog.Handler{sink},
 		peers:            peers,
+		transport:        transport.TCP{},
+		seed:             time.Now().UnixNano(),
+		logDir:           DefaultLogDir,
+		recent:           &recentLog{},
+		closing:          make(chan struct{}),
 	}
+	// Mọi sink cùng các dòng log gần nhất cho admin endpoint
+	p.rebuildLogger()
 
 	for i := 0; i < numProcesses; i++ {
 		if i != id {
@@ -64,40 +149,169 @@ func NewProcess(id int, address string, port int, numProcesses int, peers map[in
 		}
 	}
 
-	logger.Printf("=== PROCESS INITIALIZED ===")
-	logger.Printf("Initial State: tP=%v, V_P=[]", p.VectorClock.GetLocalTime())
+	p.infof("=== PROCESS INITIALIZED ===")
+	p.infof("Initial State: tP=%v, V_P=[]", p.VectorClock.GetLocalTime())
 
-	return p, nil
+	return p
+}
+
+// SetBufferLimit giới hạn số message trong buffer và chọn cách xử lý khi đầy.
+// limit <= 0 bỏ giới hạn. Với OverflowSpill, limit là số message giữ trong
+// memory, phần còn lại ghi vào process_N.spill trong thư mục log.
+func (p *Process) SetBufferLimit(limit int, policy OverflowPolicy) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if policy == OverflowSpill && limit > 0 {
+		path := filepath.Join(p.logDir, fmt.Sprintf("process_%d.spill", p.ID))
+		if err := p.MessageBuffer.EnableSpill(path, limit); err != nil {
+			return err
+		}
+	}
+	p.bufferLimit = limit
+	p.overflowPolicy = policy
+	p.infof("Buffer limit: %d (policy: %s)", limit, policy)
+	return nil
+}
+
+// SetTransport thay transport mặc định (TCP), phải gọi trước Start
+func (p *Process) SetTransport(t transport.Transport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transport = t
+}
+
+// SetTopology giới hạn process chỉ kết nối trực tiếp với neighbor: message
+// đến process không kề được gửi qua hop kế tiếp và relay đến đích
+func (p *Process) SetTopology(t *topology.Topology) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.topology = t
+	p.infof("Topology: %s", t)
+}
+
+// SetKeyring bật HMAC: mọi message gửi đi được ký, message đến không có
+// MAC hợp lệ bị từ chối trước khi chạm vào vector clock
+func (p *Process) SetKeyring(k *auth.Keyring) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyring = k
+}
+
+// SetPayloadEncryption bật mã hóa Content end-to-end: Content chỉ được giải
+// mã khi deliver, buffer/spill/record chỉ chứa ciphertext
+func (p *Process) SetPayloadEncryption(k *auth.Keyring) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.payloadKeys = k
+}
+
+// SetHLCMaxOffset giới hạn độ lệch giờ của HLC nhận từ peer (0 = không
+// giới hạn, xem hlc.Clock.SetMaxOffset)
+func (p *Process) SetHLCMaxOffset(d time.Duration) {
+	p.Clock.SetMaxOffset(d)
+	if d > 0 {
+		p.infof("HLC max offset: %v", d)
+	}
+}
+
+// SetSeed đặt seed cho random delay khi gửi (mặc định lấy theo thời gian)
+func (p *Process) SetSeed(seed int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seed = seed
+	p.infof("Seed: %d", seed)
+}
+
+// Seed trả về seed đang dùng
+func (p *Process) Seed() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seed
+}
+
+// StartRecording ghi mọi message đến vào process_N.record.jsonl trong thư
+// mục log
+func (p *Process) StartRecording() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	path := filepath.Join(p.logDir, fmt.Sprintf("process_%d.record.jsonl", p.ID))
+	recorder, err := NewRecorder(path, RecordHeader{
+		ProcessID:      p.ID,
+		NumProcesses:   p.NumProcesses,
+		Seed:           p.seed,
+		BufferLimit:    p.bufferLimit,
+		OverflowPolicy: p.overflowPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	p.recorder = recorder
+	p.infof("Recording arrivals to %s", path)
+	return nil
 }
 
 func (p *Process) Start() error {
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", p.Address, p.Port))
+	listener, err := p.transport.Listen(fmt.Sprintf("%s:%d", p.Address, p.Port))
 	if err != nil {
 		return err
 	}
 	p.listener = listener
 
-	p.Logger.Printf("Process started at %s:%d", p.Address, p.Port)
-	fmt.Printf("[P%d] Started at %s:%d\n", p.ID, p.Address, p.Port)
+	p.infof("Process started at %s", listener.Addr())
 
-	go p.acceptConnections()
+	go p.acceptConnections(listener)
 	return nil
 }
 
+// Latencies trả về thời gian từ lúc gửi đến lúc deliver của mọi message đã
+// deliver. Chỉ có nghĩa khi sender và receiver dùng chung đồng hồ.
+func (p *Process) Latencies() []time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]time.Duration(nil), p.latencies...)
+}
+
+// DeliveredMessages trả về bản sao các message đã deliver, theo thứ tự
+func (p *Process) DeliveredMessages() []message.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]message.Message(nil), p.DeliveredMsgs...)
+}
+
 func (p *Process) Close() {
+	p.closeOnce.Do(func() {
+		if p.closing != nil {
+			close(p.closing)
+		}
+	})
+	p.releaseHeld()
 	if p.listener != nil {
 		p.listener.Close()
 	}
+	p.MessageBuffer.Close()
+	p.closeOutboxes()
+	if p.recorder != nil {
+		p.recorder.Close()
+	}
+	if p.tracer != nil {
+		p.tracer.close(time.Now())
+	}
 	if p.LogFile != nil {
 		p.LogFile.Close()
 	}
 }
 
-func (p *Process) acceptConnections() {
+func (p *Process) acceptConnections(listener net.Listener) {
 	for {
-		conn, err := p.listener.Accept()
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
 		if err != nil {
-			p.Logger.Printf("Error accepting connection: %v", err)
+			p.warnf("Error accepting connection: %v", err)
 			continue
 		}
 		go p.handleConnection(conn)
@@ -106,21 +320,50 @@ func (p *Process) acceptConnections() {
 
 func (p *Process) handleConnection(conn net.Conn) {
 	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
 	msg, err := message.DecodeMessage(conn)
 	if err != nil {
-		p.Logger.Printf("Error decoding message: %v", err)
+		p.warnf("Error decoding message from %s: %v", conn.RemoteAddr(), err)
+		p.mu.Lock()
+		p.countInvalid("malformed")
+		p.mu.Unlock()
+		ack := message.Ack{Invalid: true, Reason: "malformed message"}
+		ack.Encode(conn)
 		return
 	}
-	p.receiveMessage(msg)
+
+	// Với mTLS: peer phải là sender hoặc relay đứng ngay trước process này
+	// trên đường từ sender
+	if peerID, authenticated, err := transport.PeerID(conn); authenticated && (err != nil || !p.allowedHop(peerID, msg)) {
+		detail := fmt.Sprintf("certificate is P%d, message claims P%d", peerID, msg.SenderID)
+		if err != nil {
+			detail = err.Error()
+		}
+		p.mu.Lock()
+		ack := p.rejectInvalid(msg, &message.ValidationError{MessageID: msg.ID, Kind: message.ErrSenderMismatch, Detail: detail})
+		p.mu.Unlock()
+		ack.Encode(conn)
+		return
+	}
+
+	var ack message.Ack
+	if p.relaying(msg) {
+		ack = p.forward(msg)
+	} else {
+		ack = p.receiveMessage(msg)
+	}
+	if err := ack.Encode(conn); err != nil {
+		p.warnf("Error sending ack for %s: %v", msg.ID, err)
+	}
 }
 
 func (p *Process) SendMessages(messagesPerProcess int, messagesPerMinute int) {
 	var wg sync.WaitGroup
 	interval := time.Minute / time.Duration(messagesPerMinute)
 
-	p.Logger.Printf("=== STARTING TO SEND MESSAGES ===")
-	p.Logger.Printf("Messages per process: %d", messagesPerProcess)
-	p.Logger.Printf("Rate: %d messages/minute", messagesPerMinute)
+	p.infof("=== STARTING TO SEND MESSAGES ===")
+	p.infof("Messages per process: %d", messagesPerProcess)
+	p.infof("Rate: %d messages/minute", messagesPerMinute)
 
 	for targetID := 0; targetID < p.NumProcesses; targetID++ {
 		if targetID == p.ID {
@@ -133,79 +376,277 @@ func (p *Process) SendMessages(messagesPerProcess int, messagesPerMinute int) {
 		}(targetID)
 	}
 	wg.Wait()
+	p.logFinalState()
+}
 
-	// Log final state
+// logFinalState log tP, V_P và buffer sau khi gửi xong
+func (p *Process) logFinalState() {
 	p.mu.Lock()
 	finalTime := p.VectorClock.GetLocalTime()
 	finalVP := p.VectorClock.GetEntries()
-	p.Logger.Printf("=== FINISHED SENDING ALL MESSAGES ===")
-	p.Logger.Printf("Final tP: %v", finalTime)
-	p.Logger.Printf("Final V_P: %v", finalVP)
-	p.Logger.Printf("Buffer size: %d", len(p.MessageBuffer))
-	p.Logger.Printf("Delivered: %d", len(p.DeliveredMsgs))
+	p.infof("=== FINISHED SENDING ALL MESSAGES ===")
+	p.infof("Final tP: %v", finalTime)
+	p.infof("Final V_P: %v", finalVP)
+	p.infof("Buffer size: %d", p.MessageBuffer.Len())
+	p.infof("Delivered: %d", len(p.DeliveredMsgs))
 	p.mu.Unlock()
-
-	fmt.Printf("[P%d] Finished sending | tP=%v | Buffer=%d | Delivered=%d\n",
-		p.ID, finalTime, len(p.MessageBuffer), len(p.DeliveredMsgs))
 }
 
 func (p *Process) sendToProcess(targetID int, count int, interval time.Duration) {
+	flow := p.flowFor(targetID)
+	// Mỗi target có nguồn random riêng để delay chỉ phụ thuộc vào seed
+	rng := rand.New(rand.NewSource(p.seed + int64(targetID)))
 	for i := 0; i < count; i++ {
-		// Random delay
-		time.Sleep(time.Duration(rand.Int63n(int64(interval))))
+		// Random delay, cộng thêm backoff nếu receiver đang báo quá tải
+		time.Sleep(time.Duration(rng.Int63n(int64(interval))) + flow.delay())
 
-		// Chuẩn bị gửi theo thuật toán SES
-		// 1. tm = tP hiện tại
-		// 2. V_M = V_P (không bao gồm entry cho targetID)
-		// 3. Thêm (targetID, tm) vào V_P của sender
-		// 4. tP[senderID]++
-		tm, vm := p.VectorClock.PrepareToSend(targetID)
+		msg, err := p.prepare(targetID, fmt.Sprintf("message %d", i+1))
+		if err != nil {
+			p.errorf("❌ ERROR sending to P%d: %v", targetID, err)
+			continue
+		}
+		p.transmit(targetID, msg, flow)
+	}
+}
 
-		msg := message.NewMessage(p.ID, targetID, i+1, fmt.Sprintf("message %d", i+1), tm, vm)
+// prepare tạo message mới đến targetID theo thuật toán SES:
+// tm = tP hiện tại, V_M = V_P, rồi tP[senderID]++ và cập nhật V_P.
+// HLC của message là một tick mới của p.Clock. Message được mã hóa/ký
+// ngay: nếu không có key dùng được, message không được tạo và tP không
+// đổi; tP đã đổi mà không ký được thì message không thể gửi, để lại lỗ
+// hổng nhân quả vĩnh viễn, nên process dừng hẳn.
+// Giữ p.mu để thứ tự send/receive trong file record đúng như thực tế.
+func (p *Process) prepare(targetID int, content string) (message.Message, error) {
+	return p.prepareHop(targetID, content, nil)
+}
 
-		p.mu.Lock()
-		p.SentMsgCount[targetID]++
-		p.mu.Unlock()
+// prepareHop là prepare cho message mang hop của một chuỗi (nil = không)
+func (p *Process) prepareHop(targetID int, content string, hop *message.Hop) (message.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, keys := range []*auth.Keyring{p.keyring, p.payloadKeys} {
+		if keys == nil {
+			continue
+		}
+		if err := keys.CanSign(p.ID, targetID); err != nil {
+			return message.Message{}, fmt.Errorf("cannot send to P%d: %w", targetID, err)
+		}
+	}
+
+	tm, vm := p.VectorClock.PrepareToSend(targetID)
+	p.SentMsgCount[targetID]++
+	if p.recorder != nil {
+		if err := p.recorder.RecordSend(targetID, tm, vm); err != nil {
+			p.errorf("Error recording send to P%d: %v", targetID, err)
+		}
+	}
+	msg := message.NewMessage(p.ID, targetID, p.SentMsgCount[targetID], content, tm, vm)
+	msg.HLC = p.Clock.Now()
+	msg.Chain = hop
+	if err := seal(&msg, p.keyring, p.payloadKeys); err != nil {
+		err = fmt.Errorf("%s prepared but cannot be protected: %w", msg.ID, err)
+		p.failLoudly(err)
+		return message.Message{}, err
+	}
+	if p.tracer != nil {
+		p.tracer.prepared(msg)
+	}
+	return msg, nil
+}
+
+// transmit gửi msg (đã được prepare mã hóa/ký) đến khi được chấp nhận.
+// Nếu targetID đang bị Hold, msg chờ đến khi Release hoặc Close.
+func (p *Process) transmit(targetID int, msg message.Message, flow *flowControl) error {
+	if release := flow.held(); release != nil {
+		p.logAt(slog.LevelDebug, msg.HLC, "✋ HELD: %s to P%d until release", msg.ID, targetID)
+		select {
+		case <-release:
+		case <-p.closing:
+			return fmt.Errorf("%s to P%d: %w", msg.ID, targetID, errClosed)
+		}
+	}
+	err := p.sendWithBackpressure(targetID, msg, flow)
+	if p.tracer != nil {
+		p.tracer.sent(msg, time.Now(), err)
+	}
+	if err != nil {
+		p.errorf("❌ ERROR sending to P%d: %v", targetID, err)
+		return err
+	}
+	// Dòng SENT mang HLC của message, không phải HLC lúc được ack, để luôn
+	// đứng trước dòng DELIVERED của receiver khi sắp theo HLC
+	p.logAt(slog.LevelDebug, msg.HLC, "📤 SENT to P%d: %s | tm=%v | V_M=%s | %s",
+		targetID, msg.ID, msg.Timestamp, message.FormatVectorP(msg.VectorP), msg.PayloadSummary())
+	return nil
+}
 
-		if err := p.sendMessage(targetID, msg); err != nil {
-			p.Logger.Printf("❌ ERROR sending to P%d: %v", targetID, err)
-		} else {
-			p.Logger.Printf("📤 SENT to P%d: %s | tm=%v | V_M=%s",
-				targetID, msg.ID, msg.Timestamp, message.FormatVectorP(msg.VectorP))
-			fmt.Printf("[P%d] SENT to P%d: %s (tm=%v)\n", p.ID, targetID, msg.ID, msg.Timestamp)
+// sendWithBackpressure gửi msg và gửi lại nếu receiver từ chối vì buffer đầy
+// hoặc lỗi mạng (peer chưa listen, mất kết nối). Message đã được
+// PrepareToSend nên không thể bỏ qua: phải gửi lại đến khi được chấp nhận
+// (hoặc process bị Close), nếu không receiver sẽ chờ dependency này mãi mãi.
+func (p *Process) sendWithBackpressure(targetID int, msg message.Message, flow *flowControl) error {
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-p.closing:
+			return fmt.Errorf("%s to P%d: %w", msg.ID, targetID, errClosed)
+		default:
+		}
+		ack, err := p.sendMessage(targetID, msg)
+		if err != nil {
+			backoff := flow.onReject(errorRetryAfter)
+			p.warnf("🔁 RETRY %s to P%d (#%d) in %v: %v", msg.ID, targetID, attempt, backoff, err)
+			if !p.sleep(backoff) {
+				return fmt.Errorf("%s to P%d: %w", msg.ID, targetID, errClosed)
+			}
+			continue
+		}
+		// Duplicate: receiver đã nhận message này ở lần gửi trước mà ack bị mất
+		if ack.Accepted || ack.Duplicate {
+			flow.onAccept()
+			return nil
 		}
+		if ack.Invalid {
+			return fmt.Errorf("%s %w by P%d: %s", msg.ID, errInvalid, targetID, ack.Reason)
+		}
+
+		backoff := flow.onReject(ack.RetryAfter)
+		p.warnf("⏸ BACKPRESSURE from P%d: %s rejected (%s), retry #%d in %v",
+			targetID, msg.ID, ack.Reason, attempt, backoff)
+		if !p.sleep(backoff) {
+			return fmt.Errorf("%s to P%d: %w", msg.ID, targetID, errClosed)
+		}
+	}
+}
+
+var (
+	// errClosed là lỗi của các lần gửi bị dừng vì process đã Close
+	errClosed = errors.New("process closed")
+	// errInvalid: receiver từ chối message là không hợp lệ, gửi lại vô ích
+	errInvalid = errors.New("rejected as invalid")
+)
+
+// sleep chờ d, false nếu process bị Close trong lúc chờ
+func (p *Process) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-p.closing:
+		return false
 	}
 }
 
-func (p *Process) sendMessage(targetID int, msg message.Message) error {
-	address, ok := p.peers[targetID]
+// sendMessage gửi msg đến targetID (qua hop kế tiếp nếu không kề) và chờ ack
+func (p *Process) sendMessage(targetID int, msg message.Message) (message.Ack, error) {
+	hop, hops := p.route(targetID)
+	address, ok := p.peers[hop]
 	if !ok {
-		return fmt.Errorf("unknown peer: %d", targetID)
+		return message.Ack{}, fmt.Errorf("unknown peer: %d", hop)
 	}
-	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	conn, err := p.transport.Dial(hop, address, 5*time.Second)
 	if err != nil {
-		return err
+		return message.Ack{}, err
 	}
 	defer conn.Close()
-	return msg.Encode(conn)
+	if err := msg.Encode(conn); err != nil {
+		return message.Ack{}, err
+	}
+	// Ack đi ngược lại qua từng relay
+	conn.SetReadDeadline(time.Now().Add(time.Duration(hops) * hopTimeout))
+	return message.DecodeAck(conn)
 }
 
-// receiveMessage xử lý message nhận được
-func (p *Process) receiveMessage(msg message.Message) {
+func (p *Process) flowFor(targetID int) *flowControl {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.ReceivedMsgCount[msg.SenderID]++
+	if p.flow == nil {
+		p.flow = make(map[int]*flowControl)
+	}
+	if p.flow[targetID] == nil {
+		p.flow[targetID] = &flowControl{}
+	}
+	return p.flow[targetID]
+}
+
+// receiveMessage xử lý message nhận được và trả về ack cho sender
+// Nếu đang record, message và quyết định của process được ghi lại theo đúng
+// thứ tự đến để có thể replay.
+func (p *Process) receiveMessage(msg message.Message) message.Ack {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ack, outcome := p.receiveWithOutcome(msg)
+	// Replay chỉ tái hiện quyết định của SES, total order không được record
+	if p.recorder != nil && msg.Kind == message.KindCausal {
+		if err := p.recorder.RecordArrival(msg, outcome); err != nil {
+			p.errorf("Error recording %s: %v", msg.ID, err)
+		}
+	}
+	return ack
+}
+
+// receiveWithOutcome xử lý message (p.mu phải đang được giữ) và trả về
+// các quyết định BUFFERED/DELIVERED mà message này gây ra
+func (p *Process) receiveWithOutcome(msg message.Message) (message.Ack, Outcome) {
+	p.outcome = &Outcome{}
+	defer func() { p.outcome = nil }()
+
+	ack := p.receive(msg)
+
+	outcome := *p.outcome
+	outcome.Accepted = ack.Accepted
+	outcome.LocalTime = p.VectorClock.GetLocalTime()
+	outcome.BufferSize = p.MessageBuffer.Len()
+	return ack, outcome
+}
+
+func (p *Process) receive(msg message.Message) message.Ack {
+	ack := message.Ack{MessageID: msg.ID, Accepted: true}
 
+	// Không tin tưởng peer: kiểm tra mọi trường trước khi chạm vào vector clock
+	if err := p.validate(msg); err != nil {
+		return p.rejectInvalid(msg, err)
+	}
+	// Quá xa các message chưa đến từ cùng sender: chờ chúng trước
+	if w := p.window(msg); w != nil && w.ahead(msg.SeqNum) {
+		p.warnf("⛔ REJECTED: %s | seq_num=%d too far ahead of P%d's missing messages", msg.ID, msg.SeqNum, msg.SenderID)
+		ack.Accepted = false
+		ack.Reason = "too far ahead"
+		ack.RetryAfter = rejectRetryAfter
+		return ack
+	}
+	if msg.Kind != message.KindCausal {
+		return p.receiveTotal(msg)
+	}
+
+	p.mergeHLC(msg)
 	localTime := p.VectorClock.GetLocalTime()
-	p.Logger.Printf("📥 RECEIVED from P%d: %s | tm=%v | V_M=%s | tP=%v",
-		msg.SenderID, msg.ID, msg.Timestamp, message.FormatVectorP(msg.VectorP), localTime)
-	fmt.Printf("[P%d] RECEIVED from P%d: %s (tm=%v, tP=%v)\n",
-		p.ID, msg.SenderID, msg.ID, msg.Timestamp, localTime)
+	p.debugf("📥 RECEIVED from P%d: %s | tm=%v | V_M=%s | tP=%v | %s",
+		msg.SenderID, msg.ID, msg.Timestamp, message.FormatVectorP(msg.VectorP), localTime, msg.PayloadSummary())
 
 	// QUAN TRỌNG: Truyền senderID vào CanDeliver
 	canDeliver, reason := p.VectorClock.CanDeliver(msg.SenderID, msg.Timestamp, msg.VectorP)
 
+	// Buffer đầy: message deliver được vẫn nhận (không làm buffer lớn thêm)
+	if !canDeliver && p.bufferFull() {
+		switch p.overflowPolicy {
+		case OverflowFail:
+			p.failLoudly(fmt.Errorf("buffer overflow: %d messages buffered (limit %d) when %s arrived",
+				p.MessageBuffer.Len(), p.bufferLimit, msg.ID))
+		case OverflowReject, "":
+			p.RejectedMsgCount++
+			p.warnf("⛔ REJECTED: %s | Buffer full (%d/%d)", msg.ID, p.MessageBuffer.Len(), p.bufferLimit)
+			ack.Accepted = false
+			ack.Reason = "buffer full"
+			ack.RetryAfter = rejectRetryAfter
+			return ack
+		}
+	}
+
+	p.ReceivedMsgCount[msg.SenderID]++
+	p.markSeen(msg)
+
 	if canDeliver {
 		p.deliverMessage(msg)
 		// Sau khi deliver, thử deliver các message trong buffer
@@ -213,59 +654,178 @@ func (p *Process) receiveMessage(msg message.Message) {
 	} else {
 		p.bufferMessage(msg, reason)
 	}
+	return ack
+}
+
+// validate kiểm tra message theo cấu hình của process và phát hiện trùng lặp
+func (p *Process) validate(msg message.Message) error {
+	if err := msg.Validate(p.NumProcesses, p.ID); err != nil {
+		return err
+	}
+	if p.keyring != nil {
+		if err := p.keyring.Verify(&msg); err != nil {
+			return err
+		}
+	}
+	if p.payloadKeys != nil {
+		if err := p.payloadKeys.Authenticate(&msg); err != nil {
+			return err
+		}
+	}
+	if w := p.window(msg); w != nil && w.has(msg.SeqNum) {
+		return &message.ValidationError{MessageID: msg.ID, Kind: message.ErrDuplicate,
+			Detail: fmt.Sprintf("seq_num=%d from P%d already received", msg.SeqNum, msg.SenderID)}
+	}
+	return nil
+}
+
+// mergeHLC gộp HLC của message vừa nhận vào clock. HLC chỉ dùng để sắp
+// log nên message có HLC đi trước quá max offset vẫn được nhận: clock giới
+// hạn nó lại, chỉ log cảnh báo.
+func (p *Process) mergeHLC(msg message.Message) {
+	if err := p.Clock.Check(msg.HLC); err != nil {
+		p.warnf("⏱ HLC of %s from P%d clamped: %v", msg.ID, msg.SenderID, err)
+	}
+	p.Clock.Update(msg.HLC)
+}
+
+// seal mã hóa Content bằng payloadKeys rồi ký bằng keyring (nil = tắt),
+// cả hai bằng cùng một key (xem auth.Protect)
+func seal(msg *message.Message, keyring, payloadKeys *auth.Keyring) error {
+	return auth.Protect(msg, keyring, payloadKeys)
+}
+
+// rejectInvalid đếm và log message không hợp lệ, trả về ack Invalid
+func (p *Process) rejectInvalid(msg message.Message, err error) message.Ack {
+	p.countInvalid(message.RejectKind(err))
+	p.warnf("🚫 INVALID %s: %v", msg.ID, err)
+	if p.outcome != nil {
+		p.outcome.Reason = err.Error()
+	}
+	return message.Ack{MessageID: msg.ID, Invalid: true, Reason: err.Error(),
+		Duplicate: errors.Is(err, message.ErrDuplicate)}
+}
+
+func (p *Process) countInvalid(kind string) {
+	if p.InvalidMsgCount == nil {
+		p.InvalidMsgCount = make(map[string]int)
+	}
+	p.InvalidMsgCount[kind]++
+}
+
+// bufferFull kiểm tra buffer đã chạm giới hạn chưa.
+// Với OverflowSpill buffer không bao giờ đầy, phần vượt được ghi ra disk.
+func (p *Process) bufferFull() bool {
+	return p.bufferLimit > 0 && p.overflowPolicy != OverflowSpill &&
+		p.MessageBuffer.Len() >= p.bufferLimit
+}
+
+// failLoudly ghi lỗi ra log và console rồi dừng process
+func (p *Process) failLoudly(err error) {
+	p.errorf("💥 FATAL: %v", err)
+	fmt.Fprintf(os.Stderr, "[P%d] FATAL: %v\n", p.ID, err)
+	if p.recorder != nil {
+		p.recorder.Close()
+	}
+	if p.tracer != nil {
+		p.tracer.close(time.Now())
+	}
+	p.LogFile.Sync()
+	exit(1)
 }
 
 // deliverMessage deliver message và cập nhật vector clock
 func (p *Process) deliverMessage(msg message.Message) {
 	beforeTime := p.VectorClock.GetLocalTime()
 
+	// Ciphertext đã được xác thực khi message đến, chỉ giải mã khi deliver
+	if p.payloadKeys != nil {
+		if err := p.payloadKeys.Open(&msg); err != nil {
+			p.errorf("❌ ERROR decrypting %s: %v", msg.ID, err)
+		}
+	}
 	p.DeliveredMsgs = append(p.DeliveredMsgs, msg)
+	p.latencies = append(p.latencies, time.Since(msg.PhysicalTS))
 	p.VectorClock.DeliverMessage(msg.SenderID, msg.Timestamp, msg.VectorP)
+	p.Clock.Update(msg.HLC)
+	if p.outcome != nil {
+		p.outcome.Delivered = append(p.outcome.Delivered, msg.ID)
+	}
 
 	afterTime := p.VectorClock.GetLocalTime()
+	if p.tracer != nil {
+		p.tracer.delivered(msg, time.Now(), afterTime)
+	}
+
+	p.debugf("✅ DELIVERED: %s | tP: %v → %v", msg.ID, beforeTime, afterTime)
 
-	p.Logger.Printf("✅ DELIVERED: %s | tP: %v → %v", msg.ID, beforeTime, afterTime)
-	fmt.Printf("[P%d] ✓ DELIVERED: %s | tP: %v → %v\n", p.ID, msg.ID, beforeTime, afterTime)
+	// Message trong chuỗi request/response: gửi hop tiếp theo
+	if msg.Chain != nil {
+		p.continueChain(*msg.Chain)
+	}
 }
 
-// bufferMessage lưu message vào buffer
+// bufferMessage lưu message vào buffer, index theo dependency đang chặn nó
 func (p *Process) bufferMessage(msg message.Message, reason string) {
-	p.MessageBuffer = append(p.MessageBuffer, msg)
+	component, need, _ := p.VectorClock.WaitingOn(msg.VectorP)
+	if err := p.MessageBuffer.Add(msg, component, need); err != nil {
+		p.failLoudly(err)
+		return
+	}
+	p.BufferedMsgCount++
+	if p.tracer != nil {
+		p.tracer.buffered(msg, time.Now(), component, need, reason)
+	}
+	if p.outcome != nil {
+		p.outcome.Buffered = true
+		p.outcome.Reason = reason
+	}
 
-	p.Logger.Printf("🔄 BUFFERED: %s | Reason: %s | BufferSize: %d | tP: %v",
-		msg.ID, reason, len(p.MessageBuffer), p.VectorClock.GetLocalTime())
-	fmt.Printf("[P%d] ⊗ BUFFERED: %s | Reason: %s | Buffer size: %d\n",
-		p.ID, msg.ID, reason, len(p.MessageBuffer))
+	p.debugf("🔄 BUFFERED: %s | Reason: %s | BufferSize: %d | tP: %v",
+		msg.ID, reason, p.MessageBuffer.Len(), p.VectorClock.GetLocalTime())
 }
 
 // tryDeliverBuffered thử deliver các message trong buffer
+// Chỉ những message có dependency vừa được thỏa (tP[j] >= need) mới được
+// xét lại; message cũ nhất được deliver trước để giữ thứ tự FIFO.
 func (p *Process) tryDeliverBuffered() {
-	delivered := true
-	deliveryRound := 0
-
-	for delivered {
-		delivered = false
-		deliveryRound++
-
-		for i := 0; i < len(p.MessageBuffer); i++ {
-			msg := p.MessageBuffer[i]
-			// QUAN TRỌNG: Truyền senderID vào CanDeliver
-			canDeliver, _ := p.VectorClock.CanDeliver(msg.SenderID, msg.Timestamp, msg.VectorP)
-
-			if canDeliver {
-				p.Logger.Printf("📦 DELIVERING FROM BUFFER (Round %d): %s", deliveryRound, msg.ID)
-
-				p.deliverMessage(msg)
-				// Remove from buffer
-				p.MessageBuffer = append(p.MessageBuffer[:i], p.MessageBuffer[i+1:]...)
-				delivered = true
-				break // Start over
+	delivered := 0
+	ready := &readyQueue{}
+	p.wakeBuffered(ready)
+
+	for ready.Len() > 0 {
+		bm := heap.Pop(ready).(*bufferedMessage)
+
+		// Vẫn còn dependency khác chưa thỏa → index lại theo dependency mới
+		if component, need, blocked := p.VectorClock.WaitingOn(bm.msg.VectorP); blocked {
+			if err := p.MessageBuffer.requeue(bm, component, need); err != nil {
+				p.failLoudly(err)
+				return
 			}
+			continue
 		}
+
+		delivered++
+		p.debugf("📦 DELIVERING FROM BUFFER (#%d): %s", delivered, bm.msg.ID)
+		p.deliverMessage(bm.msg)
+		p.wakeBuffered(ready)
 	}
 
-	if deliveryRound > 1 {
-		p.Logger.Printf("✨ Delivered %d rounds from buffer", deliveryRound-1)
+	if delivered > 0 {
+		p.debugf("✨ Delivered %d messages from buffer", delivered)
+	}
+}
+
+// wakeBuffered đánh thức các message có dependency đã được tP hiện tại thỏa
+func (p *Process) wakeBuffered(ready *readyQueue) {
+	if p.MessageBuffer.Len() == 0 {
+		return
+	}
+	for j, value := range p.VectorClock.GetLocalTime() {
+		if err := p.MessageBuffer.wake(j, value, ready); err != nil {
+			p.failLoudly(err)
+			return
+		}
 	}
 }
 
@@ -281,8 +841,23 @@ func (p *Process) GetStats() map[string]interface{} {
 		"sent_messages":     p.SentMsgCount,
 		"received_messages": p.ReceivedMsgCount,
 		"delivered_count":   len(p.DeliveredMsgs),
-		"buffered_count":    len(p.MessageBuffer),
+		"buffered_count":    p.MessageBuffer.Len(),
+		"buffered_total":    p.BufferedMsgCount,
+		"spilled_count":     p.MessageBuffer.Spilled(),
+		"rejected_count":    p.RejectedMsgCount,
+		"forwarded_count":   p.ForwardedMsgCount,
+		"chains_completed":  p.ChainsCompleted,
+		"invalid_messages":  p.copyInvalidCounts(),
+		"transport_stats":   transport.Stats(p.transport),
+	}
+}
+
+func (p *Process) copyInvalidCounts() map[string]int {
+	counts := make(map[string]int, len(p.InvalidMsgCount))
+	for kind, n := range p.InvalidMsgCount {
+		counts[kind] = n
 	}
+	return counts
 }
 
 // WaitForCompletion chờ cho đến khi tất cả message được deliver
@@ -291,7 +866,7 @@ func (p *Process) WaitForCompletion(timeout time.Duration) error {
 
 	for time.Now().Before(deadline) {
 		p.mu.Lock()
-		bufferSize := len(p.MessageBuffer)
+		bufferSize := p.MessageBuffer.Len()
 		expectedTotal := 0
 
 		for _, count := range p.ReceivedMsgCount {
@@ -302,7 +877,7 @@ func (p *Process) WaitForCompletion(timeout time.Duration) error {
 		p.mu.Unlock()
 
 		if bufferSize == 0 && deliveredCount == expectedTotal {
-			p.Logger.Printf("✅ COMPLETION: All %d messages delivered!", deliveredCount)
+			p.infof("✅ COMPLETION: All %d messages delivered!", deliveredCount)
 			return nil
 		}
 
@@ -313,7 +888,7 @@ func (p *Process) WaitForCompletion(timeout time.Duration) error {
 	defer p.mu.Unlock()
 
 	return fmt.Errorf("TIMEOUT: Buffer=%d, Delivered=%d, Expected=%d",
-		len(p.MessageBuffer),
+		p.MessageBuffer.Len(),
 		len(p.DeliveredMsgs),
 		p.getTotalReceived())
 }
@@ -0,0 +1,53 @@
+package process
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxRecent là số dòng log gần nhất được giữ cho admin endpoint
+const maxRecent = 200
+
+// LogLine là một dòng log của process, Seq tăng dần từ 1
+type LogLine struct {
+	Seq  int    `json:"seq"`
+	Line string `json:"line"`
+}
+
+// recentLog giữ maxRecent dòng log gần nhất. Nó là một output của Logger
+// nên nhận mọi dòng mà process ghi vào file log.
+type recentLog struct {
+	mu    sync.Mutex
+	lines []LogLine // ring buffer, dòng Seq nằm ở lines[(Seq-1) % maxRecent]
+	seq   int
+}
+
+func (l *recentLog) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Logger gọi Write một lần cho mỗi dòng
+	l.seq++
+	line := LogLine{Seq: l.seq, Line: strings.TrimSuffix(string(b), "\n")}
+	if len(l.lines) < maxRecent {
+		l.lines = append(l.lines, line)
+	} else {
+		l.lines[(l.seq-1)%maxRecent] = line
+	}
+	return len(b), nil
+}
+
+// after trả về các dòng có Seq > seq còn được giữ, theo thứ tự
+func (l *recentLog) after(seq int) []LogLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var lines []LogLine
+	for i := range l.lines {
+		line := l.lines[(l.seq+i)%len(l.lines)]
+		if line.Seq > seq {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,227 @@
+package process
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/vectorclock"
+)
+
+// Outcome là các quyết định process đưa ra khi một message đến
+type Outcome struct {
+	Accepted   bool     `json:"accepted"`
+	Buffered   bool     `json:"buffered"`
+	Reason     string   `json:"reason,omitempty"`
+	Delivered  []string `json:"delivered,omitempty"` // ID các message được deliver, theo thứ tự
+	LocalTime  []int    `json:"local_time"`          // tP sau khi xử lý
+	BufferSize int      `json:"buffer_size"`
+}
+
+// RecordHeader là dòng đầu tiên của file record
+type RecordHeader struct {
+	ProcessID      int            `json:"process_id"`
+	NumProcesses   int            `json:"num_processes"`
+	Seed           int64          `json:"seed"`
+	BufferLimit    int            `json:"buffer_limit"`
+	OverflowPolicy OverflowPolicy `json:"overflow_policy"`
+}
+
+// EventKind phân biệt các loại event trong file record
+type EventKind string
+
+const (
+	// EventSend: process gọi PrepareToSend, làm thay đổi tP và V_P
+	EventSend EventKind = "send"
+	// EventArrival: một message đến receiveMessage
+	EventArrival EventKind = "arrival"
+)
+
+// Event là một thay đổi state của process theo đúng thứ tự xảy ra.
+// Send cũng phải được ghi vì PrepareToSend tăng tP[own] xen giữa các arrival.
+type Event struct {
+	Seq  int       `json:"seq"`
+	Kind EventKind `json:"kind"`
+
+	// EventSend
+	TargetID  int                       `json:"target_id,omitempty"`
+	Timestamp []int                     `json:"timestamp,omitempty"`
+	VectorP   []vectorclock.VectorEntry `json:"vector_p,omitempty"`
+
+	// EventArrival
+	Message *message.Message `json:"message,omitempty"`
+	Outcome *Outcome         `json:"outcome,omitempty"`
+}
+
+// Recorder ghi header và từng Event thành một dòng JSON (JSONL)
+type Recorder struct {
+	file *os.File
+	enc  *json.Encoder
+	seq  int
+}
+
+// NewRecorder tạo file record tại path và ghi header
+func NewRecorder(path string, header RecordHeader) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{file: file, enc: json.NewEncoder(file)}
+	if err := r.enc.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// RecordArrival ghi một message đến cùng outcome của nó
+func (r *Recorder) RecordArrival(msg message.Message, outcome Outcome) error {
+	r.seq++
+	return r.enc.Encode(Event{Seq: r.seq, Kind: EventArrival, Message: &msg, Outcome: &outcome})
+}
+
+// RecordSend ghi kết quả PrepareToSend(targetID)
+func (r *Recorder) RecordSend(targetID int, tm []int, vm []vectorclock.VectorEntry) error {
+	r.seq++
+	return r.enc.Encode(Event{Seq: r.seq, Kind: EventSend, TargetID: targetID, Timestamp: tm, VectorP: vm})
+}
+
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// ReadRecording đọc file record do Recorder ghi
+func ReadRecording(path string) (RecordHeader, []Event, error) {
+	var header RecordHeader
+	file, err := os.Open(path)
+	if err != nil {
+		return header, nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(file))
+	if err := dec.Decode(&header); err != nil {
+		return header, nil, fmt.Errorf("read header: %w", err)
+	}
+	var events []Event
+	for {
+		var e Event
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return header, events, fmt.Errorf("read event %d: %w", len(events)+1, err)
+		}
+		if e.Kind == EventArrival && (e.Message == nil || e.Outcome == nil) {
+			return header, events, fmt.Errorf("event %d: arrival without message or outcome", e.Seq)
+		}
+		events = append(events, e)
+	}
+	return header, events, nil
+}
+
+// Mismatch là một event mà replay cho kết quả khác với lúc record
+type Mismatch struct {
+	Seq    int
+	Kind   EventKind
+	What   string // message ID hoặc "send to P<n>"
+	Detail string
+}
+
+// ReplayReport là kết quả của Replay
+type ReplayReport struct {
+	Header     RecordHeader
+	Arrivals   int
+	Sends      int
+	Delivered  int
+	Buffered   int
+	Mismatches []Mismatch
+}
+
+// Replay chạy lại các event trong file record theo đúng thứ tự đã ghi:
+// send được áp dụng lại bằng PrepareToSend, arrival được đưa lại qua
+// receiveMessage. Mọi quyết định BUFFERED/DELIVERED và tP sau mỗi event được
+// so sánh với bản ghi. Log của process replay được ghi vào logOut.
+func Replay(path string, logOut io.Writer) (*ReplayReport, error) {
+	header, events, err := ReadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := newLineHandler(logOut, fmt.Sprintf("[P%d replay] ", header.ProcessID), "", slog.LevelDebug)
+	p := newProcess(header.ProcessID, "", 0, header.NumProcesses, nil, sink)
+	p.seed = header.Seed
+	p.bufferLimit = header.BufferLimit
+	p.overflowPolicy = header.OverflowPolicy
+	if header.OverflowPolicy == OverflowSpill && header.BufferLimit > 0 {
+		spill, err := os.CreateTemp("", fmt.Sprintf("ses-replay-p%d-*.spill", header.ProcessID))
+		if err != nil {
+			return nil, err
+		}
+		spill.Close()
+		if err := p.MessageBuffer.EnableSpill(spill.Name(), header.BufferLimit); err != nil {
+			return nil, err
+		}
+		defer p.MessageBuffer.Close()
+	}
+
+	report := &ReplayReport{Header: header}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range events {
+		switch e.Kind {
+		case EventSend:
+			report.Sends++
+			tm, vm := p.VectorClock.PrepareToSend(e.TargetID)
+			if !reflect.DeepEqual(tm, e.Timestamp) || !sameEntries(vm, e.VectorP) {
+				report.Mismatches = append(report.Mismatches, Mismatch{
+					Seq:  e.Seq,
+					Kind: e.Kind,
+					What: fmt.Sprintf("send to P%d", e.TargetID),
+					Detail: fmt.Sprintf("recorded tm=%v V_M=%s, replayed tm=%v V_M=%s",
+						e.Timestamp, message.FormatVectorP(e.VectorP), tm, message.FormatVectorP(vm)),
+				})
+			}
+
+		case EventArrival:
+			report.Arrivals++
+			_, outcome := p.receiveWithOutcome(*e.Message)
+			if !reflect.DeepEqual(normalizeOutcome(outcome), normalizeOutcome(*e.Outcome)) {
+				report.Mismatches = append(report.Mismatches, Mismatch{
+					Seq:    e.Seq,
+					Kind:   e.Kind,
+					What:   e.Message.ID,
+					Detail: fmt.Sprintf("recorded %+v, replayed %+v", *e.Outcome, outcome),
+				})
+			}
+			if outcome.Buffered {
+				report.Buffered++
+			}
+
+		default:
+			return report, fmt.Errorf("event %d: unknown kind %q", e.Seq, e.Kind)
+		}
+	}
+	report.Delivered = len(p.DeliveredMsgs)
+	return report, nil
+}
+
+// sameEntries so sánh hai V_M, coi slice rỗng và nil là như nhau
+func sameEntries(a, b []vectorclock.VectorEntry) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// normalizeOutcome coi slice rỗng và nil là như nhau (JSON bỏ qua slice rỗng)
+func normalizeOutcome(o Outcome) Outcome {
+	if len(o.Delivered) == 0 {
+		o.Delivered = nil
+	}
+	return o
+}
@@ -0,0 +1,95 @@
+package process
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// hopTimeout là thời gian chờ ack cho mỗi hop trên đường đến đích
+const hopTimeout = 5 * time.Second
+
+// route trả về hop kế tiếp đến targetID và số hop còn lại
+func (p *Process) route(targetID int) (hop int, hops int) {
+	p.mu.Lock()
+	t := p.topology
+	p.mu.Unlock()
+	if t == nil || targetID < 0 || targetID >= t.Size() {
+		return targetID, 1
+	}
+	return t.NextHop(p.ID, targetID), len(t.Path(p.ID, targetID)) - 1
+}
+
+// relaying cho biết msg đi qua process này đến process khác
+func (p *Process) relaying(msg message.Message) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.topology != nil && msg.ReceiverID != p.ID
+}
+
+// allowedHop kiểm tra peer (identity trong certificate) có được giao msg
+// cho process này không: chính sender, hoặc hop đứng ngay trước process này
+// trên đường sender → receiver. Relay có thể giả mạo message của sender;
+// dùng HMAC nếu cần xác thực end-to-end.
+func (p *Process) allowedHop(peerID int, msg message.Message) bool {
+	if peerID == msg.SenderID {
+		return true
+	}
+	p.mu.Lock()
+	t := p.topology
+	p.mu.Unlock()
+	if t == nil || !inRange(msg.SenderID, t.Size()) || !inRange(msg.ReceiverID, t.Size()) {
+		return false
+	}
+	return t.OnPath(msg.SenderID, msg.ReceiverID, peerID, p.ID)
+}
+
+func inRange(id int, n int) bool {
+	return id >= 0 && id < n
+}
+
+// forward relay msg đến hop kế tiếp và trả ack của phía sau về cho hop
+// trước. Relay không chạm vào vector clock: SES chỉ được áp dụng ở đích.
+func (p *Process) forward(msg message.Message) message.Ack {
+	if err := p.validateRelay(msg); err != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.rejectInvalid(msg, err)
+	}
+
+	msg.Hops++
+	hop, _ := p.route(msg.ReceiverID)
+	p.mu.Lock()
+	p.ForwardedMsgCount++
+	p.mu.Unlock()
+	p.debugf("🔀 FORWARD: %s (P%d → P%d) via P%d | hop %d",
+		msg.ID, msg.SenderID, msg.ReceiverID, hop, msg.Hops)
+
+	ack, err := p.sendMessage(msg.ReceiverID, msg)
+	if err != nil {
+		// Lỗi mạng phía sau: báo hop trước gửi lại sau
+		p.errorf("❌ ERROR forwarding %s to P%d: %v", msg.ID, hop, err)
+		return message.Ack{
+			MessageID:  msg.ID,
+			Reason:     fmt.Sprintf("relay P%d: %v", p.ID, err),
+			RetryAfter: rejectRetryAfter,
+		}
+	}
+	return ack
+}
+
+// validateRelay kiểm tra những gì relay kiểm tra được mà không cần key:
+// cấu trúc message và số hop (chặn vòng lặp khi các process có topology
+// khác nhau)
+func (p *Process) validateRelay(msg message.Message) error {
+	if !inRange(msg.ReceiverID, p.NumProcesses) {
+		return &message.ValidationError{MessageID: msg.ID, Kind: message.ErrWrongReceiver,
+			Detail: fmt.Sprintf("receiver_id=%d, want 0..%d", msg.ReceiverID, p.NumProcesses-1)}
+	}
+	if msg.Hops >= p.NumProcesses {
+		return &message.ValidationError{MessageID: msg.ID, Kind: message.ErrBadRoute,
+			Detail: fmt.Sprintf("relayed %d times, more than any route in %d processes", msg.Hops, p.NumProcesses)}
+	}
+	return msg.Validate(p.NumProcesses, msg.ReceiverID)
+}
@@ -0,0 +1,100 @@
+package process
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/topology"
+	"github.com/NationalWind/ses-project/pkg/transport"
+)
+
+// startLine chạy n process trên TCP loopback với topology đường thẳng
+// P0 - P1 - ... - P(n-1)
+func startLine(t *testing.T, n int) []*Process {
+	t.Helper()
+	var edges [][2]int
+	for i := 0; i+1 < n; i++ {
+		edges = append(edges, [2]int{i, i + 1})
+	}
+	line, err := topology.Graph(n, edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	procs := make([]*Process, n)
+	peers := make(map[int]string)
+	for i := range procs {
+		p := newQuietProcess(t, i, n)
+		p.Address, p.transport, p.topology, p.peers = "127.0.0.1", transport.TCP{}, line, peers
+		if err := p.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { p.listener.Close() })
+		peers[i] = p.listener.Addr().String()
+		procs[i] = p
+	}
+	return procs
+}
+
+func TestRelayDeliversToNonNeighbor(t *testing.T) {
+	procs := startLine(t, 4)
+	p0, p3 := procs[0], procs[3]
+
+	for i := 1; i <= 3; i++ {
+		tm, vm := p0.VectorClock.PrepareToSend(3)
+		msg := message.NewMessage(0, 3, i, "far", tm, vm)
+		if err := p0.sendWithBackpressure(3, msg, p0.flowFor(3)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p3.mu.Lock()
+	delivered := len(p3.DeliveredMsgs)
+	p3.mu.Unlock()
+	if delivered != 3 {
+		t.Fatalf("P3 delivered %d, want 3", delivered)
+	}
+	for _, relay := range procs[1:3] {
+		relay.mu.Lock()
+		forwarded, local := relay.ForwardedMsgCount, len(relay.DeliveredMsgs)
+		relay.mu.Unlock()
+		if forwarded != 3 || local != 0 {
+			t.Fatalf("P%d forwarded %d and delivered %d, want 3 and 0", relay.ID, forwarded, local)
+		}
+	}
+}
+
+func TestRelayStopsLoops(t *testing.T) {
+	procs := startLine(t, 3)
+	msg := message.NewMessage(0, 2, 1, "loop", []int{0, 0, 0}, nil)
+	msg.Hops = 3
+
+	ack := procs[1].forward(msg)
+	if !ack.Invalid || procs[1].InvalidMsgCount[message.ErrBadRoute.Error()] != 1 {
+		t.Fatalf("ack = %+v, invalid = %v", ack, procs[1].InvalidMsgCount)
+	}
+	if err := procs[1].validateRelay(msg); !errors.Is(err, message.ErrBadRoute) {
+		t.Fatalf("validateRelay() = %v", err)
+	}
+}
+
+func TestAllowedHop(t *testing.T) {
+	procs := startLine(t, 4)
+	msg := message.NewMessage(0, 3, 1, "far", []int{0, 0, 0, 0}, nil)
+	cases := []struct {
+		at, peer int
+		want     bool
+	}{
+		{1, 0, true},  // sender
+		{2, 1, true},  // relay ngay trước P2
+		{3, 2, true},  // relay ngay trước đích
+		{3, 1, false}, // P1 không kề P3
+		{2, 3, false}, // ngược chiều
+	}
+	for _, c := range cases {
+		if got := procs[c.at].allowedHop(c.peer, msg); got != c.want {
+			t.Errorf("P%d.allowedHop(P%d) = %v, want %v", c.at, c.peer, got, c.want)
+		}
+	}
+}
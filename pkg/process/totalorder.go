@@ -0,0 +1,365 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/auth"
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// Total-order multicast theo Lamport: message KindTotal được gửi đến mọi
+// process (kể cả chính sender) và mọi process deliver chúng theo cùng thứ
+// tự (Lamport, SenderID), độc lập với thứ tự nhân quả của SES.
+//
+//   - Gửi: L++, message mang Lamport = L, được đưa vào hold-back queue của
+//     sender và gửi đến mọi process khác
+//   - Nhận message hoặc ack từ q: L = max(L, Lamport) + 1, ghi nhận Lamport
+//     mới nhất từ q; nhận message thì đưa vào queue và gửi ack (Lamport mới)
+//     đến mọi process khác
+//   - Deliver message đầu queue m khi mọi process khác q đã gửi đến đây
+//     một message hoặc ack có Lamport >= m.Lamport: message sau đó của q
+//     chắc chắn xếp sau m
+//
+// Điều kiện cuối cần kênh FIFO và không mất message giữa từng cặp process.
+// Mỗi cặp có một outbox gửi lần lượt từng message, gửi lại (backoff có giới
+// hạn) đến khi receiver chấp nhận rồi mới gửi message tiếp theo, nên mọi
+// message đều đến và đến đúng thứ tự gửi, kể cả khi peer chưa listen. Nếu
+// peer từ chối hẳn một message, các process không thể deliver cùng một dãy
+// nữa: process dừng hẳn (failLoudly) thay vì bỏ qua message đó.
+
+// totalOrder là state total order của một process (được bảo vệ bởi p.mu)
+type totalOrder struct {
+	clock     int64             // Lamport clock
+	count     int               // số message đã multicast, dùng cho ID
+	latest    []int64           // Lamport lớn nhất đã nhận từ mỗi process
+	queue     []message.Message // hold-back, theo (Lamport, SenderID)
+	delivered []message.Message
+	outboxes  map[int]*outbox
+	seen      []seqWindow // SeqNum multicast đã nhận từ mỗi sender
+}
+
+// outboxItem là một message chờ gửi; done nhận kết quả (nil với ack)
+type outboxItem struct {
+	msg  message.Message
+	done chan error
+}
+
+// outbox gửi message đến một peer theo đúng thứ tự được đưa vào
+type outbox struct {
+	mu     sync.Mutex
+	items  []outboxItem
+	wake   chan struct{}
+	closed bool
+}
+
+func (o *outbox) push(item outboxItem) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		if item.done != nil {
+			item.done <- errClosed
+		}
+		return
+	}
+	o.items = append(o.items, item)
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run gửi các message trong outbox bằng send đến khi outbox bị đóng
+func (o *outbox) run(send func(message.Message) error) {
+	for range o.wake {
+		for {
+			o.mu.Lock()
+			if len(o.items) == 0 {
+				o.mu.Unlock()
+				break
+			}
+			item := o.items[0]
+			o.items = o.items[1:]
+			o.mu.Unlock()
+
+			err := send(item.msg)
+			if item.done != nil {
+				item.done <- err
+			}
+		}
+	}
+}
+
+func (o *outbox) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.closed {
+		o.closed = true
+		close(o.wake)
+	}
+}
+
+// totalState trả về state total order, tạo mới nếu chưa có (p.mu phải
+// đang được giữ)
+func (p *Process) totalState() *totalOrder {
+	if p.total == nil {
+		p.total = &totalOrder{
+			latest:   make([]int64, p.NumProcesses),
+			outboxes: make(map[int]*outbox),
+		}
+	}
+	return p.total
+}
+
+// outboxFor trả về outbox đến targetID, khởi động goroutine gửi nếu chưa có
+// (p.mu phải đang được giữ)
+func (p *Process) outboxFor(targetID int) *outbox {
+	t := p.totalState()
+	o := t.outboxes[targetID]
+	if o == nil {
+		o = &outbox{wake: make(chan struct{}, 1)}
+		t.outboxes[targetID] = o
+		flow := &flowControl{}
+		go o.run(func(msg message.Message) error {
+			return p.sendTotal(targetID, msg, flow)
+		})
+	}
+	return o
+}
+
+// sendTotal gửi msg qua cùng vòng gửi lại với message SES. Peer từ chối
+// msg là không hợp lệ thì total order không còn đảm bảo: process dừng hẳn.
+func (p *Process) sendTotal(targetID int, msg message.Message, flow *flowControl) error {
+	err := p.sendWithBackpressure(targetID, msg, flow)
+	if errors.Is(err, errInvalid) {
+		p.failLoudly(fmt.Errorf("total order broken: %w", err))
+	}
+	return err
+}
+
+// sealTotal mã hóa/ký message total order ngay khi tạo (p.mu phải đang
+// được giữ). Lamport clock đã tăng nên không ký được là lỗi không sửa được.
+func (p *Process) sealTotal(msg *message.Message) {
+	if err := seal(msg, p.keyring, p.payloadKeys); err != nil {
+		p.failLoudly(fmt.Errorf("%s to P%d cannot be protected: %w", msg.ID, msg.ReceiverID, err))
+	}
+}
+
+// Multicast gửi content đến mọi process với total order: mọi process (kể
+// cả process này) deliver các message Multicast theo cùng một thứ tự. done
+// nhận lỗi gửi của các peer, gộp lại, khi mọi peer đã chấp nhận message.
+func (p *Process) Multicast(content string) (msg message.Message, done <-chan error) {
+	p.mu.Lock()
+	// Không có key cho một peer nào đó: không tạo message, Lamport clock
+	// không đổi
+	for target := 0; target < p.NumProcesses; target++ {
+		for _, keys := range []*auth.Keyring{p.keyring, p.payloadKeys} {
+			if target == p.ID || keys == nil {
+				continue
+			}
+			if err := keys.CanSign(p.ID, target); err != nil {
+				p.mu.Unlock()
+				failed := make(chan error, 1)
+				failed <- fmt.Errorf("cannot multicast: %w", err)
+				return message.Message{}, failed
+			}
+		}
+	}
+	t := p.totalState()
+	t.clock++
+	t.count++
+	msg = message.Message{
+		ID:         fmt.Sprintf("P%d-ALL-M%d", p.ID, t.count),
+		SenderID:   p.ID,
+		ReceiverID: p.ID,
+		Content:    content,
+		PhysicalTS: time.Now(),
+		HLC:        p.Clock.Now(),
+		SeqNum:     t.count,
+		Kind:       message.KindTotal,
+		Lamport:    t.clock,
+	}
+	t.latest[p.ID] = t.clock
+	p.enqueueTotal(msg)
+	p.logAt(slog.LevelDebug, msg.HLC, "📣 MULTICAST %s | lamport=%d | %s", msg.ID, msg.Lamport, msg.PayloadSummary())
+
+	var pending []chan error
+	for target := 0; target < p.NumProcesses; target++ {
+		if target == p.ID {
+			continue
+		}
+		c := msg
+		c.ReceiverID = target
+		p.sealTotal(&c)
+		d := make(chan error, 1)
+		p.outboxFor(target).push(outboxItem{c, d})
+		pending = append(pending, d)
+	}
+	p.deliverTotal()
+	p.mu.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, d := range pending {
+			errs = append(errs, <-d)
+		}
+		result <- errors.Join(errs...)
+	}()
+	return msg, result
+}
+
+// receiveTotal xử lý message KindTotal hoặc KindTotalAck đã hợp lệ (p.mu
+// phải đang được giữ)
+func (p *Process) receiveTotal(msg message.Message) message.Ack {
+	t := p.totalState()
+	t.clock = max(t.clock, msg.Lamport) + 1
+	t.latest[msg.SenderID] = max(t.latest[msg.SenderID], msg.Lamport)
+	p.mergeHLC(msg)
+
+	if msg.Kind == message.KindTotal {
+		p.markSeen(msg)
+		p.debugf("📨 TOTAL-ORDER from P%d: %s | lamport=%d | L=%d | %s",
+			msg.SenderID, msg.ID, msg.Lamport, t.clock, msg.PayloadSummary())
+		p.enqueueTotal(msg)
+
+		// Ack cho mọi process khác biết L của process này đã vượt qua msg
+		t.clock++
+		t.latest[p.ID] = t.clock
+		for target := 0; target < p.NumProcesses; target++ {
+			if target == p.ID {
+				continue
+			}
+			ack := message.Message{
+				ID:         fmt.Sprintf("P%d-ACK-L%d-P%d", p.ID, t.clock, target),
+				SenderID:   p.ID,
+				ReceiverID: target,
+				PhysicalTS: time.Now(),
+				HLC:        p.Clock.Now(),
+				Kind:       message.KindTotalAck,
+				Lamport:    t.clock,
+			}
+			p.sealTotal(&ack)
+			p.outboxFor(target).push(outboxItem{msg: ack})
+		}
+	}
+	p.deliverTotal()
+	return message.Ack{MessageID: msg.ID, Accepted: true}
+}
+
+// enqueueTotal đưa msg vào hold-back queue, giữ thứ tự (Lamport, SenderID)
+func (p *Process) enqueueTotal(msg message.Message) {
+	t := p.total
+	i := sort.Search(len(t.queue), func(i int) bool { return totalBefore(msg, t.queue[i]) })
+	t.queue = append(t.queue, message.Message{})
+	copy(t.queue[i+1:], t.queue[i:])
+	t.queue[i] = msg
+}
+
+// totalBefore là thứ tự total order: Lamport trước, SenderID khi bằng nhau
+func totalBefore(a, b message.Message) bool {
+	if a.Lamport != b.Lamport {
+		return a.Lamport < b.Lamport
+	}
+	return a.SenderID < b.SenderID
+}
+
+// deliverTotal deliver các message đầu queue không còn message nào có thể
+// xếp trước (p.mu phải đang được giữ)
+func (p *Process) deliverTotal() {
+	t := p.total
+	for len(t.queue) > 0 {
+		head := t.queue[0]
+		for q, latest := range t.latest {
+			if q != p.ID && latest < head.Lamport {
+				return // q có thể vẫn gửi message xếp trước head
+			}
+		}
+		t.queue = t.queue[1:]
+
+		if head.SenderID != p.ID && p.payloadKeys != nil {
+			if err := p.payloadKeys.Open(&head); err != nil {
+				p.errorf("❌ ERROR decrypting %s: %v", head.ID, err)
+			}
+		}
+		t.delivered = append(t.delivered, head)
+		p.Clock.Update(head.HLC)
+		p.debugf("🔢 TOTAL DELIVERED #%d: %s | lamport=%d from P%d",
+			len(t.delivered), head.ID, head.Lamport, head.SenderID)
+	}
+}
+
+// TotalOrderDelivered trả về bản sao các message Multicast đã deliver,
+// theo total order
+func (p *Process) TotalOrderDelivered() []message.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.total == nil {
+		return nil
+	}
+	return append([]message.Message(nil), p.total.delivered...)
+}
+
+// MulticastMessages multicast count message với total order, cách nhau một
+// khoảng random trung bình interval/2 theo seed (như SendMessages)
+func (p *Process) MulticastMessages(count int, messagesPerMinute int) {
+	interval := time.Minute / time.Duration(messagesPerMinute)
+	rng := rand.New(rand.NewSource(p.seed - 1))
+	var pending []<-chan error
+	for i := 0; i < count; i++ {
+		time.Sleep(time.Duration(rng.Int63n(int64(interval))))
+		_, done := p.Multicast(fmt.Sprintf("total %d", i+1))
+		pending = append(pending, done)
+	}
+	for _, done := range pending {
+		<-done
+	}
+	p.infof("=== FINISHED MULTICASTING %d MESSAGES ===", count)
+}
+
+// WaitForTotalOrder chờ đến khi process đã deliver expected message
+// Multicast và hold-back queue rỗng
+func (p *Process) WaitForTotalOrder(expected int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		p.mu.Lock()
+		delivered, queued := 0, 0
+		if p.total != nil {
+			delivered, queued = len(p.total.delivered), len(p.total.queue)
+		}
+		p.mu.Unlock()
+		if delivered >= expected && queued == 0 {
+			p.infof("✅ TOTAL ORDER: All %d multicast messages delivered!", delivered)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("TIMEOUT: total order delivered %d of %d, %d in hold-back queue", delivered, expected, queued)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// closeOutboxes dừng các goroutine gửi total order
+func (p *Process) closeOutboxes() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.total == nil {
+		return
+	}
+	for _, o := range p.total.outboxes {
+		o.close()
+	}
+}
+
+// totalDelivered đếm message Multicast đã deliver (p.mu phải đang được giữ)
+func (p *Process) totalDelivered() int {
+	if p.total == nil {
+		return 0
+	}
+	return len(p.total.delivered)
+}
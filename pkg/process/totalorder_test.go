@@ -0,0 +1,132 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/transport"
+	"github.com/NationalWind/ses-project/pkg/verify"
+)
+
+// Mọi process multicast cùng lúc, xen với message SES: thứ tự deliver total
+// order phải giống nhau ở mọi process và SES vẫn deliver đủ
+func TestMulticastDeliversSameOrderEverywhere(t *testing.T) {
+	const n, perProcess = 4, 5
+	c := NewCluster(n, io.Discard)
+	if err := c.Listen(func(int) transport.Transport { return transport.TCP{} }); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for _, p := range c.Processes {
+		wg.Add(1)
+		go func(p *Process) {
+			defer wg.Done()
+			for i := 0; i < perProcess; i++ {
+				_, done := p.Multicast(fmt.Sprintf("total %d", i+1))
+				_, sent, err := p.Send((p.ID+1)%n, "causal")
+				if err == nil {
+					err = <-sent
+				}
+				if err := errors.Join(err, <-done); err != nil {
+					t.Error(err)
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	sequences := make(map[int][]string)
+	for _, p := range c.Processes {
+		if err := p.WaitForTotalOrder(n*perProcess, 5*time.Second); err != nil {
+			t.Fatalf("P%d: %v", p.ID, err)
+		}
+		for _, msg := range p.TotalOrderDelivered() {
+			sequences[p.ID] = append(sequences[p.ID], msg.ID)
+		}
+	}
+	if mismatches := verify.SameOrder(sequences, n*perProcess); len(mismatches) > 0 {
+		t.Fatalf("total order differs: %v", mismatches)
+	}
+	if err := c.WaitIdle(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Multicast của cùng một sender giữ thứ tự gửi
+	var fromP0 []string
+	for _, id := range sequences[0] {
+		if id[:3] == "P0-" {
+			fromP0 = append(fromP0, id)
+		}
+	}
+	if want := []string{"P0-ALL-M1", "P0-ALL-M2", "P0-ALL-M3", "P0-ALL-M4", "P0-ALL-M5"}; !reflect.DeepEqual(fromP0, want) {
+		t.Fatalf("P0 multicasts delivered as %v", fromP0)
+	}
+}
+
+// Multicast khi một peer chưa listen: message đến peer đó được gửi lại đến
+// khi peer lên, nên mọi process vẫn deliver cùng một dãy đầy đủ
+func TestMulticastReachesPeerThatStartsLate(t *testing.T) {
+	const n, perProcess = 3, 3
+	c := NewCluster(n, io.Discard)
+	defer c.Close()
+
+	// Như Cluster.Listen nhưng P2 chỉ có địa chỉ, chưa listen
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(reserved.Addr().String())
+	reserved.Close()
+	peers := map[int]string{2: reserved.Addr().String()}
+	for _, p := range c.Processes {
+		p.Address, p.transport, p.peers = "127.0.0.1", transport.TCP{}, peers
+	}
+	for _, p := range c.Processes[:2] {
+		if err := p.Start(); err != nil {
+			t.Fatal(err)
+		}
+		peers[p.ID] = p.listener.Addr().String()
+	}
+	late := c.Processes[2]
+
+	var pending []<-chan error
+	for _, p := range c.Processes[:2] {
+		for i := 0; i < perProcess; i++ {
+			_, done := p.Multicast(fmt.Sprintf("early %d", i+1))
+			pending = append(pending, done)
+		}
+	}
+	time.Sleep(3 * errorRetryAfter) // vài lần gửi đến P2 thất bại
+
+	late.Port, _ = strconv.Atoi(port)
+	if err := late.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, done := range pending {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sequences := make(map[int][]string)
+	for _, p := range c.Processes {
+		if err := p.WaitForTotalOrder(2*perProcess, 5*time.Second); err != nil {
+			t.Fatalf("P%d: %v", p.ID, err)
+		}
+		for _, msg := range p.TotalOrderDelivered() {
+			sequences[p.ID] = append(sequences[p.ID], msg.ID)
+		}
+	}
+	if mismatches := verify.SameOrder(sequences, 2*perProcess); len(mismatches) > 0 {
+		t.Fatalf("total order differs: %v", mismatches)
+	}
+}
@@ -0,0 +1,209 @@
+package process
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/tracing"
+)
+
+// Mỗi message là một trace riêng với ba span:
+//
+//   - send (producer, ở sender): từ lúc tạo message đến khi receiver chấp
+//     nhận, link đến span deliver mới nhất từ mỗi process mà sender đã
+//     deliver trước đó, tức các message xảy ra trước (happened-before)
+//   - deliver (consumer, ở receiver): con của send và link đến send, từ lúc
+//     message đến đến lúc được deliver
+//   - buffered (internal, ở receiver): con của deliver, thời gian message
+//     nằm trong buffer, chỉ có khi message phải chờ
+//
+// ID được tính từ message ID và giờ gửi (PhysicalTS), nên sender và
+// receiver tính ra cùng ID mà không cần thêm gì vào message.
+
+// tracer chuyển các sự kiện SES của process thành span
+type tracer struct {
+	mu       sync.Mutex
+	exporter *tracing.Exporter
+	links    map[string][]tracing.Link // message đang gửi: link lấy lúc prepare
+	arrived  map[string]bufferedSince  // message đang trong buffer
+	frontier map[int]tracing.Link      // span deliver mới nhất từ mỗi sender
+}
+
+type bufferedSince struct {
+	msg             message.Message
+	at              time.Time
+	component, need int
+	reason          string
+}
+
+func newTracer(exporter *tracing.Exporter) *tracer {
+	return &tracer{
+		exporter: exporter,
+		links:    make(map[string][]tracing.Link),
+		arrived:  make(map[string]bufferedSince),
+		frontier: make(map[int]tracing.Link),
+	}
+}
+
+// StartTracing ghi span của mọi message gửi và deliver vào
+// process_N.trace.jsonl (OTLP/JSON) trong thư mục log. Phải gọi trước Start.
+func (p *Process) StartTracing() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	path := filepath.Join(p.logDir, fmt.Sprintf("process_%d.trace.jsonl", p.ID))
+	exporter, err := tracing.Create(path, fmt.Sprintf("ses-P%d", p.ID),
+		tracing.String("service.namespace", "ses"),
+		tracing.String("service.instance.id", strconv.Itoa(p.ID)),
+		tracing.Int("ses.process.id", p.ID),
+		tracing.Int("ses.num_processes", p.NumProcesses))
+	if err != nil {
+		return err
+	}
+	p.tracer = newTracer(exporter)
+	p.infof("Tracing messages to %s", path)
+	return nil
+}
+
+func traceID(msg message.Message) tracing.TraceID {
+	return tracing.NewTraceID(msg.ID, strconv.FormatInt(msg.PhysicalTS.UnixNano(), 10))
+}
+
+func spanID(msg message.Message, kind string) tracing.SpanID {
+	return tracing.NewSpanID(msg.ID, strconv.FormatInt(msg.PhysicalTS.UnixNano(), 10), kind)
+}
+
+// messageAttrs là attribute chung của mọi span về msg
+func messageAttrs(msg message.Message) []tracing.Attribute {
+	return []tracing.Attribute{
+		tracing.String("messaging.system", "ses"),
+		tracing.String("messaging.message.id", msg.ID),
+		tracing.String("messaging.destination.name", fmt.Sprintf("P%d", msg.ReceiverID)),
+		tracing.Int("ses.sender", msg.SenderID),
+		tracing.Int("ses.receiver", msg.ReceiverID),
+		tracing.String("ses.tm", fmt.Sprint(msg.Timestamp)),
+		tracing.String("ses.v_m", message.FormatVectorP(msg.VectorP)),
+		tracing.String("ses.hlc", msg.HLC.String()),
+	}
+}
+
+// prepared ghi lại các message xảy ra trước msg (p.mu phải đang được giữ,
+// để không lẫn với deliver xảy ra sau prepare)
+func (t *tracer) prepared(msg message.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	senders := make([]int, 0, len(t.frontier))
+	for sender := range t.frontier {
+		senders = append(senders, sender)
+	}
+	sort.Ints(senders)
+	links := make([]tracing.Link, 0, len(senders))
+	for _, sender := range senders {
+		links = append(links, t.frontier[sender])
+	}
+	t.links[msg.ID] = links
+}
+
+// sent xuất span send của msg, err != nil nếu gửi thất bại
+func (t *tracer) sent(msg message.Message, end time.Time, err error) {
+	t.mu.Lock()
+	links := t.links[msg.ID]
+	delete(t.links, msg.ID)
+	t.mu.Unlock()
+
+	span := tracing.Span{
+		TraceID:    traceID(msg),
+		SpanID:     spanID(msg, "send"),
+		Name:       fmt.Sprintf("send P%d", msg.ReceiverID),
+		Kind:       tracing.KindProducer,
+		Start:      msg.PhysicalTS,
+		End:        end,
+		Attributes: append(messageAttrs(msg), tracing.String("messaging.operation.type", "send")),
+		Links:      links,
+	}
+	if err != nil {
+		span.Status, span.StatusMessage = tracing.StatusError, err.Error()
+	}
+	t.exporter.Export(span)
+}
+
+// buffered ghi nhận msg vào buffer lúc at, chờ tP[component] >= need
+func (t *tracer) buffered(msg message.Message, at time.Time, component, need int, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.arrived[msg.ID] = bufferedSince{msg, at, component, need, reason}
+}
+
+// delivered xuất span deliver (và buffered nếu msg đã phải chờ) của msg,
+// tP là local time sau khi deliver
+func (t *tracer) delivered(msg message.Message, at time.Time, tP []int) {
+	t.mu.Lock()
+	b, wasBuffered := t.arrived[msg.ID]
+	delete(t.arrived, msg.ID)
+	deliver := tracing.Link{TraceID: traceID(msg), SpanID: spanID(msg, "deliver"),
+		Attributes: []tracing.Attribute{tracing.String("ses.link", "happened_before")}}
+	t.frontier[msg.SenderID] = deliver
+	t.mu.Unlock()
+
+	start := at
+	if wasBuffered {
+		start = b.at
+		t.exporter.Export(bufferedSpan(b, at, tracing.StatusUnset, ""))
+	}
+	t.exporter.Export(tracing.Span{
+		TraceID: deliver.TraceID,
+		SpanID:  deliver.SpanID,
+		Parent:  spanID(msg, "send"),
+		Name:    fmt.Sprintf("deliver P%d", msg.SenderID),
+		Kind:    tracing.KindConsumer,
+		Start:   start,
+		End:     at,
+		Attributes: append(messageAttrs(msg),
+			tracing.String("messaging.operation.type", "process"),
+			tracing.Bool("ses.buffered", wasBuffered),
+			tracing.String("ses.t_p", fmt.Sprint(tP))),
+		Links: []tracing.Link{{TraceID: deliver.TraceID, SpanID: spanID(msg, "send"),
+			Attributes: []tracing.Attribute{tracing.String("ses.link", "sent_by")}}},
+	})
+}
+
+func bufferedSpan(b bufferedSince, end time.Time, status tracing.StatusCode, statusMessage string) tracing.Span {
+	return tracing.Span{
+		TraceID: traceID(b.msg),
+		SpanID:  spanID(b.msg, "buffered"),
+		Parent:  spanID(b.msg, "deliver"),
+		Name:    "buffered",
+		Kind:    tracing.KindInternal,
+		Start:   b.at,
+		End:     end,
+		Attributes: append(messageAttrs(b.msg),
+			tracing.String("ses.reason", b.reason),
+			tracing.Int("ses.waiting_on.process", b.component),
+			tracing.Int("ses.waiting_on.time", b.need)),
+		Status:        status,
+		StatusMessage: statusMessage,
+	}
+}
+
+// close xuất span buffered của message chưa bao giờ được deliver rồi đóng
+// file trace
+func (t *tracer) close(at time.Time) error {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.arrived))
+	for id := range t.arrived {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		t.exporter.Export(bufferedSpan(t.arrived[id], at, tracing.StatusError, "still buffered at shutdown"))
+	}
+	t.arrived = make(map[string]bufferedSince)
+	t.mu.Unlock()
+	return t.exporter.Close()
+}
@@ -0,0 +1,89 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/tracing"
+	"github.com/NationalWind/ses-project/pkg/transport"
+)
+
+// Cùng kịch bản với TestHoldForcesBuffering: P1-P2-M1 phải chờ P0-P2-M1
+// trong buffer. Span của nó phải nối được về send ở P1, và send ở P1 phải
+// link đến deliver của P0-P1-M1 đã xảy ra trước.
+func TestTraceLinksSendDeliverAndBuffer(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCluster(3, io.Discard)
+	for _, p := range c.Processes {
+		p.logDir = dir
+		if err := p.StartTracing(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Listen(func(int) transport.Transport { return transport.TCP{} }); err != nil {
+		t.Fatal(err)
+	}
+	p0, p1 := c.Processes[0], c.Processes[1]
+
+	if err := p0.Hold(2); err != nil {
+		t.Fatal(err)
+	}
+	p0.Send(2, "held")
+	send(t, p0, 1, "first")
+	waitFor(t, func() bool { return len(c.Delivered(1)) == 1 })
+	send(t, p1, 2, "second")
+	waitFor(t, func() bool { return len(c.Buffered(2)) == 1 })
+	p0.Release(2)
+	if err := c.WaitIdle(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	spans := make(map[string]tracing.Span) // "<message> <name>"
+	for id := 0; id < 3; id++ {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("process_%d.trace.jsonl", id)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		read, err := tracing.Read(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range read {
+			if s.Attr("service.name") != fmt.Sprintf("ses-P%d", id) {
+				t.Errorf("%s in trace of P%d has service %v", s.Name, id, s.Attr("service.name"))
+			}
+			spans[fmt.Sprintf("%s %s", s.Attr("messaging.message.id"), s.Name)] = s
+		}
+	}
+	if len(spans) != 7 {
+		t.Fatalf("got %d spans, want 3 send + 3 deliver + 1 buffered: %v", len(spans), spans)
+	}
+
+	sent, delivered, buffered := spans["P1-P2-M1 send P2"], spans["P1-P2-M1 deliver P1"], spans["P1-P2-M1 buffered"]
+	if delivered.TraceID != sent.TraceID || delivered.Parent != sent.SpanID ||
+		len(delivered.Links) != 1 || delivered.Links[0].SpanID != sent.SpanID {
+		t.Errorf("deliver span not linked to send span: %+v", delivered)
+	}
+	if buffered.Parent != delivered.SpanID || buffered.Attr("ses.waiting_on.process") != int64(0) ||
+		buffered.Start != delivered.Start || buffered.End != delivered.End {
+		t.Errorf("buffered span = %+v, deliver span = %+v", buffered, delivered)
+	}
+	if delivered.Attr("ses.buffered") != true || spans["P0-P1-M1 deliver P0"].Attr("ses.buffered") != false {
+		t.Error("ses.buffered not set")
+	}
+
+	// P1 deliver P0-P1-M1 trước khi gửi P1-P2-M1
+	before := spans["P0-P1-M1 deliver P0"]
+	if len(sent.Links) != 1 || sent.Links[0].TraceID != before.TraceID || sent.Links[0].SpanID != before.SpanID {
+		t.Errorf("send span links = %+v, want deliver of P0-P1-M1", sent.Links)
+	}
+	if len(spans["P0-P2-M1 send P2"].Links) != 0 {
+		t.Errorf("P0 had delivered nothing, links = %+v", spans["P0-P2-M1 send P2"].Links)
+	}
+}
@@ -0,0 +1,94 @@
+package process
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+// SetWorkload gắn generator cho process. Phải gọi trước Start để message
+// trong chuỗi request/response đến sớm vẫn được gửi tiếp.
+func (p *Process) SetWorkload(g *workload.Generator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.workload = g
+}
+
+// RunWorkload gửi message theo lịch của workload đã gắn và chờ đến khi mọi
+// message (kể cả các hop của chuỗi mà process này gửi) được chấp nhận.
+// Không như SendMessages, các send không chờ nhau: receiver chậm không làm
+// chậm lịch gửi đến receiver khác.
+func (p *Process) RunWorkload() error {
+	p.mu.Lock()
+	g := p.workload
+	p.mu.Unlock()
+	if g == nil {
+		return fmt.Errorf("no workload configured")
+	}
+
+	p.infof("=== STARTING WORKLOAD ===")
+	p.infof("Messages: %d", g.Total())
+
+	var wg sync.WaitGroup
+	for n := 1; ; n++ {
+		s, ok := g.Next()
+		if !ok {
+			break
+		}
+		flow := p.flowFor(s.Target)
+		time.Sleep(s.Delay + flow.delay())
+
+		content := fmt.Sprintf("workload %d", n)
+		if s.Chain != nil {
+			content = s.Chain.String()
+			p.debugf("🔗 CHAIN %s started, %d hops", s.Chain.Chain, s.Chain.Remaining+1)
+		}
+		msg, err := p.prepareHop(s.Target, content, s.Chain)
+		if err != nil {
+			p.errorf("❌ ERROR sending to P%d: %v", s.Target, err)
+			continue
+		}
+		wg.Add(1)
+		go func(target int) {
+			defer wg.Done()
+			p.transmit(target, msg, flow)
+		}(s.Target)
+	}
+	wg.Wait()
+	p.chains.Wait()
+	p.logFinalState()
+	return nil
+}
+
+// continueChain gửi hop tiếp theo khi deliver một message trong chuỗi
+// (p.mu phải đang được giữ). Message gửi đi sau khi deliver nên phụ thuộc
+// nhân quả vào message vừa deliver.
+func (p *Process) continueChain(hop workload.Hop) {
+	if p.workload == nil {
+		p.warnf("🔗 CHAIN %s dropped: this process has no workload to continue it", hop.Chain)
+		return
+	}
+	if err := p.workload.CheckHop(hop); err != nil {
+		p.warnf("🔗 CHAIN %s dropped: %v", hop.Chain, err)
+		return
+	}
+	next, target, ok := p.workload.NextHop(hop)
+	if !ok {
+		p.ChainsCompleted++
+		p.debugf("🔗 CHAIN %s completed", hop.Chain)
+		return
+	}
+
+	p.chains.Add(1)
+	go func() {
+		defer p.chains.Done()
+		msg, err := p.prepareHop(target, next.String(), &next)
+		if err != nil {
+			p.errorf("❌ ERROR continuing chain %s: %v", hop.Chain, err)
+			return
+		}
+		p.transmit(target, msg, p.flowFor(target))
+	}()
+}
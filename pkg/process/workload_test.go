@@ -0,0 +1,84 @@
+package process
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/workload"
+)
+
+func TestWorkloadChainsReturnToOrigin(t *testing.T) {
+	procs := startLine(t, 3)
+	cfg := workload.Config{Arrivals: "poisson", Messages: 2, Rate: 60000, ChainProbability: 1, ChainDepth: 3}
+	for _, p := range procs {
+		g, err := workload.New(cfg, p.ID, 3, int64(p.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SetWorkload(g)
+	}
+
+	if err := procs[0].RunWorkload(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		procs[0].mu.Lock()
+		completed := procs[0].ChainsCompleted
+		procs[0].mu.Unlock()
+		if completed == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("P0 completed %d chains, want 2", completed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 2 chuỗi × 4 message, mỗi message được deliver đúng một lần
+	delivered := 0
+	for _, p := range procs {
+		p.chains.Wait()
+		p.mu.Lock()
+		delivered += len(p.DeliveredMsgs)
+		p.mu.Unlock()
+	}
+	if delivered != 8 {
+		t.Fatalf("delivered %d messages in total, want 8", delivered)
+	}
+}
+
+// Chuỗi chỉ đi theo trường Chain đã được xác thực: nội dung giống chuỗi
+// không kéo theo hop nào, và hop dài hơn chain_depth bị bỏ kèm log
+func TestChainHopOnlyFromTypedField(t *testing.T) {
+	var logs bytes.Buffer
+	p := newQuietProcess(t, 0, 3)
+	sink, _ := NewLogHandler(&logs, LogFormatLine, slog.LevelWarn, p.ID)
+	p.Logger = slog.New(sink)
+	g, err := workload.New(workload.Config{Arrivals: "uniform", Rate: 60, ChainDepth: 2}, 0, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.workload = g
+
+	text := message.NewMessage(1, 0, 1, "chain x origin=0 remaining=100000", []int{0, 0, 0}, nil)
+	long := message.NewMessage(2, 0, 1, "long", []int{0, 0, 0}, nil)
+	long.Chain = &message.Hop{Chain: "P2-C1", Origin: 2, Remaining: 100000}
+	for _, msg := range []message.Message{text, long} {
+		if ack := p.receiveMessage(msg); !ack.Accepted {
+			t.Fatalf("%s rejected: %+v", msg.ID, ack)
+		}
+	}
+	p.chains.Wait()
+	if p.SentMsgCount[1]+p.SentMsgCount[2] != 0 {
+		t.Fatalf("sent %v after untrusted chain hops", p.SentMsgCount)
+	}
+	if got := logs.String(); strings.Count(got, "CHAIN") != 1 || !strings.Contains(got, "P2-C1 dropped") {
+		t.Fatalf("logs = %q, want one dropped chain", got)
+	}
+}
@@ -0,0 +1,301 @@
+// Package runlog quản lý thư mục log của từng lần chạy: mỗi lần chạy ghi
+// vào <log-dir>/<tên run> riêng, kèm manifest.json mô tả lần chạy đó (config,
+// seed, git commit, thời gian, exit status của từng process) để có thể lưu
+// trữ và so sánh các lần chạy.
+package runlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ManifestFile là tên file manifest trong thư mục run
+	ManifestFile = "manifest.json"
+	// LatestFile trong <log-dir> chứa tên run mới nhất. Dùng file thường
+	// thay cho symlink để chạy được cả trên Windows.
+	LatestFile = "latest"
+	// NameLayout là dạng thời gian của tên run tự đặt
+	NameLayout = "20060102-150405"
+)
+
+// lockTimeout là thời gian chờ tối đa process khác cập nhật xong manifest
+const lockTimeout = 5 * time.Second
+
+// Manifest mô tả một lần chạy
+type Manifest struct {
+	Name       string          `json:"name"`
+	Command    []string        `json:"command"`
+	ConfigPath string          `json:"config_path"`           // file config lúc chạy
+	ConfigFile string          `json:"config_file,omitempty"` // bản sao trong thư mục run
+	Seed       int64           `json:"seed"`
+	GitCommit  string          `json:"git_commit,omitempty"`
+	Start      time.Time       `json:"start"`
+	End        *time.Time      `json:"end,omitempty"` // nil = đang chạy hoặc bị dừng đột ngột
+	Processes  []ProcessStatus `json:"processes"`
+}
+
+// ProcessStatus là kết quả của một process trong lần chạy
+type ProcessStatus struct {
+	ID       int        `json:"id"`
+	Seed     int64      `json:"seed"` // seed process thực sự dùng
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end,omitempty"`
+	ExitCode *int       `json:"exit_code,omitempty"` // nil = chưa kết thúc
+	Error    string     `json:"error,omitempty"`
+	// FileLogLevel là level của process_N.log (log.file.level), rỗng ở
+	// manifest cũ. verify cần level debug để thấy dòng SENT/DELIVERED.
+	FileLogLevel string `json:"file_log_level,omitempty"`
+}
+
+// Duration là thời gian chạy, 0 nếu chưa kết thúc
+func (m *Manifest) Duration() time.Duration {
+	if m.End == nil {
+		return 0
+	}
+	return m.End.Sub(m.Start)
+}
+
+// Succeeded đếm số process đã kết thúc với exit code 0
+func (m *Manifest) Succeeded() int {
+	n := 0
+	for _, p := range m.Processes {
+		if p.ExitCode != nil && *p.ExitCode == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Process trả về status của process id, thêm mới nếu chưa có
+func (m *Manifest) Process(id int) *ProcessStatus {
+	for i := range m.Processes {
+		if m.Processes[i].ID == id {
+			return &m.Processes[i]
+		}
+	}
+	m.Processes = append(m.Processes, ProcessStatus{ID: id})
+	sort.Slice(m.Processes, func(i, j int) bool { return m.Processes[i].ID < m.Processes[j].ID })
+	return m.Process(id)
+}
+
+// Started ghi nhận process id bắt đầu lúc t với seed
+func (m *Manifest) Started(id int, t time.Time, seed int64) {
+	p := m.Process(id)
+	p.Seed, p.Start, p.End, p.ExitCode, p.Error = seed, t, nil, nil, ""
+}
+
+// Finished ghi nhận process id kết thúc lúc t với exit code code. Khi mọi
+// process đã kết thúc, run cũng kết thúc lúc t.
+func (m *Manifest) Finished(id int, t time.Time, code int, err error) {
+	p := m.Process(id)
+	p.End, p.ExitCode, p.Error = &t, &code, ""
+	if err != nil {
+		p.Error = err.Error()
+	}
+	for _, p := range m.Processes {
+		if p.ExitCode == nil {
+			return
+		}
+	}
+	m.End = &t
+}
+
+// Create tạo thư mục run mới trong base. name rỗng thì lấy theo start
+// (thêm -2, -3... nếu trùng). Manifest ban đầu được ghi ngay, và base/latest
+// trỏ đến run mới.
+func Create(base, name string, start time.Time) (string, error) {
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return "", err
+	}
+	auto := name == ""
+	if auto {
+		name = start.Format(NameLayout)
+	}
+	dir := filepath.Join(base, name)
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			break
+		}
+		if !auto || !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		dir = filepath.Join(base, fmt.Sprintf("%s-%d", name, i))
+	}
+
+	m := Manifest{Name: filepath.Base(dir), Start: start}
+	if err := write(dir, &m); err != nil {
+		return "", err
+	}
+	return dir, os.WriteFile(filepath.Join(base, LatestFile), []byte(m.Name+"\n"), 0644)
+}
+
+// Join trả về thư mục run name trong base, tạo mới nếu chưa có. Dùng khi
+// nhiều process chạy riêng (mỗi terminal một process) ghi chung một run.
+func Join(base, name string, start time.Time) (string, error) {
+	dir := filepath.Join(base, name)
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return dir, nil
+	}
+	dir, err := Create(base, name, start)
+	if errors.Is(err, fs.ErrExist) {
+		// Process khác vừa tạo run này
+		return filepath.Join(base, name), nil
+	}
+	return dir, err
+}
+
+// Load đọc manifest trong thư mục run dir
+func Load(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, ManifestFile), err)
+	}
+	return &m, nil
+}
+
+// Update đọc manifest của dir, gọi change rồi ghi lại. Các process cùng run
+// có thể gọi Update cùng lúc: manifest.lock đảm bảo chỉ một process sửa tại
+// một thời điểm.
+func Update(dir string, change func(m *Manifest)) error {
+	unlock, err := lock(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	m, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	change(m)
+	return write(dir, m)
+}
+
+// lock tạo manifest.lock. Lock cũ hơn lockTimeout được coi là của process
+// đã chết và bị bỏ qua.
+func lock(dir string) (unlock func(), err error) {
+	path := filepath.Join(dir, ManifestFile+".lock")
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockTimeout {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// write ghi manifest qua file tạm rồi rename, để người đọc không bao giờ
+// thấy manifest ghi dở
+func write(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ManifestFile+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ManifestFile))
+}
+
+// CopyConfig chép file config vào thư mục run và trả về tên bản sao
+func CopyConfig(dir, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	name := "config" + filepath.Ext(path)
+	return name, os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// Latest trả về thư mục của run mới nhất trong base, ok = false nếu base
+// chưa có run nào
+func Latest(base string) (dir string, ok bool) {
+	data, err := os.ReadFile(filepath.Join(base, LatestFile))
+	if err != nil {
+		return "", false
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" {
+		return "", false
+	}
+	return filepath.Join(base, name), true
+}
+
+// List trả về manifest của mọi run trong base, cũ nhất trước
+func List(base string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Manifest
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m, err := Load(filepath.Join(base, e.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // không phải thư mục run
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Name = e.Name()
+		runs = append(runs, m)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Start.Before(runs[j].Start) })
+	return runs, nil
+}
+
+// GitCommit trả về commit của mã nguồn: commit được Go ghi vào binary khi
+// build, không có thì hỏi git trong thư mục hiện tại. Rỗng nếu không biết.
+func GitCommit() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if revision != "" {
+			if modified == "true" {
+				revision += "-dirty"
+			}
+			return revision
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
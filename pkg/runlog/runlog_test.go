@@ -0,0 +1,98 @@
+package runlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCreateNamesRunsAfterStart(t *testing.T) {
+	base := t.TempDir()
+	start := time.Date(2024, 1, 31, 15, 4, 5, 0, time.UTC)
+	first, err := Create(base, "", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Create(base, "", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(first) != "20240131-150405" || filepath.Base(second) != "20240131-150405-2" {
+		t.Fatalf("runs = %s, %s", first, second)
+	}
+	if dir, ok := Latest(base); !ok || dir != second {
+		t.Fatalf("latest = %q, %v", dir, ok)
+	}
+	if _, err := Create(base, "20240131-150405", start); err == nil {
+		t.Fatal("Create reused an existing run")
+	}
+	if dir, err := Join(base, "20240131-150405", start); err != nil || dir != first {
+		t.Fatalf("join = %q, %v", dir, err)
+	}
+}
+
+func TestUpdateFromManyProcesses(t *testing.T) {
+	base := t.TempDir()
+	start := time.Now()
+	dir, err := Join(base, "demo", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for id := 0; id < 8; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := Update(dir, func(m *Manifest) { m.Started(id, start, int64(id)) }); err != nil {
+				t.Error(err)
+			}
+			code := 0
+			if id == 3 {
+				code = 1
+			}
+			if err := Update(dir, func(m *Manifest) { m.Finished(id, start.Add(time.Second), code, nil) }); err != nil {
+				t.Error(err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Processes) != 8 || m.Succeeded() != 7 || m.End == nil || m.Duration() != time.Second {
+		t.Fatalf("manifest = %+v", m)
+	}
+	for i, p := range m.Processes {
+		if p.ID != i || p.Seed != int64(i) {
+			t.Fatalf("processes[%d] = %+v", i, p)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile+".lock")); !os.IsNotExist(err) {
+		t.Fatalf("lock left behind: %v", err)
+	}
+}
+
+func TestListSkipsOtherDirectories(t *testing.T) {
+	base := t.TempDir()
+	start := time.Now()
+	for i := 2; i >= 0; i-- {
+		if _, err := Create(base, fmt.Sprintf("run%d", i), start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Join(base, "old"), 0755)
+	os.WriteFile(filepath.Join(base, "process_0.log"), nil, 0644)
+
+	runs, err := List(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[0].Name != "run0" || runs[2].Name != "run2" {
+		t.Fatalf("runs = %+v", runs)
+	}
+}
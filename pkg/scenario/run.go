@@ -0,0 +1,137 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+	"github.com/NationalWind/ses-project/pkg/process"
+)
+
+// Step là kết quả của một câu lệnh
+type Step struct {
+	Statement
+	Result  string // kết quả thực tế, ví dụ "DELIVERED, releases m3"
+	Failure string // khác rỗng nếu kết quả khác kỳ vọng
+}
+
+// Report là kết quả của Run
+type Report struct {
+	Scenario *Scenario
+	Steps    []Step
+	Failures int
+}
+
+// Run chạy kịch bản trên một process.Cluster: message đến receiver đúng theo
+// thứ tự các lệnh "arrives", và kết quả BUFFERED/DELIVERED của mỗi bước được
+// so với kỳ vọng. Log của các process được ghi vào logOut.
+func Run(s *Scenario, logOut io.Writer) (*Report, error) {
+	cluster := process.NewCluster(s.NumProcesses, logOut)
+	report := &Report{Scenario: s}
+	msgs := make(map[string]message.Message)
+	names := make(map[string]string) // message ID → tên trong kịch bản
+
+	for _, st := range s.Statements {
+		step := Step{Statement: st}
+		switch st.Kind {
+		case Send:
+			msg, err := cluster.Send(st.From, st.To, st.Msg)
+			if err != nil {
+				return report, fmt.Errorf("line %d: %w", st.Line, err)
+			}
+			msgs[st.Msg] = msg
+			names[msg.ID] = st.Msg
+			step.Result = fmt.Sprintf("tm=%v, V_M=%s", msg.Timestamp, message.FormatVectorP(msg.VectorP))
+
+		case Arrive:
+			outcome, err := cluster.Arrive(msgs[st.Msg])
+			if err != nil {
+				return report, fmt.Errorf("line %d: %w", st.Line, err)
+			}
+			result, releases := describe(outcome, msgs[st.Msg].ID, names)
+			step.Result = result
+			if st.Expect != "" && !strings.HasPrefix(result, st.Expect) {
+				step.Failure = fmt.Sprintf("expected %s, got %s", st.Expect, result)
+			} else if st.Expect == Delivered && !sameNames(releases, st.Releases, true) {
+				step.Failure = fmt.Sprintf("expected releases %s, got %s", list(st.Releases), list(releases))
+			}
+
+		case ExpectDelivered:
+			got := rename(cluster.Delivered(st.Process), names)
+			step.Result = list(got)
+			if !sameNames(got, st.Messages, true) {
+				step.Failure = fmt.Sprintf("P%d delivered %s, expected %s", st.Process, list(got), list(st.Messages))
+			}
+
+		case ExpectBuffered:
+			got := rename(cluster.Buffered(st.Process), names)
+			step.Result = list(got)
+			if !sameNames(got, st.Messages, false) {
+				step.Failure = fmt.Sprintf("P%d buffers %s, expected %s", st.Process, list(got), list(st.Messages))
+			}
+		}
+
+		if step.Failure != "" {
+			report.Failures++
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report, nil
+}
+
+// describe mô tả outcome theo cú pháp của kịch bản và trả về tên các
+// message khác được deliver nhờ message id
+func describe(outcome process.Outcome, id string, names map[string]string) (string, []string) {
+	switch {
+	case !outcome.Accepted:
+		return Rejected, nil
+	case outcome.Buffered:
+		return fmt.Sprintf("%s (%s)", Buffered, outcome.Reason), nil
+	}
+	var releases []string
+	for _, delivered := range outcome.Delivered {
+		if delivered != id {
+			releases = append(releases, names[delivered])
+		}
+	}
+	if len(releases) == 0 {
+		return Delivered, nil
+	}
+	return Delivered + ", releases " + strings.Join(releases, ", "), releases
+}
+
+func rename(ids []string, names map[string]string) []string {
+	renamed := make([]string, len(ids))
+	for i, id := range ids {
+		renamed[i] = names[id]
+	}
+	return renamed
+}
+
+// sameNames so sánh hai danh sách tên, ordered = false bỏ qua thứ tự
+func sameNames(a, b []string, ordered bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if !ordered {
+		a = append([]string(nil), a...)
+		b = append([]string(nil), b...)
+		sort.Strings(a)
+		sort.Strings(b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func list(names []string) string {
+	if len(names) == 0 {
+		return "nothing"
+	}
+	return strings.Join(names, " ")
+}
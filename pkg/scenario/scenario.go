@@ -0,0 +1,303 @@
+// Package scenario đọc và chạy các kịch bản causal ordering viết tay, ví dụ:
+//
+//	P0 sends m1 to P2
+//	P0 sends m2 to P1
+//	m2 arrives at P1: DELIVERED
+//	P1 sends m3 to P2
+//	m3 arrives at P2: BUFFERED
+//	m1 arrives at P2: DELIVERED, releases m3
+//	expect P2 delivered m1 m3
+//
+// Mỗi dòng (hoặc mỗi đoạn phân cách bằng ";") là một câu lệnh, "#" bắt đầu
+// comment. Trace do "ses explore" in ra cũng là kịch bản hợp lệ.
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Kind là loại câu lệnh
+type Kind int
+
+const (
+	// Send: "Pi sends m to Pj"
+	Send Kind = iota
+	// Arrive: "m arrives [at Pj][: DELIVERED[, releases m...] | BUFFERED | REJECTED]"
+	Arrive
+	// ExpectDelivered: "expect Pi delivered m..." (đúng thứ tự, "nothing" = rỗng)
+	ExpectDelivered
+	// ExpectBuffered: "expect Pi buffered m..." (không tính thứ tự)
+	ExpectBuffered
+)
+
+// Các kết quả của một Arrive
+const (
+	Delivered = "DELIVERED"
+	Buffered  = "BUFFERED"
+	Rejected  = "REJECTED"
+)
+
+// Statement là một câu lệnh đã parse
+type Statement struct {
+	Line int
+	Text string
+	Kind Kind
+
+	Msg      string // Send, Arrive: tên message
+	From, To int    // Send: sender và receiver; Arrive: To là receiver
+
+	Expect   string   // Arrive: "" = không kiểm tra, Delivered, Buffered hoặc Rejected
+	Releases []string // Arrive: message được deliver khỏi buffer nhờ message này
+
+	Process  int      // Expect*: process được kiểm tra
+	Messages []string // Expect*: các message
+}
+
+// Scenario là một kịch bản đã parse và kiểm tra
+type Scenario struct {
+	Name         string
+	NumProcesses int
+	Statements   []Statement
+}
+
+// ParseFile đọc kịch bản từ file, Name là tên file
+func ParseFile(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Name = filepath.Base(path)
+	return s, nil
+}
+
+// Parse đọc kịch bản và kiểm tra: message chỉ đến sau khi được gửi, tên
+// message không trùng, receiver khớp với lệnh gửi
+func Parse(src string) (*Scenario, error) {
+	s := &Scenario{}
+	declared := 0
+	sent := make(map[string]Statement)
+
+	for i, line := range strings.Split(src, "\n") {
+		if c := strings.Index(line, "#"); c >= 0 {
+			line = line[:c]
+		}
+		for _, text := range strings.Split(line, ";") {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			fields := strings.Fields(text)
+			if fields[0] == "processes" {
+				n, err := strconv.Atoi(strings.Join(fields[1:], ""))
+				if err != nil || n < 2 {
+					return nil, fmt.Errorf("line %d: invalid %q (want processes N, N >= 2)", i+1, text)
+				}
+				declared = n
+				continue
+			}
+
+			st, err := parseStatement(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			st.Line = i + 1
+			if err := check(&st, sent); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			s.Statements = append(s.Statements, st)
+			s.NumProcesses = max(s.NumProcesses, st.From+1, st.To+1, st.Process+1)
+		}
+	}
+
+	if len(s.Statements) == 0 {
+		return nil, fmt.Errorf("empty scenario")
+	}
+	if declared > 0 {
+		if declared < s.NumProcesses {
+			return nil, fmt.Errorf("scenario uses P%d but declares %d processes", s.NumProcesses-1, declared)
+		}
+		s.NumProcesses = declared
+	}
+	s.NumProcesses = max(s.NumProcesses, 2)
+	return s, nil
+}
+
+// check kiểm tra st với các message đã gửi trước đó và điền receiver cho
+// Arrive không ghi "at Pj"
+func check(st *Statement, sent map[string]Statement) error {
+	switch st.Kind {
+	case Send:
+		if _, ok := sent[st.Msg]; ok {
+			return fmt.Errorf("message %s is sent twice", st.Msg)
+		}
+		sent[st.Msg] = *st
+
+	case Arrive:
+		snd, ok := sent[st.Msg]
+		if !ok {
+			return fmt.Errorf("message %s arrives before it is sent", st.Msg)
+		}
+		if st.To >= 0 && st.To != snd.To {
+			return fmt.Errorf("message %s is sent to P%d, not P%d", st.Msg, snd.To, st.To)
+		}
+		st.To = snd.To
+		for _, name := range st.Releases {
+			if _, ok := sent[name]; !ok {
+				return fmt.Errorf("message %s is released before it is sent", name)
+			}
+		}
+
+	default:
+		for _, name := range st.Messages {
+			if _, ok := sent[name]; !ok {
+				return fmt.Errorf("unknown message %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+func parseStatement(text string) (Statement, error) {
+	st := Statement{Text: text, From: -1, To: -1, Process: -1}
+
+	// Bỏ số thứ tự ("3.") và phần giải thích trong ngoặc mà trace của
+	// explorer có, để dán trace vào kịch bản được
+	head, tail, hasExpect := strings.Cut(text, ":")
+	fields := strings.Fields(cutParen(head))
+	if len(fields) > 0 && strings.HasSuffix(fields[0], ".") {
+		if _, err := strconv.Atoi(strings.TrimSuffix(fields[0], ".")); err == nil {
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return st, fmt.Errorf("empty statement %q", text)
+	}
+
+	var err error
+	switch {
+	case len(fields) == 5 && fields[1] == "sends" && fields[3] == "to":
+		st.Kind = Send
+		st.Msg = fields[2]
+		if st.From, err = parseProcess(fields[0]); err != nil {
+			return st, err
+		}
+		if st.To, err = parseProcess(fields[4]); err != nil {
+			return st, err
+		}
+		if st.From == st.To {
+			return st, fmt.Errorf("P%d cannot send to itself", st.From)
+		}
+		if hasExpect {
+			return st, fmt.Errorf("unexpected %q after send", tail)
+		}
+
+	case len(fields) >= 2 && fields[1] == "arrives":
+		st.Kind = Arrive
+		st.Msg = fields[0]
+		switch {
+		case len(fields) == 4 && fields[2] == "at":
+			if st.To, err = parseProcess(fields[3]); err != nil {
+				return st, err
+			}
+		case len(fields) != 2:
+			return st, fmt.Errorf("invalid arrival %q (want m arrives [at Pj])", head)
+		}
+		if hasExpect {
+			if err := parseExpect(&st, tail); err != nil {
+				return st, err
+			}
+		}
+
+	case len(fields) >= 3 && fields[0] == "expect":
+		switch fields[2] {
+		case "delivered":
+			st.Kind = ExpectDelivered
+		case "buffered":
+			st.Kind = ExpectBuffered
+		default:
+			return st, fmt.Errorf("invalid expectation %q (want delivered or buffered)", fields[2])
+		}
+		if st.Process, err = parseProcess(fields[1]); err != nil {
+			return st, err
+		}
+		st.Messages = parseNames(strings.Join(fields[3:], " "))
+		if len(st.Messages) == 1 && st.Messages[0] == "nothing" {
+			st.Messages = nil
+		}
+
+	default:
+		return st, fmt.Errorf("unknown statement %q", text)
+	}
+
+	for _, name := range append(append([]string{st.Msg}, st.Releases...), st.Messages...) {
+		if name != "" && !validName(name) {
+			return st, fmt.Errorf("invalid message name %q", name)
+		}
+	}
+	return st, nil
+}
+
+// parseExpect đọc phần sau ":" của Arrive
+func parseExpect(st *Statement, tail string) error {
+	outcome, releases, _ := strings.Cut(cutParen(tail), ",")
+	st.Expect = strings.ToUpper(strings.TrimSpace(outcome))
+	switch st.Expect {
+	case Delivered:
+	case Buffered, Rejected:
+		if strings.TrimSpace(releases) != "" {
+			return fmt.Errorf("only a DELIVERED message can release others")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid outcome %q (want DELIVERED, BUFFERED or REJECTED)", outcome)
+	}
+
+	names := parseNames(releases)
+	if len(names) == 0 {
+		return nil
+	}
+	if names[0] != "releases" || len(names) == 1 {
+		return fmt.Errorf("invalid %q (want DELIVERED, releases m...)", strings.TrimSpace(tail))
+	}
+	st.Releases = names[1:]
+	return nil
+}
+
+// cutParen bỏ mọi thứ từ dấu "(" đầu tiên
+func cutParen(s string) string {
+	before, _, _ := strings.Cut(s, "(")
+	return before
+}
+
+// parseNames tách danh sách tên phân cách bằng space hoặc dấu phẩy
+func parseNames(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+}
+
+func validName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case i > 0 && (r >= '0' && r <= '9' || r == '-'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+func parseProcess(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(s, "P"))
+	if err != nil || id < 0 || !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid process %q", s)
+	}
+	return id, nil
+}
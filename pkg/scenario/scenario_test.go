@@ -0,0 +1,97 @@
+package scenario
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExampleScenarios(t *testing.T) {
+	paths, err := filepath.Glob("../../scenarios/*.ses")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no scenarios found: %v", err)
+	}
+	for _, path := range paths {
+		s, err := ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := Run(s, io.Discard)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for _, step := range report.Steps {
+			if step.Failure != "" {
+				t.Errorf("%s:%d %s: %s", s.Name, step.Line, step.Text, step.Failure)
+			}
+		}
+	}
+}
+
+func TestFailedExpectations(t *testing.T) {
+	s, err := Parse(`
+		P0 sends m1 to P2; P0 sends m2 to P1
+		m2 arrives: DELIVERED; P1 sends m3 to P2
+		m3 arrives: DELIVERED          # sai: m3 phải chờ m1
+		m1 arrives: DELIVERED          # sai: không release m3
+		expect P2 delivered m3 m1      # sai thứ tự
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Run(s, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failures != 3 {
+		t.Fatalf("got %d failures, want 3: %+v", report.Failures, report.Steps)
+	}
+	if got := report.Steps[4].Failure; !strings.HasPrefix(got, "expected DELIVERED, got BUFFERED") {
+		t.Errorf("m3 failure = %q", got)
+	}
+	if got := report.Steps[5].Failure; got != "expected releases nothing, got m3" {
+		t.Errorf("m1 failure = %q", got)
+	}
+}
+
+func TestExplorerTraceIsAScenario(t *testing.T) {
+	s, err := Parse(`
+		 1. P0 sends m1 to P2 (tm=[0 0 0], V_M=[])
+		 2. P0 sends m2 to P1 (tm=[1 0 0], V_M=[(P2,[1 0 0])])
+		 3. m2 arrives at P1: DELIVERED
+		 4. P1 sends m3 to P2 (tm=[2 1 0], V_M=[(P2,[1 0 0])])
+		 5. m3 arrives at P2: BUFFERED (waiting for P0)
+		 6. m1 arrives at P2: DELIVERED, releases m3
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.NumProcesses != 3 || len(s.Statements) != 6 || s.Statements[4].Expect != Buffered {
+		t.Fatalf("parsed %+v", s)
+	}
+	report, err := Run(s, io.Discard)
+	if err != nil || report.Failures != 0 {
+		t.Fatalf("Run() = %+v, %v", report, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"":                                                     "empty scenario",
+		"m1 arrives":                                           "arrives before it is sent",
+		"P0 sends m1 to P1; P0 sends m1 to P2":                 "sent twice",
+		"P0 sends m1 to P1; m1 arrives at P2":                  "sent to P1, not P2",
+		"P0 sends m1 to P0":                                    "cannot send to itself",
+		"P0 sends m1 to P1; m1 arrives: LOST":                  "invalid outcome",
+		"P0 sends m1 to P1; expect P1 seen m1":                 "invalid expectation",
+		"P0 sends m1 to P1; expect P1 buffered m2":             "unknown message m2",
+		"processes 2; P0 sends m1 to P2":                       "declares 2 processes",
+		"P0 sends m1 to P1; m1 arrives: BUFFERED, releases m1": "only a DELIVERED",
+		"P0 pings P1":                                          "unknown statement",
+	} {
+		if _, err := Parse(src); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) = %v, want error containing %q", src, err, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package topology mô tả process nào kết nối trực tiếp được với process nào
+// và chọn hop kế tiếp khi gửi message đến process không kề.
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Config là phần "topology" trong config.json
+type Config struct {
+	Type   string   `json:"type"`             // full (mặc định) | ring | star | tree | graph
+	Center int      `json:"center,omitempty"` // star: process ở giữa
+	Fanout int      `json:"fanout,omitempty"` // tree: số con mỗi node (mặc định 2)
+	Edges  [][2]int `json:"edges,omitempty"`  // graph: các cạnh vô hướng
+}
+
+// Topology là đồ thị vô hướng, liên thông giữa numProcesses process
+type Topology struct {
+	Name      string
+	neighbors [][]int // đã sort tăng dần
+	next      [][]int // next[from][to] = hop kế tiếp từ from đến to
+}
+
+// New tạo topology theo config
+func New(cfg Config, numProcesses int) (*Topology, error) {
+	switch cfg.Type {
+	case "", "full":
+		return FullMesh(numProcesses), nil
+	case "ring":
+		return Ring(numProcesses)
+	case "star":
+		return Star(numProcesses, cfg.Center)
+	case "tree":
+		fanout := cfg.Fanout
+		if fanout == 0 {
+			fanout = 2
+		}
+		return Tree(numProcesses, fanout)
+	case "graph":
+		return Graph(numProcesses, cfg.Edges)
+	}
+	return nil, fmt.Errorf("unknown topology %q (want full, ring, star, tree or graph)", cfg.Type)
+}
+
+// FullMesh: mọi process kết nối trực tiếp với nhau
+func FullMesh(n int) *Topology {
+	var edges [][2]int
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			edges = append(edges, [2]int{a, b})
+		}
+	}
+	t, _ := build("full", n, edges) // luôn liên thông
+	return t
+}
+
+// Ring: P_i kề P_(i±1 mod n)
+func Ring(n int) (*Topology, error) {
+	var edges [][2]int
+	for i := 0; i < n && n > 1; i++ {
+		edges = append(edges, [2]int{i, (i + 1) % n})
+	}
+	return build("ring", n, edges)
+}
+
+// Star: mọi process chỉ kề center
+func Star(n int, center int) (*Topology, error) {
+	if center < 0 || center >= n {
+		return nil, fmt.Errorf("star center P%d out of range", center)
+	}
+	var edges [][2]int
+	for i := 0; i < n; i++ {
+		if i != center {
+			edges = append(edges, [2]int{center, i})
+		}
+	}
+	return build(fmt.Sprintf("star(center=P%d)", center), n, edges)
+}
+
+// Tree: cây fanout-phân, gốc P0, cha của P_i là P_((i-1)/fanout)
+func Tree(n int, fanout int) (*Topology, error) {
+	if fanout < 1 {
+		return nil, fmt.Errorf("tree fanout must be at least 1, got %d", fanout)
+	}
+	var edges [][2]int
+	for i := 1; i < n; i++ {
+		edges = append(edges, [2]int{(i - 1) / fanout, i})
+	}
+	return build(fmt.Sprintf("tree(fanout=%d)", fanout), n, edges)
+}
+
+// Graph: đồ thị tùy ý, phải liên thông
+func Graph(n int, edges [][2]int) (*Topology, error) {
+	return build("graph", n, edges)
+}
+
+func build(name string, n int, edges [][2]int) (*Topology, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("topology needs at least 1 process, got %d", n)
+	}
+	adjacent := make([]map[int]bool, n)
+	for i := range adjacent {
+		adjacent[i] = make(map[int]bool)
+	}
+	for _, e := range edges {
+		a, b := e[0], e[1]
+		if a < 0 || a >= n || b < 0 || b >= n {
+			return nil, fmt.Errorf("edge P%d-P%d out of range (0..%d)", a, b, n-1)
+		}
+		if a == b {
+			return nil, fmt.Errorf("edge P%d-P%d is a self loop", a, b)
+		}
+		adjacent[a][b] = true
+		adjacent[b][a] = true
+	}
+
+	t := &Topology{Name: name, neighbors: make([][]int, n), next: make([][]int, n)}
+	for i, set := range adjacent {
+		for j := range set {
+			t.neighbors[i] = append(t.neighbors[i], j)
+		}
+		sort.Ints(t.neighbors[i])
+		t.next[i] = make([]int, n)
+	}
+
+	// BFS từ mỗi đích: cha của from trong cây BFS là hop kế tiếp đến đích.
+	// Duyệt neighbor theo thứ tự tăng dần để route luôn giống nhau ở mọi process.
+	for to := 0; to < n; to++ {
+		visited := make([]bool, n)
+		visited[to] = true
+		t.next[to][to] = to
+		queue := []int{to}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, nb := range t.neighbors[cur] {
+				if !visited[nb] {
+					visited[nb] = true
+					t.next[nb][to] = cur
+					queue = append(queue, nb)
+				}
+			}
+		}
+		for i, ok := range visited {
+			if !ok {
+				return nil, fmt.Errorf("%s topology is not connected: P%d cannot reach P%d", name, i, to)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Size là số process
+func (t *Topology) Size() int {
+	return len(t.neighbors)
+}
+
+// Neighbors trả về các process kề id
+func (t *Topology) Neighbors(id int) []int {
+	return append([]int(nil), t.neighbors[id]...)
+}
+
+// Adjacent kiểm tra a và b có kết nối trực tiếp không
+func (t *Topology) Adjacent(a, b int) bool {
+	i := sort.SearchInts(t.neighbors[a], b)
+	return i < len(t.neighbors[a]) && t.neighbors[a][i] == b
+}
+
+// NextHop là process from phải gửi đến để message đi tới to theo đường ngắn nhất
+func (t *Topology) NextHop(from, to int) int {
+	return t.next[from][to]
+}
+
+// Path là đường đi from → to, gồm cả hai đầu
+func (t *Topology) Path(from, to int) []int {
+	path := []int{from}
+	for cur := from; cur != to; {
+		cur = t.next[cur][to]
+		path = append(path, cur)
+	}
+	return path
+}
+
+// OnPath kiểm tra hop a → b có nằm trên đường from → to không
+func (t *Topology) OnPath(from, to, a, b int) bool {
+	path := t.Path(from, to)
+	for i := 0; i+1 < len(path); i++ {
+		if path[i] == a && path[i+1] == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Diameter là số hop lớn nhất giữa hai process
+func (t *Topology) Diameter() int {
+	d := 0
+	for from := range t.next {
+		for to := range t.next {
+			if hops := len(t.Path(from, to)) - 1; hops > d {
+				d = hops
+			}
+		}
+	}
+	return d
+}
+
+func (t *Topology) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, diameter %d:", t.Name, t.Diameter())
+	for i, nbs := range t.neighbors {
+		fmt.Fprintf(&b, " P%d-%v", i, nbs)
+	}
+	return b.String()
+}
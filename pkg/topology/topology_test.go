@@ -0,0 +1,87 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNextHop(t *testing.T) {
+	ring, err := Ring(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	star, err := Star(5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := Tree(7, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		t        *Topology
+		from, to int
+		path     []int
+	}{
+		{"ring short way", ring, 0, 2, []int{0, 1, 2}},
+		{"ring wraps", ring, 0, 4, []int{0, 5, 4}},
+		{"star via center", star, 0, 4, []int{0, 2, 4}},
+		{"star to center", star, 3, 2, []int{3, 2}},
+		{"tree leaf to leaf", tree, 3, 6, []int{3, 1, 0, 2, 6}},
+		{"self", tree, 4, 4, []int{4}},
+		{"full mesh", FullMesh(4), 0, 3, []int{0, 3}},
+	}
+	for _, c := range cases {
+		if got := c.t.Path(c.from, c.to); !reflect.DeepEqual(got, c.path) {
+			t.Errorf("%s: Path(%d, %d) = %v, want %v", c.name, c.from, c.to, got, c.path)
+		}
+		if len(c.path) > 1 && c.t.NextHop(c.from, c.to) != c.path[1] {
+			t.Errorf("%s: NextHop(%d, %d) = %d", c.name, c.from, c.to, c.t.NextHop(c.from, c.to))
+		}
+	}
+	if d := tree.Diameter(); d != 4 {
+		t.Errorf("tree diameter = %d, want 4", d)
+	}
+}
+
+// Route chỉ đi theo các cạnh của đồ thị và OnPath nhận ra mọi hop trên route
+func TestRoutesUseOnlyEdges(t *testing.T) {
+	g, err := Graph(6, [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}, {1, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for from := 0; from < g.Size(); from++ {
+		for to := 0; to < g.Size(); to++ {
+			path := g.Path(from, to)
+			for i := 0; i+1 < len(path); i++ {
+				if !g.Adjacent(path[i], path[i+1]) {
+					t.Fatalf("path %v uses non-edge P%d-P%d", path, path[i], path[i+1])
+				}
+				if !g.OnPath(from, to, path[i], path[i+1]) {
+					t.Fatalf("OnPath(%d, %d, %d, %d) = false", from, to, path[i], path[i+1])
+				}
+			}
+		}
+	}
+	if g.OnPath(0, 5, 2, 3) {
+		t.Fatal("0→5 should take the 1-4 shortcut")
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	cases := map[string]Config{
+		"disconnected": {Type: "graph", Edges: [][2]int{{0, 1}, {2, 3}}},
+		"out of range": {Type: "graph", Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 9}}},
+		"self loop":    {Type: "graph", Edges: [][2]int{{0, 0}}},
+		"bad center":   {Type: "star", Center: 4},
+		"bad fanout":   {Type: "tree", Fanout: -1},
+		"unknown":      {Type: "hypercube"},
+	}
+	for name, cfg := range cases {
+		if _, err := New(cfg, 4); err == nil {
+			t.Errorf("%s: New succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+// Package tracing ghi span theo định dạng OTLP/JSON, không cần collector:
+// mỗi dòng của file là một ExportTraceServiceRequest hoàn chỉnh (giống
+// file exporter của OpenTelemetry Collector), nên có thể nối file của nhiều
+// process lại, đọc bằng otlpjsonfile receiver hoặc mở trong trace viewer
+// hỗ trợ OTLP.
+package tracing
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScopeName là instrumentation scope của mọi span do package này ghi
+const ScopeName = "github.com/NationalWind/ses-project/pkg/tracing"
+
+// DefaultBatchSize là số span gom lại trước khi ghi một dòng
+const DefaultBatchSize = 128
+
+// TraceID là ID 16 byte của một trace
+type TraceID [16]byte
+
+// SpanID là ID 8 byte của một span
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsZero cho biết id chưa được đặt (span gốc không có parent)
+func (id SpanID) IsZero() bool { return id == SpanID{} }
+
+// NewTraceID tạo trace ID cố định từ parts: các process tính ra cùng một ID
+// cho cùng một message mà không cần gửi ID kèm message
+func NewTraceID(parts ...string) TraceID {
+	var id TraceID
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	copy(id[:], sum[:])
+	return id
+}
+
+// NewSpanID tạo span ID cố định từ parts, như NewTraceID
+func NewSpanID(parts ...string) SpanID {
+	var id SpanID
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	copy(id[:], sum[:])
+	return id
+}
+
+// SpanKind theo OTLP
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+	KindProducer SpanKind = 4
+	KindConsumer SpanKind = 5
+)
+
+// StatusCode theo OTLP
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute là một cặp key/value. Value là string, int, int64, bool hoặc
+// float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String, Int và Bool tạo Attribute
+func String(key, value string) Attribute    { return Attribute{key, value} }
+func Int(key string, value int) Attribute   { return Attribute{key, int64(value)} }
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// Link trỏ đến span ở trace khác (hoặc cùng trace) có quan hệ nhân quả
+type Link struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Attributes []Attribute
+}
+
+// Span là một khoảng thời gian trong trace
+type Span struct {
+	TraceID       TraceID
+	SpanID        SpanID
+	Parent        SpanID // zero = span gốc
+	Name          string
+	Kind          SpanKind
+	Start, End    time.Time
+	Attributes    []Attribute
+	Links         []Link
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Attr trả về value của attribute key, nil nếu không có
+func (s Span) Attr(key string) interface{} {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+// Exporter gom span rồi ghi từng batch thành một dòng OTLP/JSON vào w.
+// Dùng được từ nhiều goroutine.
+type Exporter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	closer    io.Closer // nil nếu w không do Exporter mở
+	resource  []otlpKeyValue
+	pending   []otlpSpan
+	batchSize int
+}
+
+// NewExporter ghi span vào w, với resource service.name = service và attrs
+func NewExporter(w io.Writer, service string, attrs ...Attribute) *Exporter {
+	resource := []otlpKeyValue{toKeyValue(String("service.name", service))}
+	for _, a := range attrs {
+		resource = append(resource, toKeyValue(a))
+	}
+	return &Exporter{w: w, resource: resource, batchSize: DefaultBatchSize}
+}
+
+// Create tạo file path (ghi đè nếu có) và trả về Exporter ghi vào đó
+func Create(path, service string, attrs ...Attribute) (*Exporter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	e := NewExporter(f, service, attrs...)
+	e.closer = f
+	return e, nil
+}
+
+// Export thêm s vào batch, ghi batch khi đủ DefaultBatchSize span
+func (e *Exporter) Export(s Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, toOTLP(s))
+	if len(e.pending) >= e.batchSize {
+		return e.flush()
+	}
+	return nil
+}
+
+// Flush ghi các span còn trong batch
+func (e *Exporter) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.flush()
+}
+
+// Close ghi batch cuối và đóng file (nếu Exporter mở file)
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.flush()
+	if e.closer != nil {
+		if cerr := e.closer.Close(); err == nil {
+			err = cerr
+		}
+		e.closer = nil
+	}
+	return err
+}
+
+func (e *Exporter) flush() error {
+	if len(e.pending) == 0 {
+		return nil
+	}
+	req := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: e.resource},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: ScopeName}, Spans: e.pending}},
+	}}}
+	e.pending = nil
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+// Read đọc mọi span trong r (các dòng OTLP/JSON do Exporter ghi, hoặc một
+// object OTLP/JSON duy nhất). Attribute service.name của resource được thêm
+// vào từng span.
+func Read(r io.Reader) ([]Span, error) {
+	var spans []Span
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var req otlpRequest
+		err := dec.Decode(&req)
+		if err == io.EOF {
+			return spans, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, rs := range req.ResourceSpans {
+			var service []Attribute
+			for _, kv := range rs.Resource.Attributes {
+				if kv.Key == "service.name" {
+					service = append(service, fromKeyValue(kv))
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				for _, o := range ss.Spans {
+					s, err := fromOTLP(o)
+					if err != nil {
+						return nil, err
+					}
+					s.Attributes = append(s.Attributes, service...)
+					spans = append(spans, s)
+				}
+			}
+		}
+	}
+}
+
+// Merge gộp các file OTLP/JSON thành một object duy nhất ghi vào w, cho
+// trace viewer chỉ mở được một file một lần
+func Merge(w io.Writer, paths ...string) error {
+	var merged otlpRequest
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bufio.NewReader(f))
+		for {
+			var req otlpRequest
+			err := dec.Decode(&req)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			merged.ResourceSpans = append(merged.ResourceSpans, req.ResourceSpans...)
+		}
+		f.Close()
+	}
+	if merged.ResourceSpans == nil {
+		merged.ResourceSpans = []otlpResourceSpans{}
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Các struct dưới đây là ExportTraceServiceRequest theo OTLP/JSON: ID viết
+// hex, số nguyên 64 bit viết thành string
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func toOTLP(s Span) otlpSpan {
+	o := otlpSpan{
+		TraceID:           s.TraceID.String(),
+		SpanID:            s.SpanID.String(),
+		Name:              s.Name,
+		Kind:              s.Kind,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		Status:            otlpStatus{Code: s.Status, Message: s.StatusMessage},
+	}
+	if !s.Parent.IsZero() {
+		o.ParentSpanID = s.Parent.String()
+	}
+	for _, a := range s.Attributes {
+		o.Attributes = append(o.Attributes, toKeyValue(a))
+	}
+	for _, l := range s.Links {
+		ol := otlpLink{TraceID: l.TraceID.String(), SpanID: l.SpanID.String()}
+		for _, a := range l.Attributes {
+			ol.Attributes = append(ol.Attributes, toKeyValue(a))
+		}
+		o.Links = append(o.Links, ol)
+	}
+	return o
+}
+
+func toKeyValue(a Attribute) otlpKeyValue {
+	kv := otlpKeyValue{Key: a.Key}
+	switch v := a.Value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		kv.Value.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &s
+	case bool:
+		kv.Value.BoolValue = &v
+	case float64:
+		kv.Value.DoubleValue = &v
+	default:
+		s := fmt.Sprint(v)
+		kv.Value.StringValue = &s
+	}
+	return kv
+}
+
+func fromKeyValue(kv otlpKeyValue) Attribute {
+	a := Attribute{Key: kv.Key}
+	switch v := kv.Value; {
+	case v.StringValue != nil:
+		a.Value = *v.StringValue
+	case v.IntValue != nil:
+		n, _ := strconv.ParseInt(*v.IntValue, 10, 64)
+		a.Value = n
+	case v.BoolValue != nil:
+		a.Value = *v.BoolValue
+	case v.DoubleValue != nil:
+		a.Value = *v.DoubleValue
+	}
+	return a
+}
+
+func fromOTLP(o otlpSpan) (Span, error) {
+	s := Span{Name: o.Name, Kind: o.Kind, Status: o.Status.Code, StatusMessage: o.Status.Message}
+	if err := decodeID(s.TraceID[:], o.TraceID); err != nil {
+		return Span{}, fmt.Errorf("span %q: traceId: %w", o.Name, err)
+	}
+	if err := decodeID(s.SpanID[:], o.SpanID); err != nil {
+		return Span{}, fmt.Errorf("span %q: spanId: %w", o.Name, err)
+	}
+	if o.ParentSpanID != "" {
+		if err := decodeID(s.Parent[:], o.ParentSpanID); err != nil {
+			return Span{}, fmt.Errorf("span %q: parentSpanId: %w", o.Name, err)
+		}
+	}
+	start, err := strconv.ParseInt(o.StartTimeUnixNano, 10, 64)
+	if err != nil {
+		return Span{}, fmt.Errorf("span %q: startTimeUnixNano: %w", o.Name, err)
+	}
+	end, err := strconv.ParseInt(o.EndTimeUnixNano, 10, 64)
+	if err != nil {
+		return Span{}, fmt.Errorf("span %q: endTimeUnixNano: %w", o.Name, err)
+	}
+	s.Start, s.End = time.Unix(0, start), time.Unix(0, end)
+	for _, kv := range o.Attributes {
+		s.Attributes = append(s.Attributes, fromKeyValue(kv))
+	}
+	for _, ol := range o.Links {
+		var l Link
+		if err := decodeID(l.TraceID[:], ol.TraceID); err != nil {
+			return Span{}, fmt.Errorf("span %q: link traceId: %w", o.Name, err)
+		}
+		if err := decodeID(l.SpanID[:], ol.SpanID); err != nil {
+			return Span{}, fmt.Errorf("span %q: link spanId: %w", o.Name, err)
+		}
+		for _, kv := range ol.Attributes {
+			l.Attributes = append(l.Attributes, fromKeyValue(kv))
+		}
+		s.Links = append(s.Links, l)
+	}
+	return s, nil
+}
+
+func decodeID(dst []byte, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("%q is %d bytes, want %d", s, len(b), len(dst))
+	}
+	copy(dst, b)
+	return nil
+}
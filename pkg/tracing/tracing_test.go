@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExportReadRoundTrip(t *testing.T) {
+	start := time.Unix(1700000000, 123456789)
+	span := Span{
+		TraceID:    NewTraceID("P0-P1-M1"),
+		SpanID:     NewSpanID("P0-P1-M1", "deliver"),
+		Parent:     NewSpanID("P0-P1-M1", "send"),
+		Name:       "deliver P0",
+		Kind:       KindConsumer,
+		Start:      start,
+		End:        start.Add(3 * time.Millisecond),
+		Attributes: []Attribute{String("ses.tm", "[0 0]"), Int("ses.sender", 0), Bool("ses.buffered", true)},
+		Links:      []Link{{TraceID: NewTraceID("P0-P1-M1"), SpanID: NewSpanID("P0-P1-M1", "send")}},
+		Status:     StatusError,
+	}
+
+	var out bytes.Buffer
+	e := NewExporter(&out, "ses-P1")
+	e.batchSize = 2
+	for i := 0; i < 3; i++ {
+		if err := e.Export(span); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Batch 2 span rồi batch cuối lúc Close
+	if lines := bytes.Count(out.Bytes(), []byte("\n")); lines != 2 {
+		t.Fatalf("wrote %d lines:\n%s", lines, out.String())
+	}
+
+	// OTLP/JSON: ID hex, thời gian là string
+	var raw map[string]interface{}
+	first, _, _ := bytes.Cut(out.Bytes(), []byte("\n"))
+	if err := json.Unmarshal(first, &raw); err != nil {
+		t.Fatal(err)
+	}
+	s := raw["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	if s["traceId"] != span.TraceID.String() || s["startTimeUnixNano"] != "1700000000123456789" || s["kind"] != float64(5) {
+		t.Fatalf("span JSON = %v", s)
+	}
+
+	spans, err := Read(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := span
+	want.Attributes = []Attribute{String("ses.tm", "[0 0]"), Int("ses.sender", 0), Bool("ses.buffered", true), String("service.name", "ses-P1")}
+	if len(spans) != 3 || !reflect.DeepEqual(normalize(spans[2]), normalize(want)) {
+		t.Fatalf("read %d spans, last = %+v", len(spans), spans[len(spans)-1])
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, service := range []string{"ses-P0", "ses-P1"} {
+		path := filepath.Join(dir, service+".jsonl")
+		e, err := Create(path, service)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Export(Span{TraceID: NewTraceID(service), SpanID: NewSpanID(service), Name: "send"})
+		e.Close()
+		paths = append(paths, path)
+	}
+
+	var out bytes.Buffer
+	if err := Merge(&out, paths...); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(out.Bytes(), []byte("\n")) != 1 {
+		t.Fatalf("merged output is not one object:\n%s", out.String())
+	}
+	spans, err := Read(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 2 || spans[0].Attr("service.name") != "ses-P0" || spans[1].Attr("service.name") != "ses-P1" {
+		t.Fatalf("spans = %+v", spans)
+	}
+}
+
+// normalize bỏ monotonic clock và location để so sánh thời gian bằng DeepEqual
+func normalize(s Span) Span {
+	s.Start, s.End = time.Unix(0, s.Start.UnixNano()), time.Unix(0, s.End.UnixNano())
+	return s
+}
@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CertValidity là thời hạn của certificate sinh cho cluster test
+const CertValidity = 365 * 24 * time.Hour
+
+// CertFiles là đường dẫn certificate/key của một process trong dir
+func CertFiles(dir string, processID int) (certFile, keyFile string) {
+	return filepath.Join(dir, fmt.Sprintf("p%d.pem", processID)),
+		filepath.Join(dir, fmt.Sprintf("p%d-key.pem", processID))
+}
+
+// CAFile là đường dẫn certificate của CA trong dir
+func CAFile(dir string) string {
+	return filepath.Join(dir, "ca.pem")
+}
+
+// GenerateCerts tạo CA local và certificate cho process 0..numProcesses-1
+// trong dir. Chỉ dùng cho cluster test: key không được mã hóa.
+func GenerateCerts(dir string, numProcesses int) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "ses-project local CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(CertValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(CAFile(dir), "CERTIFICATE", caDER, 0644); err != nil {
+		return err
+	}
+	if err := writeKey(filepath.Join(dir, "ca-key.pem"), caKey); err != nil {
+		return err
+	}
+
+	for id := 0; id < numProcesses; id++ {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		template := &x509.Certificate{
+			SerialNumber: randomSerial(),
+			Subject:      pkix.Name{CommonName: Identity(id)},
+			DNSNames:     []string{Identity(id), "localhost"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(CertValidity),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			return err
+		}
+		certFile, keyFile := CertFiles(dir, id)
+		if err := writePEM(certFile, "CERTIFICATE", der, 0644); err != nil {
+			return err
+		}
+		if err := writeKey(keyFile, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		panic(err)
+	}
+	return serial
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(path, "EC PRIVATE KEY", der, 0600)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Identity là tên của process trong certificate (CommonName và DNS SAN)
+func Identity(processID int) string {
+	return fmt.Sprintf("ses-p%d", processID)
+}
+
+// parseIdentity chuyển "ses-p<N>" thành N
+func parseIdentity(name string) (int, bool) {
+	var id int
+	if _, err := fmt.Sscanf(name, "ses-p%d", &id); err != nil || id < 0 || Identity(id) != name {
+		return 0, false
+	}
+	return id, true
+}
+
+// TLS là transport mutual TLS: cả hai phía phải có certificate do cùng CA
+// ký. Certificate của process N mang identity "ses-p<N>", nên khi dial peer
+// N, client kiểm tra server đúng là N; server lấy identity của client để so
+// với SenderID trong message (xem PeerID).
+type TLS struct {
+	Base Transport // transport bên dưới, nil = TCP{}
+	cert tls.Certificate
+	pool *x509.CertPool
+}
+
+func (t *TLS) base() Transport {
+	if t.Base == nil {
+		return TCP{}
+	}
+	return t.Base
+}
+
+// NewTLS đọc CA và certificate/key của process này
+func NewTLS(caFile, certFile, keyFile string) (*TLS, error) {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	return &TLS{cert: cert, pool: pool}, nil
+}
+
+func (t *TLS) Listen(address string) (net.Listener, error) {
+	ln, err := t.base().Listen(address)
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{t.cert},
+		ClientCAs:    t.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
+
+func (t *TLS) Dial(peerID int, address string, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	raw, err := t.base().Dial(peerID, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := tls.Client(raw, &tls.Config{
+		Certificates: []tls.Certificate{t.cert},
+		RootCAs:      t.pool,
+		ServerName:   Identity(peerID), // server phải có certificate của đúng peer
+		MinVersion:   tls.VersionTLS13,
+	})
+	conn.SetDeadline(deadline)
+	if err := conn.Handshake(); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// PeerID trả về process ID trong certificate của phía bên kia.
+// ok = false nếu conn không phải TLS (không có identity để kiểm tra).
+// err khác nil nếu là TLS nhưng handshake lỗi hoặc certificate không có
+// identity hợp lệ.
+func PeerID(conn net.Conn) (id int, ok bool, err error) {
+	tlsConn, isTLS := conn.(*tls.Conn)
+	if !isTLS {
+		return 0, false, nil
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return 0, true, err
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return 0, true, fmt.Errorf("peer sent no certificate")
+	}
+	id, valid := parseIdentity(certs[0].Subject.CommonName)
+	if !valid {
+		return 0, true, fmt.Errorf("certificate CN %q is not a process identity", certs[0].Subject.CommonName)
+	}
+	return id, true, nil
+}
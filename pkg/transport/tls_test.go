@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"crypto/tls"
+	"io"
+	"testing"
+	"time"
+)
+
+func newTestTLS(t *testing.T, dir string, id int) *TLS {
+	t.Helper()
+	certFile, keyFile := CertFiles(dir, id)
+	tr, err := NewTLS(CAFile(dir), certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+// listenOnce chấp nhận một connection, trả về PeerID và byte đầu tiên đọc được
+func listenOnce(t *testing.T, tr Transport) (string, <-chan error, <-chan int) {
+	t.Helper()
+	ln, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	errs := make(chan error, 1)
+	ids := make(chan int, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.ReadFull(conn, make([]byte, 1))
+		id, _, err := PeerID(conn)
+		if err != nil {
+			errs <- err
+			return
+		}
+		ids <- id
+	}()
+	return ln.Addr().String(), errs, ids
+}
+
+func TestMutualTLSIdentity(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateCerts(dir, 3); err != nil {
+		t.Fatal(err)
+	}
+	server, client := newTestTLS(t, dir, 0), newTestTLS(t, dir, 2)
+
+	addr, errs, ids := listenOnce(t, server)
+	conn, err := client.Dial(0, addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte{1})
+
+	select {
+	case id := <-ids:
+		if id != 2 {
+			t.Fatalf("PeerID = %d, want 2", id)
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	}
+}
+
+func TestMutualTLSRejectsWrongServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateCerts(dir, 3); err != nil {
+		t.Fatal(err)
+	}
+	// P1 đứng ở địa chỉ mà client tưởng là của P0
+	addr, _, _ := listenOnce(t, newTestTLS(t, dir, 1))
+	if conn, err := newTestTLS(t, dir, 2).Dial(0, addr, time.Second); err == nil {
+		conn.Close()
+		t.Fatal("dial to impostor succeeded")
+	}
+}
+
+func TestMutualTLSRejectsClientWithoutCert(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateCerts(dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestTLS(t, dir, 0)
+	addr, errs, ids := listenOnce(t, server)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: server.pool, ServerName: Identity(0)})
+	if err == nil {
+		// TLS 1.3: client chỉ thấy lỗi ở lần đọc đầu tiên
+		conn.Write([]byte{1})
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	if err == nil {
+		t.Fatal("client without certificate was accepted")
+	}
+	select {
+	case id := <-ids:
+		t.Fatalf("server accepted peer P%d", id)
+	case <-errs:
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	for _, name := range []string{"ses-p", "ses-p1x", "ses-p01", "p1", "ses-p-1"} {
+		if id, ok := parseIdentity(name); ok {
+			t.Errorf("parseIdentity(%q) = %d, want invalid", name, id)
+		}
+	}
+	if id, ok := parseIdentity(Identity(12)); !ok || id != 12 {
+		t.Errorf("parseIdentity(%q) = %d, %v", Identity(12), id, ok)
+	}
+}
@@ -0,0 +1,78 @@
+// Package transport cung cấp các cách kết nối giữa các process.
+// Mỗi message được gửi trên một connection riêng: sender Dial, ghi message,
+// đọc ack; receiver Accept, đọc message, ghi ack.
+package transport
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Transport tạo listener cho process và connection đến peer
+type Transport interface {
+	// Listen lắng nghe tại address của process này
+	Listen(address string) (net.Listener, error)
+	// Dial kết nối đến peer peerID tại address
+	Dial(peerID int, address string, timeout time.Duration) (net.Conn, error)
+}
+
+// StatsReporter là transport có counter riêng (ví dụ UDP)
+type StatsReporter interface {
+	Stats() map[string]int64
+}
+
+// Stats trả về counter của t, hoặc của transport bên dưới nếu t là TLS.
+// nil nếu transport không có counter.
+func Stats(t Transport) map[string]int64 {
+	if tlsT, ok := t.(*TLS); ok {
+		t = tlsT.base()
+	}
+	if r, ok := t.(StatsReporter); ok {
+		return r.Stats()
+	}
+	return nil
+}
+
+// TCP là transport mặc định: plaintext TCP, không xác thực peer.
+// LoopbackOnly = true: chỉ listen trên 127.0.0.1 và từ chối dial đến địa
+// chỉ không phải loopback, kể cả khi config ghi sai address.
+type TCP struct {
+	LoopbackOnly bool
+}
+
+func (t TCP) Listen(address string) (net.Listener, error) {
+	if t.LoopbackOnly {
+		var err error
+		if address, err = loopbackAddress(address); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("tcp", address)
+}
+
+func (t TCP) Dial(peerID int, address string, timeout time.Duration) (net.Conn, error) {
+	if t.LoopbackOnly {
+		var err error
+		if address, err = loopbackAddress(address); err != nil {
+			return nil, fmt.Errorf("P%d: %w", peerID, err)
+		}
+	}
+	return net.DialTimeout("tcp", address, timeout)
+}
+
+// loopbackAddress đổi "localhost:port" thành "127.0.0.1:port" và từ chối
+// host không phải loopback. Không tra DNS: chỉ nhận localhost và IP.
+func loopbackAddress(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if host == "" || host == "localhost" {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return "", fmt.Errorf("%s is not a loopback address", address)
+	}
+	return address, nil
+}
@@ -0,0 +1,507 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tham số của giao thức UDP
+const (
+	udpHeaderSize  = 14   // magic, kind, connID (8), seq (4)
+	udpSegmentSize = 1200 // payload tối đa mỗi datagram, vừa MTU thông thường
+	udpMaxPending  = 4096 // số segment đến sớm tối đa được giữ mỗi conn
+	udpInitialRTO  = 50 * time.Millisecond
+	udpMaxRTO      = time.Second
+	udpMaxRetries  = 10          // số lần gửi lại mỗi segment trước khi báo lỗi
+	udpClosedTTL   = time.Minute // thời gian nhớ conn đã đóng để ack retransmit muộn
+	udpAcceptQueue = 128
+	udpMagic       = 'S'
+)
+
+const (
+	udpData byte = 1
+	udpAck  byte = 2
+)
+
+// UDP chạy mesh trên datagram, không dựa vào thứ tự của TCP.
+//
+// Mỗi Dial là một "conn" ảo có connID ngẫu nhiên và socket riêng. Mỗi Write
+// được chia thành các segment ≤ udpSegmentSize, mỗi segment có seq riêng và
+// được gửi lại (RTO nhân đôi) đến khi nhận ACK. Phía nhận ack mọi segment
+// giữ lại được, bỏ segment trùng và ráp lại theo seq. Conn đã đóng được nhớ udpClosedTTL
+// để segment gửi lại muộn được ack chứ không thành message mới.
+//
+// Các message khác nhau đi trên các conn độc lập nên có thể đến theo bất kỳ
+// thứ tự nào: SES phải tự xử lý việc đến không FIFO.
+type UDP struct {
+	// Loss là xác suất bỏ mỗi datagram gửi đi (0..1), chỉ dùng để thử
+	// retransmission
+	Loss float64
+
+	sent, retransmitted, duplicates, dropped atomic.Int64
+}
+
+// Stats trả về các counter của giao thức
+func (u *UDP) Stats() map[string]int64 {
+	return map[string]int64{
+		"udp_segments_sent":   u.sent.Load(),
+		"udp_retransmitted":   u.retransmitted.Load(),
+		"udp_duplicates":      u.duplicates.Load(),
+		"udp_dropped_by_loss": u.dropped.Load(),
+	}
+}
+
+// write gửi một datagram, có thể bỏ theo Loss
+func (u *UDP) write(send func([]byte) error, packet []byte) error {
+	if u.Loss > 0 && mrand.Float64() < u.Loss {
+		u.dropped.Add(1)
+		return nil
+	}
+	return send(packet)
+}
+
+func (u *UDP) Listen(address string) (net.Listener, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	l := &udpListener{
+		u:      u,
+		pc:     pc,
+		conns:  make(map[uint64]*udpConn),
+		closed: make(map[uint64]time.Time),
+		accept: make(chan *udpConn, udpAcceptQueue),
+		done:   make(chan struct{}),
+	}
+	go l.readLoop()
+	return l, nil
+}
+
+func (u *UDP) Dial(peerID int, address string, timeout time.Duration) (net.Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	sock, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := newUDPConn(u, randomConnID(), sock.LocalAddr(), addr, func(b []byte) error {
+		_, err := sock.Write(b)
+		return err
+	})
+	c.onClose = func() { sock.Close() }
+
+	go func() {
+		buf := make([]byte, udpHeaderSize+udpSegmentSize)
+		for {
+			n, err := sock.Read(buf)
+			if err != nil {
+				// Peer không lắng nghe (ICMP port unreachable) hoặc socket đã đóng
+				c.fail(err)
+				return
+			}
+			kind, id, seq, payload, ok := parsePacket(buf[:n])
+			if ok && id == c.id {
+				c.handle(kind, seq, payload)
+			}
+		}
+	}()
+	return c, nil
+}
+
+func randomConnID() uint64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+func makePacket(kind byte, connID uint64, seq uint32, payload []byte) []byte {
+	packet := make([]byte, udpHeaderSize+len(payload))
+	packet[0] = udpMagic
+	packet[1] = kind
+	binary.BigEndian.PutUint64(packet[2:], connID)
+	binary.BigEndian.PutUint32(packet[10:], seq)
+	copy(packet[udpHeaderSize:], payload)
+	return packet
+}
+
+func parsePacket(b []byte) (kind byte, connID uint64, seq uint32, payload []byte, ok bool) {
+	if len(b) < udpHeaderSize || b[0] != udpMagic || (b[1] != udpData && b[1] != udpAck) {
+		return 0, 0, 0, nil, false
+	}
+	return b[1], binary.BigEndian.Uint64(b[2:]), binary.BigEndian.Uint32(b[10:]), b[udpHeaderSize:], true
+}
+
+// udpListener nhận datagram trên một socket và phân phối theo connID
+type udpListener struct {
+	u      *UDP
+	pc     *net.UDPConn
+	mu     sync.Mutex
+	conns  map[uint64]*udpConn
+	closed map[uint64]time.Time // conn đã đóng → thời điểm đóng
+	accept chan *udpConn
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (l *udpListener) readLoop() {
+	buf := make([]byte, udpHeaderSize+udpSegmentSize)
+	for {
+		n, from, err := l.pc.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		kind, id, seq, payload, ok := parsePacket(buf[:n])
+		if !ok {
+			continue
+		}
+		if c := l.lookup(id, kind, seq, from); c != nil {
+			c.handle(kind, seq, payload)
+		}
+	}
+}
+
+// lookup tìm conn của datagram, tạo conn mới nếu đây là DATA của connID
+// chưa gặp. Trả về nil nếu datagram đã được xử lý hoặc bị bỏ.
+func (l *udpListener) lookup(id uint64, kind byte, seq uint32, from *net.UDPAddr) *udpConn {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c, ok := l.conns[id]; ok {
+		return c
+	}
+	if _, ok := l.closed[id]; ok {
+		// Retransmit muộn cho conn đã đóng: ack lại để sender dừng gửi
+		if kind == udpData {
+			l.u.duplicates.Add(1)
+			l.u.write(func(b []byte) error { return l.send(from, b) }, makePacket(udpAck, id, seq, nil))
+		}
+		return nil
+	}
+	if kind != udpData {
+		return nil
+	}
+
+	l.purgeClosed()
+	c := newUDPConn(l.u, id, l.pc.LocalAddr(), from, func(b []byte) error { return l.send(from, b) })
+	c.onClose = func() { l.forget(id) }
+	select {
+	case l.accept <- c:
+	default:
+		// Hàng đợi Accept đầy: bỏ datagram, sender sẽ gửi lại
+		return nil
+	}
+	l.conns[id] = c
+	return c
+}
+
+func (l *udpListener) send(to *net.UDPAddr, packet []byte) error {
+	_, err := l.pc.WriteToUDP(packet, to)
+	return err
+}
+
+func (l *udpListener) forget(id uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, id)
+	l.closed[id] = time.Now()
+}
+
+// purgeClosed quên các conn đã đóng quá udpClosedTTL
+func (l *udpListener) purgeClosed() {
+	for id, at := range l.closed {
+		if time.Since(at) > udpClosedTTL {
+			delete(l.closed, id)
+		}
+	}
+}
+
+func (l *udpListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *udpListener) Close() error {
+	var err error
+	l.once.Do(func() {
+		close(l.done)
+		err = l.pc.Close()
+	})
+	return err
+}
+
+func (l *udpListener) Addr() net.Addr {
+	return l.pc.LocalAddr()
+}
+
+// udpConn là một luồng byte tin cậy trên datagram
+type udpConn struct {
+	u             *UDP
+	id            uint64
+	local, remote net.Addr
+	send          func([]byte) error
+	onClose       func()
+
+	mu            sync.Mutex
+	readBuf       []byte
+	nextRecv      uint32
+	pending       map[uint32][]byte // segment đến trước segment còn thiếu
+	nextSend      uint32
+	unacked       map[uint32]chan struct{} // đóng khi segment được ack
+	readable      chan struct{}
+	readDeadline  time.Time
+	writeDeadline time.Time
+	err           error // lỗi socket, trả về cho Read/Write sau đó
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newUDPConn(u *UDP, id uint64, local, remote net.Addr, send func([]byte) error) *udpConn {
+	return &udpConn{
+		u:        u,
+		id:       id,
+		local:    local,
+		remote:   remote,
+		send:     send,
+		pending:  make(map[uint32][]byte),
+		unacked:  make(map[uint32]chan struct{}),
+		readable: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// handle xử lý một datagram của conn này
+func (c *udpConn) handle(kind byte, seq uint32, payload []byte) {
+	if kind == udpAck {
+		c.mu.Lock()
+		if ch, ok := c.unacked[seq]; ok {
+			close(ch)
+			delete(c.unacked, seq)
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// DATA: ack segment trùng (ack trước có thể đã mất) và segment sẽ được
+	// giữ lại, trước khi Read thấy dữ liệu: nếu không, phía đọc có thể đóng
+	// conn trước khi ack kịp đi. Segment bị bỏ vì pending đầy không được ack
+	// để sender gửi lại.
+	if _, dup := c.pending[seq]; dup || seq < c.nextRecv {
+		c.u.duplicates.Add(1)
+		c.u.write(c.send, makePacket(udpAck, c.id, seq, nil))
+		return
+	}
+	if seq != c.nextRecv && len(c.pending) >= udpMaxPending {
+		return
+	}
+	c.u.write(c.send, makePacket(udpAck, c.id, seq, nil))
+	if seq != c.nextRecv {
+		c.pending[seq] = append([]byte(nil), payload...)
+		return
+	}
+	c.readBuf = append(c.readBuf, payload...)
+	c.nextRecv++
+	for next, ok := c.pending[c.nextRecv]; ok; next, ok = c.pending[c.nextRecv] {
+		c.readBuf = append(c.readBuf, next...)
+		delete(c.pending, c.nextRecv)
+		c.nextRecv++
+	}
+	select {
+	case c.readable <- struct{}{}:
+	default:
+	}
+}
+
+// fail đánh dấu conn hỏng (socket lỗi), đánh thức Read/Write đang chờ
+func (c *udpConn) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	c.Close()
+}
+
+func (c *udpConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if len(c.readBuf) > 0 {
+			n := copy(b, c.readBuf)
+			c.readBuf = c.readBuf[n:]
+			c.mu.Unlock()
+			return n, nil
+		}
+		deadline := c.readDeadline
+		err := c.err
+		c.mu.Unlock()
+
+		select {
+		case <-c.done:
+			if err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		default:
+		}
+		if err := waitUntil(c.readable, c.done, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// waitUntil chờ ready hoặc done, trả về timeout nếu quá deadline
+func waitUntil(ready <-chan struct{}, done <-chan struct{}, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ready:
+	case <-done:
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// Write gửi b thành các segment và chờ đến khi mọi segment được ack
+func (c *udpConn) Write(b []byte) (int, error) {
+	type segment struct {
+		packet []byte
+		acked  chan struct{}
+	}
+	var segments []segment
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return 0, c.err
+	}
+	for off := 0; off < len(b); off += udpSegmentSize {
+		acked := make(chan struct{})
+		c.unacked[c.nextSend] = acked
+		segments = append(segments, segment{makePacket(udpData, c.id, c.nextSend, b[off:min(off+udpSegmentSize, len(b))]), acked})
+		c.nextSend++
+	}
+	deadline := c.writeDeadline
+	c.mu.Unlock()
+
+	rto := udpInitialRTO
+	for attempt := 0; ; attempt++ {
+		waiting := 0
+		for _, s := range segments {
+			select {
+			case <-s.acked:
+				continue
+			default:
+			}
+			waiting++
+			if attempt > 0 {
+				c.u.retransmitted.Add(1)
+			}
+			c.u.sent.Add(1)
+			if err := c.u.write(c.send, s.packet); err != nil {
+				return 0, err
+			}
+		}
+		if waiting == 0 {
+			return len(b), nil
+		}
+		if attempt == udpMaxRetries {
+			return 0, fmt.Errorf("udp: %d segments to %s not acknowledged after %d retransmissions", waiting, c.remote, udpMaxRetries)
+		}
+
+		wait := time.Now().Add(rto)
+		if !deadline.IsZero() && deadline.Before(wait) {
+			wait = deadline
+		}
+		for _, s := range segments {
+			if err := waitUntil(s.acked, c.done, wait); err != nil {
+				break
+			}
+		}
+		select {
+		case <-c.done:
+			c.mu.Lock()
+			err := c.err
+			c.mu.Unlock()
+			if err == nil {
+				err = net.ErrClosed
+			}
+			return 0, err
+		default:
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		if rto *= 2; rto > udpMaxRTO {
+			rto = udpMaxRTO
+		}
+	}
+}
+
+func (c *udpConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return nil
+}
+
+func (c *udpConn) LocalAddr() net.Addr  { return c.local }
+func (c *udpConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *udpConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *udpConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *udpConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	return nil
+}
@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoServer trả lời mỗi JSON value nhận được bằng chính value đó, giống
+// cách process đọc message và ghi ack trên cùng một conn
+func echoServer(t *testing.T, ln net.Listener) (accepted func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	count := 0
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			count++
+			mu.Unlock()
+			go func() {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+				var v interface{}
+				if err := json.NewDecoder(conn).Decode(&v); err != nil {
+					return
+				}
+				json.NewEncoder(conn).Encode(v)
+			}()
+		}
+	}()
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func exchange(tr Transport, address string, payload string) (string, error) {
+	conn, err := tr.Dial(0, address, time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(20 * time.Second))
+	if err := json.NewEncoder(conn).Encode(payload); err != nil {
+		return "", err
+	}
+	var reply string
+	err = json.NewDecoder(conn).Decode(&reply)
+	return reply, err
+}
+
+func TestUDPDeliversExactlyOnceUnderLoss(t *testing.T) {
+	server := &UDP{Loss: 0.3}
+	ln, err := server.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := echoServer(t, ln)
+
+	client := &UDP{Loss: 0.3}
+	const n = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := fmt.Sprintf("message %d", i)
+			got, err := exchange(client, ln.Addr().String(), want)
+			if err == nil && got != want {
+				err = fmt.Errorf("reply %q, want %q", got, want)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := accepted(); got != n {
+		t.Fatalf("accepted %d conns, want %d (retransmits must not create new conns)", got, n)
+	}
+	if client.Stats()["udp_retransmitted"] == 0 {
+		t.Fatal("no retransmissions under 30% loss")
+	}
+}
+
+func TestUDPReassemblesLargeWrites(t *testing.T) {
+	u := &UDP{}
+	ln, err := u.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	echoServer(t, ln)
+
+	big := string(bytes.Repeat([]byte("0123456789"), 20000)) // ~170 segment
+	got, err := exchange(u, ln.Addr().String(), big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != big {
+		t.Fatalf("reply has %d bytes, want %d", len(got), len(big))
+	}
+}
+
+func TestUDPOutOfOrderSegments(t *testing.T) {
+	c := newUDPConn(&UDP{}, 1, nil, nil, func([]byte) error { return nil })
+	c.handle(udpData, 2, []byte("c"))
+	c.handle(udpData, 0, []byte("a"))
+	c.handle(udpData, 0, []byte("a")) // trùng
+	c.handle(udpData, 1, []byte("b"))
+
+	buf := make([]byte, 10)
+	n, err := c.Read(buf)
+	if err != nil || string(buf[:n]) != "abc" {
+		t.Fatalf("Read() = %q, %v; want \"abc\"", buf[:n], err)
+	}
+	if c.u.duplicates.Load() != 1 {
+		t.Fatalf("duplicates = %d, want 1", c.u.duplicates.Load())
+	}
+}
+
+// Segment đến sớm khi pending đã đầy bị bỏ và không được ack, nên sender
+// gửi lại thay vì stream dừng mãi ở segment đó
+func TestUDPDoesNotAckDroppedSegments(t *testing.T) {
+	acked := make(map[uint32]bool)
+	c := newUDPConn(&UDP{}, 1, nil, nil, func(packet []byte) error {
+		_, _, seq, _, _ := parsePacket(packet)
+		acked[seq] = true
+		return nil
+	})
+	for seq := uint32(1); seq <= udpMaxPending+1; seq++ {
+		c.handle(udpData, seq, []byte("x"))
+	}
+	if !acked[udpMaxPending] || acked[udpMaxPending+1] {
+		t.Fatalf("acked last stored = %v, acked dropped = %v", acked[udpMaxPending], acked[udpMaxPending+1])
+	}
+
+	// Sender gửi lại sau khi pending trống: segment được nhận và ack
+	c.handle(udpData, 0, []byte("x"))
+	c.handle(udpData, udpMaxPending+1, []byte("x"))
+	if !acked[udpMaxPending+1] {
+		t.Fatal("retransmitted segment not acked")
+	}
+	buf := make([]byte, 2*udpMaxPending)
+	if n, err := c.Read(buf); err != nil || n != udpMaxPending+2 {
+		t.Fatalf("Read() = %d, %v; want %d bytes", n, err, udpMaxPending+2)
+	}
+}
+
+func TestUDPPeerDown(t *testing.T) {
+	// Lấy một port rồi đóng ngay để chắc chắn không ai lắng nghe
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := pc.LocalAddr().String()
+	pc.Close()
+
+	start := time.Now()
+	if _, err := exchange(&UDP{}, address, "hello"); err == nil {
+		t.Fatal("exchange with a dead peer succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Fatalf("took %v to notice dead peer", elapsed)
+	}
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// maxSocketPath là giới hạn độ dài đường dẫn Unix socket (sun_path)
+const maxSocketPath = 104
+
+// Unix chạy mesh qua Unix domain socket trong Dir: process N lắng nghe tại
+// Dir/p<N>.sock và address trong config bị bỏ qua. Mỗi cluster dùng một Dir
+// riêng nên nhiều cluster chạy song song trên CI không đụng port.
+type Unix struct {
+	Dir       string
+	ProcessID int
+}
+
+// SocketPath là đường dẫn socket của process trong dir
+func SocketPath(dir string, processID int) string {
+	return filepath.Join(dir, fmt.Sprintf("p%d.sock", processID))
+}
+
+func (u Unix) Listen(address string) (net.Listener, error) {
+	if err := os.MkdirAll(u.Dir, 0700); err != nil {
+		return nil, err
+	}
+	path := SocketPath(u.Dir, u.ProcessID)
+	if len(path) >= maxSocketPath {
+		return nil, fmt.Errorf("socket path %s is too long (%d bytes, max %d)", path, len(path), maxSocketPath-1)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+func (u Unix) Dial(peerID int, address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", SocketPath(u.Dir, peerID), timeout)
+}
+
+// removeStaleSocket xóa socket còn lại từ process đã chết. Nếu vẫn có
+// process đang lắng nghe thì báo lỗi thay vì cướp socket của nó.
+func removeStaleSocket(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) && !errors.Is(err, syscall.ENOENT) {
+		return err
+	}
+	return os.Remove(path)
+}
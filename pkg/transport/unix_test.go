@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// roundTrip gửi một byte qua tr từ peer 1 đến listener ln và đọc lại
+func roundTrip(t *testing.T, tr Transport, ln net.Listener, peerID int, address string) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		_, err = conn.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	conn, err := tr.Dial(peerID, address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte{1})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnixTransport(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ln, err := Unix{Dir: dir, ProcessID: 0}.Listen("localhost:8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, Unix{Dir: dir, ProcessID: 1}, ln, 0, "ignored")
+
+	// Socket đang được dùng: không được cướp
+	if second, err := (Unix{Dir: dir, ProcessID: 0}).Listen(""); err == nil {
+		second.Close()
+		t.Fatal("listened on a socket in use")
+	}
+	ln.Close()
+
+	// Socket còn sót lại của process đã chết được dọn
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: SocketPath(dir, 2), Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+	ln, err = Unix{Dir: dir, ProcessID: 2}.Listen("")
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	ln.Close()
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	tr := TCP{LoopbackOnly: true}
+	ln, err := tr.Listen("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if ip := ln.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+		t.Fatalf("listening on %v", ip)
+	}
+	roundTrip(t, tr, ln, 0, ln.Addr().String())
+
+	for _, address := range []string{"0.0.0.0:8000", "10.0.0.1:8000", "example.com:8000"} {
+		if _, err := tr.Dial(0, address, time.Second); err == nil {
+			t.Errorf("dialed %s", address)
+		}
+		if ln, err := tr.Listen(address); err == nil {
+			ln.Close()
+			t.Errorf("listened on %s", address)
+		}
+	}
+}
@@ -56,9 +56,16 @@ func (vc *VectorClock) GetEntries() []VectorEntry {
 
 // PrepareToSend chuẩn bị gửi message đến targetID
 // Theo SES algorithm:
-// 1. Gửi message với tm = tP hiện tại và V_P (không bao gồm entry cho target)
-// 2. Thêm/update (targetID, tm) vào V_P của sender
-// 3. Increment tP[senderID]++
+// 1. Gửi message với tm = tP hiện tại và V_M = toàn bộ V_P
+// 2. Increment tP[senderID]++
+// 3. Thêm/update (targetID, tP sau increment) vào V_P của sender
+//
+// V_M phải bao gồm cả entry cho target: entry (target, t) có thể đến từ
+// process khác (merge khi deliver) và cho biết target phải deliver message
+// trước đó rồi mới được deliver message này.
+// Entry lưu tP SAU khi increment: receiver đã deliver message này thì
+// tP[senderID] của receiver > tm[senderID], nên điều kiện t <= tP trong
+// CanDeliver chỉ đúng khi message đã thực sự được deliver.
 func (vc *VectorClock) PrepareToSend(targetID int) (tm []int, vp []VectorEntry) {
 	vc.mu.Lock()
 	defer vc.mu.Unlock()
@@ -67,41 +74,39 @@ func (vc *VectorClock) PrepareToSend(targetID int) (tm []int, vp []VectorEntry)
 	tm = make([]int, len(vc.localTime))
 	copy(tm, vc.localTime)
 
-	// 2. Chuẩn bị V_P để gửi (KHÔNG bao gồm entry cho targetID)
+	// Chuẩn bị V_M = V_P (bao gồm cả entry cho targetID)
 	vp = []VectorEntry{}
 	for _, entry := range vc.entries {
-		if entry.TargetProcessID != targetID {
-			tsCopy := make([]int, len(entry.Timestamp))
-			copy(tsCopy, entry.Timestamp)
-			vp = append(vp, VectorEntry{
-				TargetProcessID: entry.TargetProcessID,
-				Timestamp:       tsCopy,
-			})
-		}
+		tsCopy := make([]int, len(entry.Timestamp))
+		copy(tsCopy, entry.Timestamp)
+		vp = append(vp, VectorEntry{
+			TargetProcessID: entry.TargetProcessID,
+			Timestamp:       tsCopy,
+		})
 	}
 
-	// 3. Cập nhật V_P: thêm/update (targetID, tm)
-	// Entry này KHÔNG được gửi trong message, nhưng được lưu local
+	// 2. Increment tP[senderID]++ (sau khi set tm)
+	vc.localTime[vc.processID]++
+
+	// 3. Cập nhật V_P: thêm/update (targetID, tP)
+	// Entry này chỉ được gửi trong các message SAU message này
 	found := false
 	for i := range vc.entries {
 		if vc.entries[i].TargetProcessID == targetID {
-			copy(vc.entries[i].Timestamp, tm)
+			copy(vc.entries[i].Timestamp, vc.localTime)
 			found = true
 			break
 		}
 	}
 	if !found {
-		tmCopy := make([]int, len(tm))
-		copy(tmCopy, tm)
+		tsCopy := make([]int, len(vc.localTime))
+		copy(tsCopy, vc.localTime)
 		vc.entries = append(vc.entries, VectorEntry{
 			TargetProcessID: targetID,
-			Timestamp:       tmCopy,
+			Timestamp:       tsCopy,
 		})
 	}
 
-	// 4. Increment tP[senderID]++ (sau khi set tm)
-	vc.localTime[vc.processID]++
-
 	return tm, vp
 }
 
@@ -114,20 +119,14 @@ func (vc *VectorClock) PrepareToSend(targetID int) (tm []int, vp []VectorEntry)
 //   - Nếu t < tP: deliver (mọi dependency đã satisfied)
 //
 // Giải thích: entry (receiverID, t) trong V_M có nghĩa là
-// "sender đã gửi message khác đến receiverID với timestamp t"
-// Nếu t >= tP[receiverID], có nghĩa là receiver chưa nhận message đó
+// "đã có message gửi đến receiverID, sau khi gửi tP của người gửi là t"
+// Nếu tồn tại j: t[j] > tP[j], có nghĩa là receiver chưa nhận message đó
 func (vc *VectorClock) CanDeliver(senderID int, tm []int, vm []VectorEntry) (bool, string) {
 	vc.mu.RLock()
 	defer vc.mu.RUnlock()
 
 	// 1. Tìm entry (receiverID, t) trong V_M
-	var entryForMe *VectorEntry = nil
-	for i := range vm {
-		if vm[i].TargetProcessID == vc.processID {
-			entryForMe = &vm[i]
-			break
-		}
-	}
+	entryForMe := vc.entryFor(vm)
 
 	// 2. Nếu KHÔNG có entry cho receiver → có thể deliver
 	if entryForMe == nil {
@@ -140,18 +139,54 @@ func (vc *VectorClock) CanDeliver(senderID int, tm []int, vm []VectorEntry) (boo
 	// HOẶC đơn giản hơn: NOT(t >= tP)
 
 	// Kiểm tra xem có component nào của t > tP không
-	for j := 0; j < len(entryForMe.Timestamp) && j < len(vc.localTime); j++ {
-		if entryForMe.Timestamp[j] > vc.localTime[j] {
-			// t >= tP (ít nhất 1 component) → BUFFER
-			return false, fmt.Sprintf("dependency not satisfied: entry has t[%d]=%d > tP[%d]=%d",
-				j, entryForMe.Timestamp[j], j, vc.localTime[j])
-		}
+	if j, blocked := vc.firstBlocking(entryForMe.Timestamp); blocked {
+		// t >= tP (ít nhất 1 component) → BUFFER
+		return false, fmt.Sprintf("dependency not satisfied: entry has t[%d]=%d > tP[%d]=%d",
+			j, entryForMe.Timestamp[j], j, vc.localTime[j])
 	}
 
 	// Tất cả components: t[j] <= tP[j] → DELIVER
 	return true, "all dependencies satisfied"
 }
 
+// WaitingOn trả về dependency đang chặn message có V_M = vm:
+// message chỉ có thể deliver khi tP[component] >= value.
+// blocked = false nghĩa là message deliver được ngay (giống CanDeliver).
+func (vc *VectorClock) WaitingOn(vm []VectorEntry) (component int, value int, blocked bool) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+
+	entryForMe := vc.entryFor(vm)
+	if entryForMe == nil {
+		return 0, 0, false
+	}
+	j, blocked := vc.firstBlocking(entryForMe.Timestamp)
+	if !blocked {
+		return 0, 0, false
+	}
+	return j, entryForMe.Timestamp[j], true
+}
+
+// entryFor tìm entry (processID, t) trong V_M, nil nếu không có
+func (vc *VectorClock) entryFor(vm []VectorEntry) *VectorEntry {
+	for i := range vm {
+		if vm[i].TargetProcessID == vc.processID {
+			return &vm[i]
+		}
+	}
+	return nil
+}
+
+// firstBlocking tìm component j đầu tiên có t[j] > tP[j]
+func (vc *VectorClock) firstBlocking(t []int) (int, bool) {
+	for j := 0; j < len(t) && j < len(vc.localTime); j++ {
+		if t[j] > vc.localTime[j] {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
 // DeliverMessage cập nhật vector clock sau khi deliver message
 // Theo SES algorithm:
 // 1. Cập nhật tP theo quy tắc vector clock:
@@ -159,6 +194,8 @@ func (vc *VectorClock) CanDeliver(senderID int, tm []int, vm []VectorEntry) (boo
 //   - tP[senderID]++ (vì đã nhận 1 message từ sender)
 //
 // 2. Merge V_M vào V_P
+//
+// senderID phải nằm trong [0, numProcesses): caller chịu trách nhiệm kiểm tra.
 func (vc *VectorClock) DeliverMessage(senderID int, tm []int, vm []VectorEntry) {
 	vc.mu.Lock()
 	defer vc.mu.Unlock()
@@ -177,7 +214,11 @@ func (vc *VectorClock) DeliverMessage(senderID int, tm []int, vm []VectorEntry)
 	// Với mỗi entry (P', t') trong V_M:
 	// - Nếu V_P có (P', t): cập nhật t = max(t, t') component-wise
 	// - Nếu không: thêm (P', t') vào V_P
+	// Entry cho chính process này đã được kiểm tra trong CanDeliver, không cần giữ
 	for _, vmEntry := range vm {
+		if vmEntry.TargetProcessID == vc.processID {
+			continue
+		}
 		found := false
 		for i := range vc.entries {
 			if vc.entries[i].TargetProcessID == vmEntry.TargetProcessID {
@@ -203,6 +244,42 @@ func (vc *VectorClock) DeliverMessage(senderID int, tm []int, vm []VectorEntry)
 	}
 }
 
+// Clone tạo bản sao độc lập của vector clock (tP và V_P)
+func (vc *VectorClock) Clone() *VectorClock {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+
+	clone := NewVectorClock(vc.processID, vc.numProcesses)
+	copy(clone.localTime, vc.localTime)
+	for _, entry := range vc.entries {
+		tsCopy := make([]int, len(entry.Timestamp))
+		copy(tsCopy, entry.Timestamp)
+		clone.entries = append(clone.entries, VectorEntry{
+			TargetProcessID: entry.TargetProcessID,
+			Timestamp:       tsCopy,
+		})
+	}
+	return clone
+}
+
+// HappenedBefore kiểm tra a → b theo vector clock chuẩn:
+// a[j] <= b[j] với mọi j và a != b
+func HappenedBefore(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	strictlyLess := false
+	for j := range a {
+		if a[j] > b[j] {
+			return false
+		}
+		if a[j] < b[j] {
+			strictlyLess = true
+		}
+	}
+	return strictlyLess
+}
+
 func (vc *VectorClock) String() string {
 	vc.mu.RLock()
 	defer vc.mu.RUnlock()
@@ -0,0 +1,372 @@
+package vectorclock
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// simMessage là message trong mô phỏng, kèm vector clock chuẩn làm ground truth
+type simMessage struct {
+	id       int
+	sender   int
+	receiver int
+	tm       []int
+	vm       []VectorEntry
+	causal   []int
+}
+
+type simProcess struct {
+	vc        *VectorClock
+	causal    []int
+	buffer    []*simMessage
+	delivered map[int]bool
+}
+
+// simulation chạy một history ngẫu nhiên gồm send và arrival theo thứ tự bất kỳ
+type simulation struct {
+	t        *testing.T
+	rng      *rand.Rand
+	procs    []*simProcess
+	sent     []*simMessage
+	inFlight []*simMessage
+}
+
+func newSimulation(t *testing.T, seed int64, n int) *simulation {
+	s := &simulation{t: t, rng: rand.New(rand.NewSource(seed))}
+	for i := 0; i < n; i++ {
+		s.procs = append(s.procs, &simProcess{
+			vc:        NewVectorClock(i, n),
+			causal:    make([]int, n),
+			delivered: map[int]bool{},
+		})
+	}
+	return s
+}
+
+func (s *simulation) send(from, to int) bool {
+	p := s.procs[from]
+	before := p.vc.GetLocalTime()
+	tm, vm := p.vc.PrepareToSend(to)
+	after := p.vc.GetLocalTime()
+
+	// tm = tP trước khi gửi, chỉ tP[own] tăng đúng 1
+	if !reflect.DeepEqual(tm, before) {
+		s.t.Logf("P%d send: tm=%v, want tP before send %v", from, tm, before)
+		return false
+	}
+	before[from]++
+	if !reflect.DeepEqual(after, before) {
+		s.t.Logf("P%d send: tP after=%v, want %v", from, after, before)
+		return false
+	}
+	// V_P ghi lại (to, tP sau khi gửi)
+	if entry := findEntry(p.vc.GetEntries(), to); entry == nil || !reflect.DeepEqual(entry.Timestamp, after) {
+		s.t.Logf("P%d send: V_P entry for P%d = %v, want %v", from, to, entry, after)
+		return false
+	}
+
+	p.causal[from]++
+	m := &simMessage{id: len(s.sent), sender: from, receiver: to, tm: tm, vm: vm,
+		causal: append([]int(nil), p.causal...)}
+	s.sent = append(s.sent, m)
+	s.inFlight = append(s.inFlight, m)
+	return true
+}
+
+func (s *simulation) arrive(i int) bool {
+	m := s.inFlight[i]
+	s.inFlight = append(s.inFlight[:i], s.inFlight[i+1:]...)
+	p := s.procs[m.receiver]
+
+	if ok, _ := p.vc.CanDeliver(m.sender, m.tm, m.vm); !ok {
+		p.buffer = append(p.buffer, m)
+		return true
+	}
+	if !s.deliver(p, m) {
+		return false
+	}
+	for progress := true; progress; {
+		progress = false
+		for j, bm := range p.buffer {
+			if ok, _ := p.vc.CanDeliver(bm.sender, bm.tm, bm.vm); ok {
+				p.buffer = append(p.buffer[:j], p.buffer[j+1:]...)
+				if !s.deliver(p, bm) {
+					return false
+				}
+				progress = true
+				break
+			}
+		}
+	}
+	return true
+}
+
+// deliver kiểm tra causal safety: mọi message đến cùng receiver mà send của
+// nó xảy ra trước send(m) phải đã được deliver
+func (s *simulation) deliver(p *simProcess, m *simMessage) bool {
+	for _, other := range s.sent {
+		if other.receiver == m.receiver && other != m && !p.delivered[other.id] &&
+			HappenedBefore(other.causal, m.causal) {
+			s.t.Logf("P%d delivered message %d before %d, but send(%d) → send(%d)",
+				m.receiver, m.id, other.id, other.id, m.id)
+			return false
+		}
+	}
+
+	before := p.vc.GetLocalTime()
+	p.vc.DeliverMessage(m.sender, m.tm, m.vm)
+	after := p.vc.GetLocalTime()
+	for j := range after {
+		if after[j] < before[j] || after[j] < m.tm[j] {
+			s.t.Logf("P%d deliver: tP=%v not >= max(%v, %v)", m.receiver, after, before, m.tm)
+			return false
+		}
+	}
+	if after[m.sender] <= m.tm[m.sender] {
+		s.t.Logf("P%d deliver: tP[%d]=%d not > tm[%d]=%d", m.receiver, m.sender, after[m.sender], m.sender, m.tm[m.sender])
+		return false
+	}
+	if findEntry(p.vc.GetEntries(), m.receiver) != nil {
+		s.t.Logf("P%d deliver: V_P contains entry for itself", m.receiver)
+		return false
+	}
+
+	for j := range p.causal {
+		if m.causal[j] > p.causal[j] {
+			p.causal[j] = m.causal[j]
+		}
+	}
+	p.causal[m.receiver]++
+	p.delivered[m.id] = true
+	return true
+}
+
+func findEntry(entries []VectorEntry, target int) *VectorEntry {
+	for i := range entries {
+		if entries[i].TargetProcessID == target {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// TestRandomHistoriesAreCausallySafe sinh history ngẫu nhiên (số process,
+// số message, thứ tự send/arrival) và kiểm tra causal safety sau mỗi deliver,
+// và liveness khi mọi message đã đến
+func TestRandomHistoriesAreCausallySafe(t *testing.T) {
+	property := func(seed int64, nProcs, nMsgs uint8) bool {
+		n := 2 + int(nProcs)%4 // 2..5 process
+		total := 1 + int(nMsgs)%40
+		s := newSimulation(t, seed, n)
+
+		for sends := 0; sends < total || len(s.inFlight) > 0; {
+			// Chọn ngẫu nhiên giữa send mới và arrival của một message đang bay
+			if sends < total && (len(s.inFlight) == 0 || s.rng.Intn(2) == 0) {
+				from := s.rng.Intn(n)
+				to := (from + 1 + s.rng.Intn(n-1)) % n
+				if !s.send(from, to) {
+					return false
+				}
+				sends++
+				continue
+			}
+			if !s.arrive(s.rng.Intn(len(s.inFlight))) {
+				return false
+			}
+		}
+
+		for i, p := range s.procs {
+			if len(p.buffer) != 0 {
+				t.Logf("seed %d: P%d still buffers %d messages after all arrivals", seed, i, len(p.buffer))
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	vc := NewVectorClock(0, 3)
+	vc.PrepareToSend(1)
+	clone := vc.Clone()
+	vc.PrepareToSend(2)
+	vc.DeliverMessage(1, []int{0, 5, 0}, []VectorEntry{{TargetProcessID: 2, Timestamp: []int{9, 9, 9}}})
+
+	if got := clone.GetLocalTime(); !reflect.DeepEqual(got, []int{1, 0, 0}) {
+		t.Fatalf("clone tP = %v, want [1 0 0]", got)
+	}
+	if got := clone.GetEntries(); len(got) != 1 || !reflect.DeepEqual(got[0].Timestamp, []int{1, 0, 0}) {
+		t.Fatalf("clone V_P = %v", got)
+	}
+}
+
+func TestHappenedBefore(t *testing.T) {
+	property := func(a, b []int8) bool {
+		x, y := toInts(a), toInts(b)
+		if HappenedBefore(x, x) {
+			return false // irreflexive
+		}
+		return !(HappenedBefore(x, y) && HappenedBefore(y, x)) // antisymmetric
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		a, b []int
+		want bool
+	}{
+		{[]int{0, 0}, []int{0, 1}, true},
+		{[]int{1, 0}, []int{0, 1}, false},
+		{[]int{1, 1}, []int{1, 1}, false},
+		{[]int{1}, []int{1, 2}, false},
+	}
+	for _, c := range cases {
+		if got := HappenedBefore(c.a, c.b); got != c.want {
+			t.Errorf("HappenedBefore(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func toInts(b []int8) []int {
+	out := make([]int, len(b))
+	for i, v := range b {
+		out[i] = int(v)
+	}
+	return out
+}
+
+// decodeEntries đọc V_M từ bytes tùy ý: mỗi entry là (target, length, values...)
+// nên có thể sinh ra vector sai độ dài, giá trị âm và process ID không tồn tại
+func decodeEntries(data []byte) []VectorEntry {
+	var vm []VectorEntry
+	for len(data) >= 2 {
+		target := int(int8(data[0]))
+		length := int(data[1] % 8)
+		data = data[2:]
+		if length > len(data) {
+			length = len(data)
+		}
+		vm = append(vm, VectorEntry{TargetProcessID: target, Timestamp: toInts(bytesToInt8(data[:length]))})
+		data = data[length:]
+	}
+	return vm
+}
+
+func bytesToInt8(b []byte) []int8 {
+	out := make([]int8, len(b))
+	for i, v := range b {
+		out[i] = int8(v)
+	}
+	return out
+}
+
+// newFuzzClock tạo vector clock cho P0 với tP tùy ý (không âm)
+func newFuzzClock(n uint8, local []byte) *VectorClock {
+	numProcesses := 1 + int(n)%6
+	vc := NewVectorClock(0, numProcesses)
+	for j := 0; j < numProcesses && j < len(local); j++ {
+		vc.localTime[j] = int(local[j] % 16)
+	}
+	return vc
+}
+
+func FuzzCanDeliver(f *testing.F) {
+	f.Add(uint8(3), 1, []byte{0, 0, 0}, []byte{0, 0, 0}, []byte{0, 3, 1, 0, 0})
+	f.Add(uint8(3), 2, []byte{1, 1, 0}, []byte{2, 0, 0}, []byte{0, 3, 2, 0, 0, 1, 3, 1, 1, 1})
+	f.Add(uint8(4), 9, []byte{}, []byte{255, 255}, []byte{200, 7, 255, 255, 255, 255, 255, 255, 255})
+	f.Add(uint8(2), -1, []byte{5}, []byte{1, 2, 3, 4, 5, 6}, []byte{0, 1, 128})
+
+	f.Fuzz(func(t *testing.T, n uint8, senderID int, local, tmBytes, vmBytes []byte) {
+		vc := newFuzzClock(n, local)
+		tm := toInts(bytesToInt8(tmBytes))
+		vm := decodeEntries(vmBytes)
+		tP := vc.GetLocalTime()
+
+		ok, reason := vc.CanDeliver(senderID, tm, vm)
+		if reason == "" {
+			t.Fatal("CanDeliver returned empty reason")
+		}
+		component, need, blocked := vc.WaitingOn(vm)
+		if ok == blocked {
+			t.Fatalf("CanDeliver=%v but WaitingOn blocked=%v", ok, blocked)
+		}
+
+		entry := findEntry(vm, 0)
+		if ok && entry != nil {
+			// Causal safety: deliver được thì mọi dependency t[j] <= tP[j]
+			for j := 0; j < len(entry.Timestamp) && j < len(tP); j++ {
+				if entry.Timestamp[j] > tP[j] {
+					t.Fatalf("delivered with t[%d]=%d > tP[%d]=%d", j, entry.Timestamp[j], j, tP[j])
+				}
+			}
+		}
+		if blocked && (component < 0 || component >= len(tP) || need <= tP[component]) {
+			t.Fatalf("WaitingOn = (%d, %d) but tP=%v", component, need, tP)
+		}
+		if !reflect.DeepEqual(vc.GetLocalTime(), tP) {
+			t.Fatal("CanDeliver modified tP")
+		}
+	})
+}
+
+func FuzzDeliverMessage(f *testing.F) {
+	f.Add(uint8(3), uint8(1), []byte{0, 0, 0}, []byte{0, 0, 0}, []byte{2, 3, 1, 0, 0})
+	f.Add(uint8(4), uint8(2), []byte{3, 1}, []byte{255, 4, 0, 0, 9}, []byte{0, 2, 5, 5, 99, 1, 0})
+	f.Add(uint8(1), uint8(0), []byte{}, []byte{}, []byte{})
+
+	f.Fuzz(func(t *testing.T, n uint8, sender uint8, local, tmBytes, vmBytes []byte) {
+		vc := newFuzzClock(n, local)
+		// senderID hợp lệ là precondition của DeliverMessage
+		senderID := int(sender) % vc.numProcesses
+		tm := toInts(bytesToInt8(tmBytes))
+		vm := decodeEntries(vmBytes)
+		before := vc.GetLocalTime()
+		entriesBefore := vc.GetEntries()
+
+		vc.DeliverMessage(senderID, tm, vm)
+		after := vc.GetLocalTime()
+
+		if len(after) != len(before) {
+			t.Fatalf("len(tP) changed: %d → %d", len(before), len(after))
+		}
+		for j := range after {
+			if after[j] < before[j] {
+				t.Fatalf("tP[%d] decreased: %d → %d", j, before[j], after[j])
+			}
+			if j < len(tm) && after[j] < tm[j] {
+				t.Fatalf("tP[%d]=%d < tm[%d]=%d", j, after[j], j, tm[j])
+			}
+		}
+		if after[senderID] <= before[senderID] {
+			t.Fatalf("tP[sender] did not increase: %d → %d", before[senderID], after[senderID])
+		}
+
+		entries := vc.GetEntries()
+		if findEntry(entries, 0) != nil {
+			t.Fatal("V_P contains entry for the receiver itself")
+		}
+		// Merge không làm mất dependency: mỗi entry cũ và mới đều được giữ (max)
+		for _, src := range [][]VectorEntry{entriesBefore, vm} {
+			for _, e := range src {
+				if e.TargetProcessID == 0 {
+					continue
+				}
+				merged := findEntry(entries, e.TargetProcessID)
+				if merged == nil {
+					t.Fatalf("entry for P%d lost after merge", e.TargetProcessID)
+				}
+				for j := 0; j < len(e.Timestamp) && j < len(merged.Timestamp); j++ {
+					if merged.Timestamp[j] < e.Timestamp[j] {
+						t.Fatalf("entry P%d: t[%d]=%d < %d after merge", e.TargetProcessID, j, merged.Timestamp[j], e.Timestamp[j])
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/NationalWind/ses-project/pkg/hlc"
+)
+
+// Entry là một dòng log trong timeline
+type Entry struct {
+	Process int
+	HLC     hlc.Timestamp // zero nếu trước dòng này process chưa có HLC
+	Line    string
+}
+
+// hlcAttr là attr hlc của một record: hlc=... ở format line/text,
+// "hlc":"..." ở format json
+var hlcAttr = regexp.MustCompile(`\bhlc"?[=:]"?(\d+\.\d{9},\d+)`)
+
+// Timeline đọc mọi file process_N.log trong dir và sắp các dòng theo HLC,
+// nên sự kiện xảy ra trước luôn đứng trước (ví dụ SENT trước DELIVERED của
+// cùng message). Dòng không có HLC lấy HLC của dòng trước nó trong cùng
+// file; các dòng cùng HLC giữ thứ tự process rồi thứ tự trong file.
+func Timeline(dir string) ([]Entry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeline []Entry
+	found := false
+	for _, entry := range entries {
+		m := logName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		found = true
+		id, _ := strconv.Atoi(m[1])
+		var last hlc.Timestamp
+		err := scan(filepath.Join(dir, entry.Name()), func(line string) error {
+			if m := hlcAttr.FindStringSubmatch(line); m != nil {
+				ts, err := hlc.Parse(m[1])
+				if err != nil {
+					return err
+				}
+				last = ts
+			}
+			timeline = append(timeline, Entry{id, last, line})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no process_N.log files in %s", dir)
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		if c := timeline[i].HLC.Compare(timeline[j].HLC); c != 0 {
+			return c < 0
+		}
+		return timeline[i].Process < timeline[j].Process
+	})
+	return timeline, nil
+}
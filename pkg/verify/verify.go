@@ -0,0 +1,292 @@
+// Package verify kiểm tra log của một lần chạy: mọi message đã gửi được
+// deliver đúng một lần, thứ tự deliver ở mỗi process tôn trọng quan hệ
+// nhân quả (điều SES đảm bảo), và mọi process deliver các message total
+// order theo cùng một thứ tự.
+package verify
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Report là kết quả kiểm tra
+type Report struct {
+	Processes   []int // ID các process có file log
+	Sent        int
+	Delivered   int
+	Undelivered []string // đã gửi nhưng không được deliver
+	Duplicates  []string // được deliver nhiều lần
+	Violations  []Violation
+	// TotalOrder là số message total order đã multicast, TotalMismatches
+	// là các process có thứ tự deliver total order khác các process khác
+	TotalOrder      int
+	TotalMismatches []string
+}
+
+// Violation: Process deliver Delivered trong khi Missing, message xảy ra
+// trước nó và cũng gửi đến Process, chưa được deliver
+type Violation struct {
+	Process   int
+	Delivered string
+	Missing   string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("P%d delivered %s before %s, which happened before it", v.Process, v.Delivered, v.Missing)
+}
+
+// OK cho biết lần chạy không có lỗi nào
+func (r *Report) OK() bool {
+	return len(r.Undelivered) == 0 && len(r.Duplicates) == 0 && len(r.Violations) == 0 &&
+		len(r.TotalMismatches) == 0
+}
+
+var (
+	logName     = regexp.MustCompile(`^process_(\d+)\.log$`)
+	messageID   = regexp.MustCompile(`^P(\d+)-P(\d+)-M\d+$`)
+	sentLine    = regexp.MustCompile(`📤 SENT to P\d+: (\S+) \| tm=\[([\d ]*)\]`)
+	receiveLine = regexp.MustCompile(`📥 RECEIVED from P\d+: (\S+) \| tm=\[([\d ]*)\]`)
+	deliverLine = regexp.MustCompile(`✅ DELIVERED: (\S+) \|`)
+	castLine    = regexp.MustCompile(`📣 MULTICAST (\S+) \|`)
+	totalLine   = regexp.MustCompile(`🔢 TOTAL DELIVERED #\d+: (\S+) \|`)
+)
+
+// sent là một message và thời điểm gửi của nó
+type sent struct {
+	id       string
+	sender   int
+	receiver int
+	// clock là vector clock của sự kiện gửi: tm (clock TRƯỚC khi gửi) với
+	// phần tử của sender cộng 1, giống như receiver cập nhật khi deliver
+	clock []int
+}
+
+// Logs kiểm tra các file process_N.log trong dir
+func Logs(dir string) (*Report, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	messages := make(map[string]*sent)
+	delivered := make(map[int][]string) // theo thứ tự deliver ở mỗi process
+	var fromSender []string             // ID theo thứ tự dòng SENT
+	total := make(map[int][]string)     // thứ tự deliver total order ở mỗi process
+
+	for _, entry := range entries {
+		m := logName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(m[1])
+		report.Processes = append(report.Processes, id)
+		total[id] = nil
+		err := scan(filepath.Join(dir, entry.Name()), func(line string) error {
+			if m := deliverLine.FindStringSubmatch(line); m != nil {
+				delivered[id] = append(delivered[id], m[1])
+				return nil
+			}
+			if m := totalLine.FindStringSubmatch(line); m != nil {
+				total[id] = append(total[id], m[1])
+				return nil
+			}
+			if castLine.MatchString(line) {
+				report.TotalOrder++
+				return nil
+			}
+			m := sentLine.FindStringSubmatch(line)
+			isSent := m != nil
+			if m == nil {
+				m = receiveLine.FindStringSubmatch(line)
+			}
+			if m == nil {
+				return nil
+			}
+			msg, err := parseMessage(m[1], m[2])
+			if err != nil {
+				return err
+			}
+			// Dòng SENT của sender được ưu tiên hơn dòng RECEIVED
+			if _, ok := messages[msg.id]; !ok || isSent {
+				messages[msg.id] = msg
+			}
+			if isSent {
+				fromSender = append(fromSender, msg.id)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(report.Processes) == 0 {
+		return nil, fmt.Errorf("no process_N.log files in %s", dir)
+	}
+	sort.Ints(report.Processes)
+
+	times := make(map[string]int)
+	for _, p := range report.Processes {
+		for _, id := range delivered[p] {
+			times[id]++
+			report.Delivered++
+			if times[id] == 2 {
+				report.Duplicates = append(report.Duplicates, id)
+			}
+		}
+	}
+	report.Sent = len(fromSender)
+	for _, id := range fromSender {
+		if times[id] == 0 {
+			report.Undelivered = append(report.Undelivered, id)
+		}
+	}
+	for _, p := range report.Processes {
+		report.Violations = append(report.Violations, causalOrder(p, delivered[p], messages)...)
+	}
+	if report.TotalOrder > 0 {
+		report.TotalMismatches = SameOrder(total, report.TotalOrder)
+	}
+	return report, nil
+}
+
+// SameOrder kiểm tra mọi process trong delivered đã deliver cùng một dãy
+// gồm want message total order. Trả về mô tả các process lệch khỏi dãy
+// dài nhất (của process có ID nhỏ nhất nếu có nhiều dãy dài bằng nhau).
+func SameOrder(delivered map[int][]string, want int) []string {
+	var ids []int
+	for id := range delivered {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+	ref := ids[0]
+	for _, id := range ids {
+		if len(delivered[id]) > len(delivered[ref]) {
+			ref = id
+		}
+	}
+
+	var mismatches []string
+	expected := delivered[ref]
+	for _, id := range ids {
+		got, diverged := delivered[id], false
+		for i := 0; i < min(len(got), len(expected)); i++ {
+			if got[i] != expected[i] {
+				mismatches = append(mismatches, fmt.Sprintf("P%d delivered %s as #%d, P%d delivered %s", id, got[i], i+1, ref, expected[i]))
+				diverged = true
+				break
+			}
+		}
+		if !diverged && len(got) < want {
+			mismatches = append(mismatches, fmt.Sprintf("P%d delivered %d of %d total order messages", id, len(got), want))
+		}
+	}
+	return mismatches
+}
+
+// causalOrder kiểm tra thứ tự deliver ở process p: khi deliver x, mọi
+// message đến p xảy ra trước x phải đã được deliver
+func causalOrder(p int, order []string, messages map[string]*sent) []Violation {
+	// Message đến p theo từng sender, theo thứ tự gửi. Clock tăng dần theo
+	// thứ tự này, nên các message xảy ra trước x là một prefix
+	bySender := make(map[int][]*sent)
+	for _, msg := range messages {
+		if msg.receiver == p {
+			bySender[msg.sender] = append(bySender[msg.sender], msg)
+		}
+	}
+	var senders []int
+	for k, list := range bySender {
+		sort.Slice(list, func(i, j int) bool { return list[i].clock[k] < list[j].clock[k] })
+		senders = append(senders, k)
+	}
+	sort.Ints(senders)
+
+	var violations []Violation
+	reported := make(map[string]bool) // mỗi message bị bỏ qua chỉ báo một lần
+	done := make(map[string]bool)
+	next := make(map[int]int) // bySender[k][:next[k]] đều đã được deliver
+	for _, id := range order {
+		x, ok := messages[id]
+		if ok {
+			for _, k := range senders {
+				list := bySender[k]
+				before := sort.Search(len(list), func(i int) bool {
+					return list[i] == x || !leq(list[i].clock, x.clock)
+				})
+				for next[k] < len(list) && done[list[next[k]].id] {
+					next[k]++
+				}
+				if next[k] < before && !reported[list[next[k]].id] {
+					reported[list[next[k]].id] = true
+					violations = append(violations, Violation{p, id, list[next[k]].id})
+				}
+			}
+		}
+		done[id] = true
+	}
+	return violations
+}
+
+// leq: a <= b ở mọi phần tử
+func leq(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMessage đọc ID và tm trong một dòng log
+func parseMessage(id, tm string) (*sent, error) {
+	m := messageID.FindStringSubmatch(id)
+	if m == nil {
+		return nil, fmt.Errorf("unexpected message ID %q", id)
+	}
+	sender, _ := strconv.Atoi(m[1])
+	receiver, _ := strconv.Atoi(m[2])
+	var clock []int
+	for _, field := range strings.Fields(tm) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad tm %q", id, tm)
+		}
+		clock = append(clock, n)
+	}
+	if sender >= len(clock) {
+		return nil, fmt.Errorf("%s: tm %v has no entry for P%d", id, clock, sender)
+	}
+	clock[sender]++
+	return &sent{id, sender, receiver, clock}, nil
+}
+
+// scan gọi fn với từng dòng của file path
+func scan(path string, fn func(line string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if err := fn(scanner.Text()); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
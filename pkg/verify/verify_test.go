@@ -0,0 +1,165 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeLogs ghi log theo định dạng của process, mỗi process một chuỗi dòng
+func writeLogs(t *testing.T, logs map[int][]string) string {
+	dir := t.TempDir()
+	for id, lines := range logs {
+		var b strings.Builder
+		for _, line := range lines {
+			b.WriteString("[P] 2026/01/02 15:04:05 " + line + "\n")
+		}
+		path := filepath.Join(dir, fmt.Sprintf("process_%d.log", id))
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// P0 gửi m1 đến P2 rồi m2 đến P1; P1 deliver m2 rồi gửi m3 đến P2, nên m1
+// xảy ra trước m3 và P2 phải deliver m1 trước
+func causalRun(p2 ...string) map[int][]string {
+	return map[int][]string{
+		0: {
+			"📤 SENT to P2: P0-P2-M1 | tm=[0 0 0] | V_M=[] | message 1",
+			"📤 SENT to P1: P0-P1-M1 | tm=[1 0 0] | V_M=[(P2,[1 0 0])] | message 1",
+		},
+		1: {
+			"📥 RECEIVED from P0: P0-P1-M1 | tm=[1 0 0] | V_M=[(P2,[1 0 0])] | tP=[0 0 0] | message 1",
+			"✅ DELIVERED: P0-P1-M1 | tP: [0 0 0] → [2 0 0]",
+			"📤 SENT to P2: P1-P2-M1 | tm=[2 0 0] | V_M=[(P2,[1 0 0])] | message 1",
+		},
+		2: p2,
+	}
+}
+
+func TestCausalRunPasses(t *testing.T) {
+	dir := writeLogs(t, causalRun(
+		"📥 RECEIVED from P1: P1-P2-M1 | tm=[2 0 0] | V_M=[(P2,[1 0 0])] | tP=[0 0 0] | message 1",
+		"🔄 BUFFERED: P1-P2-M1 | Reason: dependency | BufferSize: 1 | tP: [0 0 0]",
+		"📥 RECEIVED from P0: P0-P2-M1 | tm=[0 0 0] | V_M=[] | tP=[0 0 0] | message 1",
+		"✅ DELIVERED: P0-P2-M1 | tP: [0 0 0] → [1 0 0]",
+		"✅ DELIVERED: P1-P2-M1 | tP: [1 0 0] → [2 1 0]",
+	))
+	report, err := Logs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Sent != 3 || report.Delivered != 3 || !reflect.DeepEqual(report.Processes, []int{0, 1, 2}) {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestReportsEveryProblem(t *testing.T) {
+	dir := writeLogs(t, causalRun(
+		"✅ DELIVERED: P1-P2-M1 | tP: [0 0 0] → [2 1 0]",
+		"✅ DELIVERED: P1-P2-M1 | tP: [2 1 0] → [2 2 0]",
+	))
+	report, err := Logs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Undelivered, []string{"P0-P2-M1"}) || !reflect.DeepEqual(report.Duplicates, []string{"P1-P2-M1"}) {
+		t.Fatalf("report = %+v", report)
+	}
+	want := []Violation{{Process: 2, Delivered: "P1-P2-M1", Missing: "P0-P2-M1"}}
+	if !reflect.DeepEqual(report.Violations, want) {
+		t.Fatalf("violations = %v, want %v", report.Violations, want)
+	}
+
+	// Message đồng thời được deliver theo thứ tự bất kỳ
+	dir = writeLogs(t, map[int][]string{
+		0: {"📤 SENT to P2: P0-P2-M1 | tm=[0 0 0] | V_M=[] | message 1"},
+		1: {"📤 SENT to P2: P1-P2-M1 | tm=[0 0 0] | V_M=[] | message 1"},
+		2: {"✅ DELIVERED: P1-P2-M1 | tP: [0 0 0] → [0 1 0]", "✅ DELIVERED: P0-P2-M1 | tP: [0 1 0] → [1 1 0]"},
+	})
+	if report, err := Logs(dir); err != nil || !report.OK() {
+		t.Fatalf("concurrent messages: %+v, %v", report, err)
+	}
+
+	if _, err := Logs(t.TempDir()); err == nil {
+		t.Fatal("empty directory accepted")
+	}
+}
+
+// P1 có đồng hồ chậm: theo giờ trong log DELIVERED đứng trước SENT, theo
+// HLC thì không
+func TestTimelineOrdersByHLC(t *testing.T) {
+	dir := writeLogs(t, map[int][]string{
+		0: {
+			"=== PROCESS INITIALIZED ===",
+			"📤 SENT to P1: P0-P1-M1 | tm=[0 0] | V_M=[] | message 1 hlc=1700000005.000000000,0",
+			"Buffer size: 0",
+		},
+		1: {
+			"=== PROCESS INITIALIZED ===",
+			"📤 SENT to P0: P1-P0-M1 | tm=[0 0] | V_M=[] | message 1 hlc=1700000001.000000000,0",
+			"📥 RECEIVED from P0: P0-P1-M1 | tm=[0 0] | V_M=[] | tP=[0 1] | message 1 hlc=1700000005.000000000,1",
+			"✅ DELIVERED: P0-P1-M1 | tP: [0 1] → [1 1] hlc=1700000005.000000000,2",
+		},
+	})
+	timeline, err := Timeline(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range timeline {
+		got = append(got, fmt.Sprintf("P%d %s", e.Process, strings.Fields(e.Line)[3]))
+	}
+	want := []string{"P0 ===", "P1 ===", "P1 📤", "P0 📤", "P0 Buffer", "P1 📥", "P1 ✅"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("timeline = %v, want %v", got, want)
+	}
+}
+
+func TestTotalOrderMismatch(t *testing.T) {
+	logs := map[int][]string{
+		0: {
+			"📣 MULTICAST P0-ALL-M1 | lamport=1 | total 1",
+			"🔢 TOTAL DELIVERED #1: P0-ALL-M1 | lamport=1 from P0",
+			"🔢 TOTAL DELIVERED #2: P1-ALL-M1 | lamport=1 from P1",
+		},
+		1: {
+			"📣 MULTICAST P1-ALL-M1 | lamport=1 | total 1",
+			"🔢 TOTAL DELIVERED #1: P0-ALL-M1 | lamport=1 from P0",
+			"🔢 TOTAL DELIVERED #2: P1-ALL-M1 | lamport=1 from P1",
+		},
+		2: {
+			"🔢 TOTAL DELIVERED #1: P0-ALL-M1 | lamport=1 from P0",
+			"🔢 TOTAL DELIVERED #2: P1-ALL-M1 | lamport=1 from P1",
+		},
+	}
+	report, err := Logs(writeLogs(t, logs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.TotalOrder != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	logs[2] = []string{
+		"🔢 TOTAL DELIVERED #1: P1-ALL-M1 | lamport=1 from P1",
+		"🔢 TOTAL DELIVERED #2: P0-ALL-M1 | lamport=1 from P0",
+	}
+	logs[3] = nil
+	report, err = Logs(writeLogs(t, logs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"P2 delivered P1-ALL-M1 as #1, P0 delivered P0-ALL-M1",
+		"P3 delivered 0 of 2 total order messages",
+	}
+	if report.OK() || !reflect.DeepEqual(report.TotalMismatches, want) {
+		t.Fatalf("mismatches = %q", report.TotalMismatches)
+	}
+}
@@ -0,0 +1,34 @@
+package workload
+
+import (
+	"fmt"
+
+	"github.com/NationalWind/ses-project/pkg/message"
+)
+
+// Hop là một message trong chuỗi request/response, mang trong
+// message.Message.Chain (được ký cùng message, không đọc từ Content)
+type Hop = message.Hop
+
+// CheckHop kiểm tra hop nhận được có thể là của một chuỗi theo config này:
+// Remaining không vượt quá chain_depth, nên một message không thể kéo theo
+// nhiều hop hơn config cho phép
+func (g *Generator) CheckHop(h Hop) error {
+	if h.Remaining > g.cfg.ChainDepth {
+		return fmt.Errorf("remaining=%d exceeds chain_depth=%d", h.Remaining, g.cfg.ChainDepth)
+	}
+	return nil
+}
+
+// NextHop trả về hop tiếp theo và receiver của nó khi process self deliver
+// h. ok = false nếu chuỗi đã kết thúc.
+func (g *Generator) NextHop(h Hop) (next Hop, target int, ok bool) {
+	if h.Remaining <= 0 {
+		return Hop{}, 0, false
+	}
+	next = Hop{Chain: h.Chain, Origin: h.Origin, Remaining: h.Remaining - 1}
+	if next.Remaining == 0 && h.Origin != g.self {
+		return next, h.Origin, true // response về process mở đầu
+	}
+	return next, g.PickTarget(h.Origin), true
+}
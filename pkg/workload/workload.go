@@ -0,0 +1,203 @@
+// Package workload sinh lịch gửi message: khi nào gửi (arrivals) và gửi cho
+// ai (destinations), cùng với các chuỗi request/response tạo dependency
+// nhân quả sâu.
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Config là phần "workload" trong config.json
+type Config struct {
+	// Arrivals: uniform | poisson | burst
+	Arrivals string `json:"arrivals"`
+	// Destinations: uniform | zipf | hotspot
+	Destinations string `json:"destinations"`
+
+	Messages int     `json:"messages"` // tổng số message process này khởi tạo
+	Rate     float64 `json:"rate"`     // số message trung bình mỗi phút
+
+	BurstSize int `json:"burst_size,omitempty"` // burst: số message gửi liền nhau
+
+	ZipfS float64 `json:"zipf_s,omitempty"` // zipf: độ lệch, > 1 (mặc định 1.5)
+
+	HotSpots    []int   `json:"hot_spots,omitempty"`    // hotspot: các receiver nóng
+	HotFraction float64 `json:"hot_fraction,omitempty"` // hotspot: tỉ lệ traffic đến hot spot (mặc định 0.8)
+
+	ChainProbability float64 `json:"chain_probability,omitempty"` // tỉ lệ message mở đầu một chuỗi request/response
+	ChainDepth       int     `json:"chain_depth,omitempty"`       // số hop sau message đầu tiên
+}
+
+// Enabled cho biết config có bật workload không (Arrivals rỗng = cách gửi cũ)
+func (c Config) Enabled() bool {
+	return c.Arrivals != ""
+}
+
+// Send là một message cần gửi: chờ Delay rồi gửi đến Target
+type Send struct {
+	Delay  time.Duration
+	Target int
+	Chain  *Hop // khác nil: message mở đầu một chuỗi request/response
+}
+
+// Generator sinh lần lượt các Send theo Config. An toàn khi dùng từ nhiều
+// goroutine: PickTarget được gọi khi deliver message trong chuỗi.
+type Generator struct {
+	cfg    Config
+	self   int
+	peers  []int // các process khác, thứ tự theo khoảng cách (self+1, self+2, ...)
+	mu     sync.Mutex
+	rng    *rand.Rand
+	zipf   *rand.Zipf
+	hot    map[int]bool
+	sent   int
+	chains int
+}
+
+// New kiểm tra cfg và tạo Generator cho process self. Cùng seed cho ra cùng
+// lịch gửi.
+func New(cfg Config, self int, numProcesses int, seed int64) (*Generator, error) {
+	if numProcesses < 2 {
+		return nil, fmt.Errorf("workload needs at least 2 processes")
+	}
+	if cfg.Messages < 0 || cfg.Rate <= 0 {
+		return nil, fmt.Errorf("workload needs messages >= 0 and rate > 0, got %d and %v", cfg.Messages, cfg.Rate)
+	}
+	switch cfg.Arrivals {
+	case "uniform", "poisson":
+	case "burst":
+		if cfg.BurstSize == 0 {
+			cfg.BurstSize = 10
+		}
+		if cfg.BurstSize < 1 {
+			return nil, fmt.Errorf("burst_size must be positive, got %d", cfg.BurstSize)
+		}
+	default:
+		return nil, fmt.Errorf("unknown arrivals %q (want uniform, poisson or burst)", cfg.Arrivals)
+	}
+	if cfg.ChainProbability < 0 || cfg.ChainProbability > 1 || cfg.ChainDepth < 0 {
+		return nil, fmt.Errorf("chain_probability must be in [0, 1] and chain_depth >= 0")
+	}
+
+	g := &Generator{cfg: cfg, self: self, rng: rand.New(rand.NewSource(seed)), hot: make(map[int]bool)}
+	for k := 1; k < numProcesses; k++ {
+		g.peers = append(g.peers, (self+k)%numProcesses)
+	}
+
+	switch cfg.Destinations {
+	case "", "uniform":
+	case "zipf":
+		if g.cfg.ZipfS == 0 {
+			g.cfg.ZipfS = 1.5
+		}
+		if g.cfg.ZipfS <= 1 {
+			return nil, fmt.Errorf("zipf_s must be > 1, got %v", g.cfg.ZipfS)
+		}
+		g.zipf = rand.NewZipf(g.rng, g.cfg.ZipfS, 1, uint64(len(g.peers)-1))
+	case "hotspot":
+		if len(cfg.HotSpots) == 0 {
+			return nil, fmt.Errorf("hotspot destinations need hot_spots")
+		}
+		for _, id := range cfg.HotSpots {
+			if id < 0 || id >= numProcesses {
+				return nil, fmt.Errorf("hot spot P%d out of range", id)
+			}
+			if id != self {
+				g.hot[id] = true
+			}
+		}
+		if g.cfg.HotFraction == 0 {
+			g.cfg.HotFraction = 0.8
+		}
+		if g.cfg.HotFraction < 0 || g.cfg.HotFraction > 1 {
+			return nil, fmt.Errorf("hot_fraction must be in [0, 1], got %v", g.cfg.HotFraction)
+		}
+	default:
+		return nil, fmt.Errorf("unknown destinations %q (want uniform, zipf or hotspot)", cfg.Destinations)
+	}
+	return g, nil
+}
+
+// Total là số message Generator sẽ sinh (không tính các hop sau của chuỗi)
+func (g *Generator) Total() int {
+	return g.cfg.Messages
+}
+
+// Next trả về message tiếp theo, ok = false khi đã sinh đủ
+func (g *Generator) Next() (s Send, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.sent >= g.cfg.Messages {
+		return Send{}, false
+	}
+	s = Send{Delay: g.delay(), Target: g.pickTarget(-1)}
+	if g.cfg.ChainDepth > 0 && g.rng.Float64() < g.cfg.ChainProbability {
+		g.chains++
+		s.Chain = &Hop{Chain: fmt.Sprintf("P%d-C%d", g.self, g.chains), Origin: g.self, Remaining: g.cfg.ChainDepth}
+	}
+	g.sent++
+	return s, true
+}
+
+// delay là thời gian chờ trước message thứ g.sent, trung bình 1/Rate phút
+func (g *Generator) delay() time.Duration {
+	mean := float64(time.Minute) / g.cfg.Rate
+	switch g.cfg.Arrivals {
+	case "poisson":
+		return time.Duration(g.rng.ExpFloat64() * mean)
+	case "burst":
+		// Cả burst gửi liền nhau, nghỉ giữa các burst để giữ tốc độ trung bình
+		if g.sent%g.cfg.BurstSize == 0 && g.sent > 0 {
+			return time.Duration(mean * float64(g.cfg.BurstSize))
+		}
+		return 0
+	default:
+		return time.Duration(g.rng.Float64() * 2 * mean)
+	}
+}
+
+// PickTarget chọn receiver theo Destinations, khác self và khác exclude
+// (exclude = -1: không loại thêm)
+func (g *Generator) PickTarget(exclude int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pickTarget(exclude)
+}
+
+func (g *Generator) pickTarget(exclude int) int {
+	for {
+		var target int
+		switch {
+		case g.zipf != nil:
+			target = g.peers[g.zipf.Uint64()]
+		case len(g.hot) > 0 && g.rng.Float64() < g.cfg.HotFraction:
+			target = g.randomOf(func(id int) bool { return g.hot[id] })
+		case len(g.hot) > 0:
+			target = g.randomOf(func(id int) bool { return !g.hot[id] })
+		default:
+			target = g.peers[g.rng.Intn(len(g.peers))]
+		}
+		if target != exclude || len(g.peers) == 1 {
+			return target
+		}
+	}
+}
+
+// randomOf chọn ngẫu nhiên một peer thỏa keep, hoặc một peer bất kỳ nếu
+// không có peer nào thỏa
+func (g *Generator) randomOf(keep func(int) bool) int {
+	var candidates []int
+	for _, id := range g.peers {
+		if keep(id) {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = g.peers
+	}
+	return candidates[g.rng.Intn(len(candidates))]
+}
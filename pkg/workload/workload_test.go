@@ -0,0 +1,137 @@
+package workload
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// drain lấy hết các Send từ g
+func drain(t *testing.T, cfg Config, self, n int, seed int64) []Send {
+	t.Helper()
+	g, err := New(cfg, self, n, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sends []Send
+	for {
+		s, ok := g.Next()
+		if !ok {
+			return sends
+		}
+		if s.Target == self || s.Target < 0 || s.Target >= n {
+			t.Fatalf("P%d sends to P%d", self, s.Target)
+		}
+		sends = append(sends, s)
+	}
+}
+
+func TestSameSeedSameSchedule(t *testing.T) {
+	cfg := Config{Arrivals: "poisson", Destinations: "zipf", Messages: 50, Rate: 600, ChainProbability: 0.3, ChainDepth: 2}
+	a, b := drain(t, cfg, 1, 5, 7), drain(t, cfg, 1, 5, 7)
+	if len(a) != 50 {
+		t.Fatalf("got %d sends, want 50", len(a))
+	}
+	for i := range a {
+		if a[i].Delay != b[i].Delay || a[i].Target != b[i].Target || (a[i].Chain == nil) != (b[i].Chain == nil) {
+			t.Fatalf("send %d differs: %+v vs %+v", i, a[i], b[i])
+		}
+	}
+}
+
+func TestPoissonMeanDelay(t *testing.T) {
+	sends := drain(t, Config{Arrivals: "poisson", Messages: 5000, Rate: 60}, 0, 3, 1)
+	var total time.Duration
+	for _, s := range sends {
+		total += s.Delay
+	}
+	mean := total / time.Duration(len(sends))
+	if math.Abs(float64(mean-time.Second)) > float64(100*time.Millisecond) {
+		t.Fatalf("mean delay %v, want about 1s", mean)
+	}
+}
+
+func TestBurstArrivals(t *testing.T) {
+	sends := drain(t, Config{Arrivals: "burst", Messages: 12, Rate: 60, BurstSize: 4}, 0, 3, 1)
+	for i, s := range sends {
+		want := time.Duration(0)
+		if i > 0 && i%4 == 0 {
+			want = 4 * time.Second
+		}
+		if s.Delay != want {
+			t.Fatalf("send %d delay %v, want %v", i, s.Delay, want)
+		}
+	}
+}
+
+func TestZipfSkew(t *testing.T) {
+	counts := make(map[int]int)
+	for _, s := range drain(t, Config{Arrivals: "uniform", Destinations: "zipf", Messages: 2000, Rate: 60}, 2, 6, 1) {
+		counts[s.Target]++
+	}
+	// Peer gần nhất (P3) nhận nhiều nhất, peer xa nhất (P1) ít nhất
+	if counts[3] <= counts[4] || counts[4] <= counts[1] {
+		t.Fatalf("counts %v are not skewed towards P3", counts)
+	}
+}
+
+func TestHotSpotFraction(t *testing.T) {
+	cfg := Config{Arrivals: "uniform", Destinations: "hotspot", Messages: 4000, Rate: 60, HotSpots: []int{0, 3}, HotFraction: 0.9}
+	hot := 0
+	for _, s := range drain(t, cfg, 0, 5, 1) {
+		if s.Target == 3 {
+			hot++
+		}
+	}
+	if got := float64(hot) / 4000; math.Abs(got-0.9) > 0.03 {
+		t.Fatalf("hot spot got %.2f of traffic, want 0.9", got)
+	}
+}
+
+func TestChainHops(t *testing.T) {
+	g, err := New(Config{Arrivals: "uniform", Messages: 1, Rate: 60, ChainProbability: 1, ChainDepth: 2}, 1, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := g.Next()
+	if s.Chain == nil || s.Chain.Origin != 1 || s.Chain.Remaining != 2 {
+		t.Fatalf("chain = %+v", s.Chain)
+	}
+
+	if err := g.CheckHop(*s.Chain); err != nil {
+		t.Fatalf("CheckHop(%v) = %v", *s.Chain, err)
+	}
+	if err := g.CheckHop(Hop{Chain: "P0-C1", Remaining: 100000}); err == nil {
+		t.Fatal("hop longer than chain_depth accepted")
+	}
+
+	// Hop giữa chuỗi không quay về origin, hop cuối quay về origin
+	h := *s.Chain
+	peer, _ := New(Config{Arrivals: "uniform", Messages: 0, Rate: 60}, 3, 4, 1)
+	next, target, ok := peer.NextHop(h)
+	if !ok || next.Remaining != 1 || target == 1 || target == 3 {
+		t.Fatalf("NextHop(%+v) = %+v, P%d, %v", h, next, target, ok)
+	}
+	last, target, ok := peer.NextHop(next)
+	if !ok || last.Remaining != 0 || target != 1 {
+		t.Fatalf("NextHop(%+v) = %+v, P%d, %v", next, last, target, ok)
+	}
+	if _, _, ok := peer.NextHop(last); ok {
+		t.Fatal("chain continued past its last hop")
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Arrivals: "steady", Rate: 60},
+		{Arrivals: "uniform", Rate: 0},
+		{Arrivals: "uniform", Rate: 60, Destinations: "zipf", ZipfS: 1},
+		{Arrivals: "uniform", Rate: 60, Destinations: "hotspot"},
+		{Arrivals: "uniform", Rate: 60, Destinations: "hotspot", HotSpots: []int{9}},
+		{Arrivals: "uniform", Rate: 60, ChainProbability: 2},
+	} {
+		if _, err := New(cfg, 0, 4, 1); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
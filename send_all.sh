@@ -2,7 +2,7 @@
 
 # Build Go project once
 echo "Building SES project..."
-go build -o ses.exe cmd/main.go
+go build -o ses.exe ./cmd
 if [ $? -ne 0 ]; then
     echo "Build failed!"
     exit 1
@@ -10,11 +10,5 @@ fi
 
 echo "Build success!"
 
-# Start all processes in background with auto-send
-for i in $(seq 0 14); do
-    echo "Starting process $i..."
-    ./ses.exe $i send > "logs/console_P$i.log" 2>&1 &
-done
-
-echo "All processes started!"
-wait
+# Start all processes with auto-send, wait for them and check the logs
+./ses.exe cluster --verify "$@"